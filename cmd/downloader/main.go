@@ -2,8 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
@@ -50,8 +50,30 @@ func main() {
 				Usage: "HTTP请求超时时间",
 				Value: 30 * time.Second,
 			},
+			&cli.IntFlag{
+				Name:  "connections",
+				Usage: "并行下载连接数（服务器支持分段下载时生效）",
+				Value: 1,
+			},
+			&cli.StringFlag{
+				Name:  "guid",
+				Usage: "从RSS/Atom订阅源下载指定GUID的节目（URL为订阅源地址）",
+			},
+			&cli.IntFlag{
+				Name:  "latest",
+				Usage: "下载整个节目时只下载最新的N期",
+			},
+			&cli.StringFlag{
+				Name:  "since",
+				Usage: "下载整个节目时只下载该日期及之后发布的单集（YYYY-MM-DD）",
+			},
+			&cli.StringFlag{
+				Name:  "until",
+				Usage: "下载整个节目时只下载该日期及之前发布的单集（YYYY-MM-DD）",
+			},
 		},
-		Action: downloadPodcast,
+		Action:   downloadPodcast,
+		Commands: []*cli.Command{opmlCommand, searchCommand},
 	}
 
 	if err := app.Run(os.Args); err != nil {
@@ -67,25 +89,32 @@ func downloadPodcast(ctx *cli.Context) error {
 	}
 
 	url := ctx.Args().Get(0)
+	if guid := ctx.String("guid"); guid != "" {
+		url = downloader.FeedEpisodeURL(url, guid)
+	}
 
-	// 2. Validate URL format
-	urlValidator := validator.NewXiaoyuzhouURLValidator()
-	valid, errMsg := urlValidator.ValidateURL(url)
-	if !valid {
-		return cli.Exit(fmt.Sprintf("URL格式错误: %s", errMsg), 1)
+	// 2. Create configuration
+	cfg := createConfig(ctx)
+	if err := cfg.Validate(); err != nil {
+		return cli.Exit(fmt.Sprintf("参数错误: %v", err), 1)
 	}
 
-	fmt.Printf("正在获取播客页面: %s\n", url)
+	// 3. Find the source that handles the URL
+	sources := downloader.NewDefaultRegistry(&http.Client{Timeout: cfg.Timeout})
+	source, err := sources.Lookup(url)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("URL格式错误: %v（支持的来源: %s）", err, strings.Join(sources.Names(), ", ")), 1)
+	}
 
-	// 3. Create configuration
-	cfg := createConfig(ctx)
+	// Show URLs download every selected episode
+	if source.IsShow(url) {
+		return downloadShow(ctx, cfg, source, url)
+	}
 
-	// 4. Initialize HTTP client and extractor
-	httpClient := downloader.NewHTTPClient(cfg.Timeout)
-	extractor := downloader.NewHTMLExtractor(httpClient)
+	fmt.Printf("正在获取播客页面: %s\n", url)
 
-	// 5. Extract episode metadata
-	metadata, err := extractor.ExtractURL(context.Background(), url)
+	// 4-5. Resolve episode metadata
+	metadata, err := source.Resolve(context.Background(), url)
 	if err != nil {
 		return cli.Exit(fmt.Sprintf("获取音频链接失败: %v", err), 1)
 	}
@@ -94,6 +123,84 @@ func downloadPodcast(ctx *cli.Context) error {
 		fmt.Printf("找到播客: %s\n", metadata.Title)
 	}
 
+	return downloadEpisode(cfg, metadata)
+}
+
+// downloadShow lists the episodes of a show and downloads the selected ones one by one.
+// Episodes whose audio file already exists are skipped unless --overwrite is set.
+func downloadShow(ctx *cli.Context, cfg *config.Config, source downloader.Source, showURL string) error {
+	filter := downloader.EpisodeFilter{Latest: ctx.Int("latest")}
+	var err error
+	if filter.Since, err = parseDateFlag(ctx.String("since"), false); err != nil {
+		return cli.Exit(fmt.Sprintf("参数错误: --since 日期格式应为YYYY-MM-DD: %v", err), 1)
+	}
+	if filter.Until, err = parseDateFlag(ctx.String("until"), true); err != nil {
+		return cli.Exit(fmt.Sprintf("参数错误: --until 日期格式应为YYYY-MM-DD: %v", err), 1)
+	}
+	if !cfg.OverwriteExisting {
+		filter.Skip = func(episode *downloader.EpisodeMetadata) bool {
+			filePath := filepath.Join(cfg.OutputDirectory, sanitizeDirectoryName(episode.Title), "podcast"+episode.AudioExtension())
+			_, err := os.Stat(filePath)
+			return err == nil
+		}
+	}
+
+	fmt.Printf("正在获取节目列表: %s\n", showURL)
+	episodes, err := source.List(context.Background(), showURL)
+	var truncated *downloader.TruncatedListError
+	if errors.As(err, &truncated) {
+		logWarning("%v", err)
+	} else if err != nil {
+		return cli.Exit(fmt.Sprintf("获取节目列表失败: %v", err), 1)
+	}
+
+	selected, skipped := filter.Apply(episodes)
+	fmt.Printf("共 %d 期，将下载 %d 期，跳过已下载的 %d 期\n", len(episodes), len(selected), skipped)
+
+	var failed int
+	for i, episode := range selected {
+		fmt.Printf("\n[%d/%d] %s\n", i+1, len(selected), episode.Title)
+
+		// Listings may lack the audio URL; the episode page always has it
+		metadata := episode
+		if metadata.AudioURL == "" {
+			if metadata, err = source.Resolve(context.Background(), episode.PageURL); err != nil {
+				logWarning("获取音频链接失败: %v", err)
+				failed++
+				continue
+			}
+		}
+		if err := downloadEpisode(cfg, metadata); err != nil {
+			logWarning("%v", err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return cli.Exit(fmt.Sprintf("\n%d 期下载失败", failed), 1)
+	}
+	fmt.Printf("\n全部下载完成，共 %d 期\n", len(selected))
+	return nil
+}
+
+// parseDateFlag parses a YYYY-MM-DD flag value in local time. Upper bounds
+// include the whole day.
+func parseDateFlag(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+// downloadEpisode downloads the audio, cover and show notes of a resolved episode.
+func downloadEpisode(cfg *config.Config, metadata *downloader.EpisodeMetadata) error {
 	// 7. Generate file path with podcast title as subdirectory
 	// Use sanitized podcast title as folder name
 	podcastTitle := sanitizeDirectoryName(metadata.Title)
@@ -105,8 +212,8 @@ func downloadPodcast(ctx *cli.Context) error {
 	}
 
 	// Use simplified filenames since we already have the podcast title as directory name
-	// Files: podcast.m4a, cover.jpg, shownotes.txt
-	filename := "podcast.m4a"
+	// Files: podcast.m4a (or podcast.mp3), cover.jpg (or .png/.webp/.gif), shownotes.txt
+	filename := "podcast" + metadata.AudioExtension()
 	filePath := filepath.Join(podcastDir, filename)
 
 	// 8. Validate file path
@@ -125,14 +232,20 @@ func downloadPodcast(ctx *cli.Context) error {
 	}
 
 	fmt.Printf("下载到: %s\n", filePath)
+	if downloader.HasPartial(filePath) {
+		fmt.Println("检测到未完成的下载，将从中断处继续")
+	}
 
-	// 10. Create progress bar
-	var progressBar io.Writer
+	// 10. Create progress bar once the total size is known
+	var progressFunc downloader.ProgressFunc
 	if cfg.ShowProgress {
-		progressBar = progressbar.DefaultBytes(
-			-1, // Unknown size initially
-			"下载中",
-		)
+		var progressBar *progressbar.ProgressBar
+		progressFunc = func(p downloader.Progress) {
+			if progressBar == nil {
+				progressBar = progressbar.DefaultBytes(p.Total, "下载中")
+			}
+			progressBar.Set64(p.Downloaded)
+		}
 	}
 
 	// 11. Create downloader (use longer timeout for file downloads)
@@ -142,16 +255,11 @@ func downloadPodcast(ctx *cli.Context) error {
 	downloaderClient := &http.Client{
 		Timeout: downloadTimeout,
 	}
-	fileDownloader := downloader.NewHTTPDownloader(downloaderClient, cfg.ShowProgress)
+	fileDownloader := downloader.NewFileDownloader(downloaderClient, cfg.Connections, cfg.ShowProgress)
 
 	// 12. Download file
-	var progressWriter io.Writer
-	if progressBar != nil {
-		progressWriter = progressBar
-	}
-
 	fmt.Println() // Add newline before progress bar
-	bytesWritten, err := fileDownloader.Download(context.Background(), metadata.AudioURL, filePath, progressWriter)
+	bytesWritten, err := fileDownloader.Download(context.Background(), metadata.AudioURL, filePath, progressFunc)
 	if err != nil {
 		return cli.Exit(fmt.Sprintf("下载失败: %v", err), 1)
 	}
@@ -165,10 +273,10 @@ func downloadPodcast(ctx *cli.Context) error {
 	}
 
 	// 14. Download cover image (if available)
-	if metadata.CoverURL != "" {
-		// Use simplified filename: cover.jpg
-		coverPath := filepath.Join(podcastDir, "cover.jpg")
-
+	// Saved as cover.jpg, cover.png, cover.webp or cover.gif by its real format
+	// The largest size is used when the source offers several
+	var coverPath string
+	if coverURL := metadata.CoverDownloadURL(); coverURL != "" {
 		// Create image downloader with separate client (images download quickly)
 		imageHTTPClient := &http.Client{
 			Timeout: 2 * time.Minute, // 2 minutes for images
@@ -176,7 +284,7 @@ func downloadPodcast(ctx *cli.Context) error {
 		imageDownloader := downloader.NewHTTPImageDownloader(imageHTTPClient, 10*1024*1024) // 10MB max
 
 		// Try to download cover image with graceful degradation
-		if _, err := imageDownloader.Download(context.Background(), metadata.CoverURL, coverPath, nil); err != nil {
+		if coverPath, err = imageDownloader.DownloadCover(context.Background(), coverURL, podcastDir, nil); err != nil {
 			logWarning("Warning: Cover image download failed: %v. Audio download completed successfully.", err)
 		} else {
 			logSuccess("Cover image saved to: %s", coverPath)
@@ -199,7 +307,12 @@ func downloadPodcast(ctx *cli.Context) error {
 		}
 	}
 
-	// 16. Report success
+	// 16. Tag the audio file so players show the episode title and cover
+	if err := downloader.TagAudio(filePath, coverPath, metadata); err != nil {
+		logWarning("Warning: Audio tagging failed: %v. Audio download completed successfully.", err)
+	}
+
+	// 17. Report success
 	fmt.Printf("\n下载成功!\n")
 	fmt.Printf("文件位置: %s\n", filePath)
 	fmt.Printf("文件大小: %.2f MB\n", float64(bytesWritten)/(1024*1024))
@@ -217,6 +330,7 @@ func createConfig(ctx *cli.Context) *config.Config {
 		RetryDelay:        1 * time.Second,
 		ShowProgress:      !ctx.Bool("no-progress"),
 		ValidateFiles:     true,
+		Connections:       ctx.Int("connections"),
 	}
 }
 
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/meixg/podcast-reader/pkg/downloader"
+	"github.com/meixg/podcast-reader/pkg/opml"
+	"github.com/meixg/podcast-reader/pkg/scanner"
+	"github.com/urfave/cli/v2"
+)
+
+// opmlCommand imports and exports shows as OPML files, the format podcast
+// apps use for their subscription lists.
+var opmlCommand = &cli.Command{
+	Name:  "opml",
+	Usage: "导入或导出OPML节目列表",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "import",
+			Usage:     "下载OPML文件中每个节目的单集（支持 --latest/--since/--until）",
+			ArgsUsage: "<file>",
+			Action:    importOPML,
+		},
+		{
+			Name:  "export",
+			Usage: "按播客名称导出下载目录中的节目及其来源URL",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "file",
+					Usage: "写入文件而不是标准输出",
+				},
+			},
+			Action: exportOPML,
+		},
+	},
+}
+
+// importOPML downloads the shows and episodes listed in an OPML file.
+// Failures are reported and the import continues with the next entry.
+func importOPML(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return cli.Exit("请提供OPML文件路径", 1)
+	}
+
+	cfg := createConfig(ctx)
+	if err := cfg.Validate(); err != nil {
+		return cli.Exit(fmt.Sprintf("参数错误: %v", err), 1)
+	}
+
+	f, err := os.Open(ctx.Args().Get(0))
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("打开OPML文件失败: %v", err), 1)
+	}
+	doc, err := opml.Parse(f)
+	f.Close()
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("读取OPML文件失败: %v", err), 1)
+	}
+
+	sources := downloader.NewDefaultRegistry(&http.Client{Timeout: cfg.Timeout})
+	seen := make(map[string]bool)
+	var imported, failed int
+	for _, outline := range doc.All() {
+		url := outlineURL(sources, outline)
+		if url == "" || seen[url] {
+			continue
+		}
+		seen[url] = true
+		imported++
+
+		fmt.Printf("\n==> %s\n", outline.Name())
+		source, err := sources.Lookup(url)
+		if err != nil {
+			logWarning("跳过不支持的URL: %s", url)
+			failed++
+			continue
+		}
+		if source.IsShow(url) {
+			err = downloadShow(ctx, cfg, source, url)
+		} else {
+			var metadata *downloader.EpisodeMetadata
+			if metadata, err = source.Resolve(context.Background(), url); err == nil {
+				err = downloadEpisode(cfg, metadata)
+			}
+		}
+		if err != nil {
+			logWarning("%v", err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return cli.Exit(fmt.Sprintf("\n%d/%d 个条目导入失败", failed, imported), 1)
+	}
+	fmt.Printf("\n导入完成，共 %d 个条目\n", imported)
+	return nil
+}
+
+// outlineURL returns the show or episode an outline refers to: its feed,
+// a show page given as htmlUrl, or a linked episode.
+func outlineURL(sources *downloader.Registry, outline opml.Outline) string {
+	switch {
+	case outline.XMLURL != "":
+		return outline.XMLURL
+	case outline.HTMLURL != "" && sources.IsShow(outline.HTMLURL):
+		return outline.HTMLURL
+	default:
+		return outline.URL
+	}
+}
+
+// exportOPML writes the shows in the output directory as OPML.
+func exportOPML(ctx *cli.Context) error {
+	episodes, err := scanner.NewScanner(ctx.String("output")).ScanEpisodes()
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("扫描下载目录失败: %v", err), 1)
+	}
+	doc := opml.Library(episodes, nil)
+
+	path := ctx.String("file")
+	if path == "" {
+		return doc.Write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("创建文件失败: %v", err), 1)
+	}
+	if err := doc.Write(f); err != nil {
+		f.Close()
+		return cli.Exit(fmt.Sprintf("写入OPML失败: %v", err), 1)
+	}
+	if err := f.Close(); err != nil {
+		return cli.Exit(fmt.Sprintf("写入OPML失败: %v", err), 1)
+	}
+	logSuccess("已导出 %d 个节目到: %s", len(doc.Outlines), path)
+	return nil
+}
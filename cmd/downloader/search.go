@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/meixg/podcast-reader/pkg/scanner"
+	"github.com/meixg/podcast-reader/pkg/search"
+	"github.com/urfave/cli/v2"
+)
+
+// searchCommand searches the titles and show notes of downloaded episodes.
+var searchCommand = &cli.Command{
+	Name:      "search",
+	Usage:     "搜索已下载单集的标题、播客名称和节目笔记",
+	ArgsUsage: "<query>",
+	Flags: []cli.Flag{
+		&cli.IntFlag{
+			Name:  "limit",
+			Value: 20,
+			Usage: "最多显示的结果数（0表示不限）",
+		},
+	},
+	Action: searchEpisodes,
+}
+
+// searchEpisodes indexes the output directory and prints the best matches
+// with the matching terms highlighted.
+func searchEpisodes(ctx *cli.Context) error {
+	query := strings.TrimSpace(strings.Join(ctx.Args().Slice(), " "))
+	if query == "" {
+		return cli.Exit("请提供搜索关键词", 1)
+	}
+
+	episodes, err := scanner.NewScanner(ctx.String("output")).ScanEpisodes()
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("扫描下载目录失败: %v", err), 1)
+	}
+	// Episodes are keyed by file path; copies of an episode share its ID
+	index := search.NewIndex()
+	podcasts := make(map[string]string, len(episodes))
+	for _, episode := range episodes {
+		index.Add(search.Document{
+			ID:          episode.FilePath,
+			Title:       episode.Title,
+			PodcastName: episode.PodcastName,
+			ShowNotes:   episode.ShowNotes,
+		})
+		podcasts[episode.FilePath] = episode.PodcastName
+	}
+
+	results := index.Search(query)
+	if len(results) == 0 {
+		fmt.Println("没有找到匹配的单集")
+		return nil
+	}
+
+	open, reset := "", ""
+	if !color.NoColor {
+		open, reset = "\x1b[1;33m", "\x1b[0m"
+	}
+	limit := ctx.Int("limit")
+	for i, result := range results {
+		if limit > 0 && i >= limit {
+			fmt.Printf("\n... 还有 %d 个结果，使用 --limit 查看更多\n", len(results)-limit)
+			break
+		}
+		fmt.Printf("\n%s", result.Title.Format(open, reset))
+		if podcast := podcasts[result.ID]; podcast != "" {
+			fmt.Printf("  [%s]", podcast)
+		}
+		fmt.Println()
+		if result.Snippet.Text != "" {
+			fmt.Printf("  %s\n", result.Snippet.Format(open, reset))
+		}
+		fmt.Printf("  %s\n", result.ID)
+	}
+	fmt.Printf("\n共找到 %d 个匹配的单集\n", len(results))
+	return nil
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/meixg/podcast-reader/pkg/scanner"
 	"github.com/meixg/podcast-reader/web/handlers"
@@ -35,17 +38,83 @@ func main() {
 	}
 
 	// Initialize services
-	episodeScanner := scanner.NewScanner(downloadsDir)
-	episodeService := services.NewEpisodeService(episodeScanner)
+	libraryService := services.NewLibraryService(scanner.NewScanner(downloadsDir), filepath.Join(downloadsDir, ".library.json"))
+	if err := libraryService.Load(); err != nil {
+		log.Printf("Warning: Failed to load library: %v", err)
+	}
+	episodeService := services.NewEpisodeService(libraryService)
+	feedService := services.NewFeedService(libraryService)
 	taskService := services.NewTaskService()
+	taskService.SetStore(services.NewJournalTaskStore(filepath.Join(downloadsDir, ".tasks.jsonl")))
 	downloadService := services.NewDownloadService(downloadsDir, taskService)
+	downloadService.SetEpisodeIndexer(libraryService)
+
+	// Reconcile the library with the downloads directory in the background,
+	// picking up episodes added, changed or deleted outside the server
+	rescanInterval := services.DefaultLibraryRescanInterval
+	if interval, err := time.ParseDuration(os.Getenv("LIBRARY_RESCAN_INTERVAL")); err == nil && interval >= 0 {
+		rescanInterval = interval
+	}
+	go libraryService.Run(context.Background(), rescanInterval)
+
+	// Use parallel range requests for audio downloads if configured
+	if connections, err := strconv.Atoi(os.Getenv("DOWNLOAD_CONNECTIONS")); err == nil && connections > 1 {
+		downloadService.SetConnections(connections)
+		log.Printf("Using %d connections per download", connections)
+	}
+
+	// Configure the download queue
+	if concurrency, err := strconv.Atoi(os.Getenv("MAX_CONCURRENT_DOWNLOADS")); err == nil {
+		if err := taskService.Queue().SetConcurrency(concurrency); err != nil {
+			log.Printf("Warning: Invalid MAX_CONCURRENT_DOWNLOADS: %v", err)
+		}
+	}
+	if order := os.Getenv("QUEUE_ORDER"); order != "" {
+		if err := taskService.Queue().SetOrder(services.QueueOrder(order)); err != nil {
+			log.Printf("Warning: Invalid QUEUE_ORDER: %v", err)
+		}
+	}
+
+	// Configure automatic retries of failed downloads
+	retryPolicy := services.DefaultRetryPolicy()
+	if attempts, err := strconv.Atoi(os.Getenv("RETRY_MAX_ATTEMPTS")); err == nil && attempts > 0 {
+		retryPolicy.MaxAttempts = attempts
+	}
+	if delay, err := time.ParseDuration(os.Getenv("RETRY_BASE_DELAY")); err == nil && delay > 0 {
+		retryPolicy.BaseDelay = delay
+	}
+	if delay, err := time.ParseDuration(os.Getenv("RETRY_MAX_DELAY")); err == nil && delay > 0 {
+		retryPolicy.MaxDelay = delay
+	}
+	taskService.SetRetryPolicy(retryPolicy)
 
 	// Set download service for task service
 	taskService.SetDownloadService(downloadService)
 
+	// Reload task history and resume downloads interrupted by the last shutdown
+	if resumed, err := taskService.Restore(); err != nil {
+		log.Printf("Warning: Failed to restore tasks: %v", err)
+	} else if resumed > 0 {
+		log.Printf("Resumed %d interrupted tasks", resumed)
+	}
+
+	// Check subscribed shows for new episodes in the background
+	subscriptionService := services.NewSubscriptionService(filepath.Join(downloadsDir, ".subscriptions.json"), downloadService, taskService)
+	if err := subscriptionService.Load(); err != nil {
+		log.Printf("Warning: Failed to load subscriptions: %v", err)
+	}
+	go subscriptionService.Run(context.Background())
+	opmlService := services.NewOPMLService(libraryService, subscriptionService, taskService)
+
 	// Initialize handlers
 	episodeHandler := handlers.NewEpisodeHandler(episodeService)
 	taskHandler := handlers.NewTaskHandler(taskService)
+	adminHandler := handlers.NewAdminHandler(taskService)
+	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService)
+	opmlHandler := handlers.NewOPMLHandler(opmlService)
+	// PUBLIC_URL is the address podcast apps use, e.g. behind a reverse proxy
+	feedHandler := handlers.NewFeedHandler(feedService, os.Getenv("PUBLIC_URL"))
+	libraryHandler := handlers.NewLibraryHandler(libraryService)
 
 	// Setup routes
 	mux := http.NewServeMux()
@@ -55,10 +124,26 @@ func main() {
 
 	// Episode routes
 	mux.HandleFunc("/api/episodes", episodeHandler.GetEpisodes)
-	mux.HandleFunc("/api/episodes/", episodeHandler.GetShowNotes)
+	mux.HandleFunc("/api/episodes/", episodeHandler.HandleEpisode)
+	mux.HandleFunc("/api/library/rescan", libraryHandler.HandleRescan)
 
 	// Task routes
 	mux.HandleFunc("/api/tasks", taskHandler.HandleTasks)
+	mux.HandleFunc("/api/tasks/", taskHandler.HandleTask)
+	mux.HandleFunc("/api/tasks/events", taskHandler.HandleEvents)
+
+	// Subscription routes
+	mux.HandleFunc("/api/subscriptions", subscriptionHandler.HandleSubscriptions)
+	mux.HandleFunc("/api/subscriptions/", subscriptionHandler.HandleSubscription)
+	mux.HandleFunc("/api/rules/dry-run", subscriptionHandler.HandleDryRun)
+	mux.HandleFunc("/api/opml", opmlHandler.HandleOPML)
+
+	// Podcast feeds of the downloaded episodes
+	mux.HandleFunc("/feed.xml", feedHandler.HandleLibraryFeed)
+	mux.HandleFunc("/feeds/", feedHandler.HandlePodcastFeed)
+
+	// Admin routes
+	mux.HandleFunc("/api/admin/queue", adminHandler.HandleQueue)
 
 	// Static file server for frontend (SPA support - serve index.html for all non-API routes)
 	frontendFS := http.Dir("./frontend/dist")
@@ -107,8 +192,9 @@ func main() {
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Range")
+		w.Header().Set("Access-Control-Expose-Headers", "Content-Range, Content-Length, Accept-Ranges, ETag")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -0,0 +1,79 @@
+import { ref, onMounted, onUnmounted } from 'vue'
+import { apiClient } from '@/services/api'
+import { useTaskPolling } from './useTaskPolling'
+import type { DownloadTask, TaskEventType } from '@/types/task'
+
+const TASK_EVENTS: TaskEventType[] = ['created', 'progress', 'status_changed', 'completed', 'failed']
+
+export function useTaskEvents(
+  onTask: (task: DownloadTask, type: TaskEventType) => void,
+  fetchTasks: () => Promise<void>,
+  reconnectMs = 10000
+) {
+  const connected = ref(false)
+  const { startPolling, stopPolling } = useTaskPolling(fetchTasks, 2500, false)
+  let source: EventSource | null = null
+  let reconnectId: number | null = null
+  let lastEventId = ''
+
+  function connect() {
+    if (typeof EventSource === 'undefined') {
+      startPolling()
+      return
+    }
+
+    source = new EventSource(apiClient.taskEventsURL(lastEventId))
+
+    source.onopen = () => {
+      connected.value = true
+      stopPolling()
+    }
+
+    source.onerror = () => {
+      connected.value = false
+      startPolling()
+      // The browser retries by itself unless the connection was closed for good
+      if (source?.readyState === EventSource.CLOSED) {
+        source.close()
+        source = null
+        reconnectId = window.setTimeout(connect, reconnectMs)
+      }
+    }
+
+    for (const type of TASK_EVENTS) {
+      source.addEventListener(type, (e) => {
+        const event = e as MessageEvent
+        lastEventId = event.lastEventId
+        onTask(JSON.parse(event.data), type)
+      })
+    }
+
+    // Sent when missed events are no longer available on the server
+    source.addEventListener('resync', () => {
+      fetchTasks()
+    })
+  }
+
+  function disconnect() {
+    if (reconnectId !== null) {
+      clearTimeout(reconnectId)
+      reconnectId = null
+    }
+    source?.close()
+    source = null
+    connected.value = false
+  }
+
+  onMounted(() => {
+    connect()
+  })
+
+  onUnmounted(() => {
+    disconnect()
+    stopPolling()
+  })
+
+  return {
+    connected
+  }
+}
@@ -1,6 +1,6 @@
 import { ref, onMounted, onUnmounted } from 'vue'
 
-export function useTaskPolling(fetchCallback: () => Promise<void>, intervalMs = 2500) {
+export function useTaskPolling(fetchCallback: () => Promise<void>, intervalMs = 2500, autoStart = true) {
   const isPolling = ref(false)
   let intervalId: number | null = null
 
@@ -22,7 +22,9 @@ export function useTaskPolling(fetchCallback: () => Promise<void>, intervalMs =
   }
 
   onMounted(() => {
-    startPolling()
+    if (autoStart) {
+      startPolling()
+    }
   })
 
   onUnmounted(() => {
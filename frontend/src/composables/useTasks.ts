@@ -1,6 +1,6 @@
 import { ref } from 'vue'
 import { apiClient } from '@/services/api'
-import type { DownloadTask, CreateTaskRequest } from '@/types/task'
+import type { DownloadTask, CreateTaskRequest, TaskAction } from '@/types/task'
 
 export function useTasks() {
   const tasks = ref<DownloadTask[]>([])
@@ -24,8 +24,7 @@ export function useTasks() {
     error.value = null
     try {
       const request: CreateTaskRequest = { url }
-      const newTask = await apiClient.createTask(request)
-      tasks.value.unshift(newTask)
+      applyTask(await apiClient.createTask(request))
       return true
     } catch (e) {
       error.value = e instanceof Error ? e.message : 'Failed to create task'
@@ -35,11 +34,31 @@ export function useTasks() {
     }
   }
 
+  function applyTask(task: DownloadTask) {
+    const index = tasks.value.findIndex((t) => t.id === task.id)
+    if (index !== -1) {
+      tasks.value[index] = task
+    } else {
+      tasks.value.unshift(task)
+    }
+  }
+
+  async function runTaskAction(taskId: string, action: TaskAction) {
+    error.value = null
+    try {
+      applyTask(await apiClient.taskAction(taskId, action))
+    } catch (e) {
+      error.value = e instanceof Error ? e.message : `Failed to ${action} task`
+    }
+  }
+
   return {
     tasks,
     loading,
     error,
     fetchTasks,
-    createTask
+    createTask,
+    applyTask,
+    runTaskAction
   }
 }
@@ -1,5 +1,5 @@
-import type { PaginatedEpisodes } from '@/types/episode'
-import type { DownloadTask, CreateTaskRequest, APIError } from '@/types/task'
+import type { Chapter, EpisodeListOptions, PaginatedEpisodes } from '@/types/episode'
+import type { DownloadTask, CreateTaskRequest, APIError, TaskAction } from '@/types/task'
 
 const API_BASE_URL = import.meta.env.VITE_API_BASE_URL || 'http://localhost:8080/api'
 
@@ -28,14 +28,31 @@ class APIClient {
     }
   }
 
-  async getEpisodes(page = 1, pageSize = 20): Promise<PaginatedEpisodes> {
-    return this.request<PaginatedEpisodes>(`/episodes?page=${page}&pageSize=${pageSize}`)
+  async getEpisodes(page = 1, pageSize = 20, options: EpisodeListOptions = {}): Promise<PaginatedEpisodes> {
+    const params = new URLSearchParams({ page: String(page), pageSize: String(pageSize) })
+    for (const [key, value] of Object.entries(options)) {
+      if (value) params.set(key, value)
+    }
+    return this.request<PaginatedEpisodes>(`/episodes?${params}`)
   }
 
   async getShowNotes(episodeId: string): Promise<{ showNotes: string }> {
     return this.request<{ showNotes: string }>(`/episodes/${episodeId}/shownotes`)
   }
 
+  async getChapters(episodeId: string): Promise<{ chapters: Chapter[] }> {
+    return this.request<{ chapters: Chapter[] }>(`/episodes/${episodeId}/chapters`)
+  }
+
+  episodeAudioURL(episodeId: string): string {
+    return `${API_BASE_URL}/episodes/${episodeId}/audio`
+  }
+
+  episodeCoverURL(episodeId: string, size?: 128 | 512): string {
+    const query = size ? `?size=${size}` : ''
+    return `${API_BASE_URL}/episodes/${episodeId}/cover${query}`
+  }
+
   async getTasks(): Promise<DownloadTask[]> {
     return this.request<DownloadTask[]>('/tasks')
   }
@@ -46,6 +63,15 @@ class APIClient {
       body: JSON.stringify(request)
     })
   }
+
+  taskEventsURL(lastEventId?: string): string {
+    const query = lastEventId ? `?lastEventId=${encodeURIComponent(lastEventId)}` : ''
+    return `${API_BASE_URL}/tasks/events${query}`
+  }
+
+  async taskAction(taskId: string, action: TaskAction): Promise<DownloadTask> {
+    return this.request<DownloadTask>(`/tasks/${taskId}/${action}`, { method: 'POST' })
+  }
 }
 
 export const apiClient = new APIClient()
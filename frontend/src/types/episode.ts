@@ -4,7 +4,26 @@ export interface PodcastMetadata {
   episode_title?: string
   podcast_name?: string
   source_url?: string
+  episode_guid?: string
+  podcast_id?: string
+  cover_urls?: CoverURLs
   extracted_at: string
+  published_at?: string
+  publish_precision?: 'exact' | 'minute' | 'hour' | 'day' | 'week' | 'month' | 'year'
+  chapters?: Chapter[]
+}
+
+export interface CoverURLs {
+  original?: string
+  large?: string
+  medium?: string
+  small?: string
+  thumbnail?: string
+}
+
+export interface Chapter {
+  start: number // Seconds from the beginning of the episode
+  title: string
 }
 
 export interface Episode {
@@ -12,13 +31,30 @@ export interface Episode {
   title: string
   podcastName: string
   duration: string
+  durationSeconds: number
   fileSize: number
   downloadDate: string
+  publishedAt?: string
   showNotes: string
   filePath: string
   coverImagePath?: string
   sourceUrl?: string
   metadata?: PodcastMetadata
+  match?: SearchMatch // Present in search results
+}
+
+export interface SearchMatch {
+  score: number
+  title: string // HTML with the matching terms wrapped in <mark>
+  snippet: string // HTML excerpt of the show notes around the first match
+}
+
+export interface EpisodeListOptions {
+  sort?: 'downloadDate' | 'publishDate' | 'relevance'
+  q?: string
+  order?: 'asc' | 'desc'
+  since?: string
+  until?: string
 }
 
 export interface PaginatedEpisodes {
@@ -1,18 +1,52 @@
-export type TaskStatus = 'pending' | 'downloading' | 'completed' | 'failed'
+export type TaskStatus =
+  | 'pending'
+  | 'downloading'
+  | 'extracting_metadata'
+  | 'completed'
+  | 'failed'
+  | 'paused'
+  | 'cancelled'
 
 export interface DownloadTask {
   id: string
   url: string
+  source?: string
   status: TaskStatus
   createdAt: string
   completedAt?: string
   progress?: number
   errorMessage?: string
   episodeId?: string
+  priority?: number
+  queuePosition?: number
+  bytesDownloaded?: number
+  totalBytes?: number
+  speed?: number
+  eta?: number
+  attempt?: number
+  maxAttempts?: number
+  nextRetryAt?: string
+  kind?: TaskKind
+  parentId?: string
+  childIds?: string[]
+  skipped?: number
+  unlisted?: number
 }
 
+export type TaskKind = 'batch'
+
+export type TaskAction = 'cancel' | 'pause' | 'resume' | 'retry'
+
+export type TaskEventType = 'created' | 'progress' | 'status_changed' | 'completed' | 'failed'
+
 export interface CreateTaskRequest {
   url: string
+  priority?: number
+  // Episode selection for show URLs
+  latest?: number
+  since?: string
+  until?: string
+  skipExisting?: boolean
 }
 
 export interface APIError {
@@ -28,6 +28,9 @@ type Config struct {
 	// ValidateFiles controls whether to validate downloaded audio files
 	ValidateFiles bool
 
+	// Connections is the number of parallel range requests used per download
+	Connections int
+
 	// ServerHost is the host address for the HTTP server
 	ServerHost string
 
@@ -48,6 +51,7 @@ func DefaultConfig() *Config {
 		RetryDelay:        1 * time.Second,
 		ShowProgress:      true,
 		ValidateFiles:     true,
+		Connections:       1,
 		ServerHost:        "localhost",
 		ServerPort:        8080,
 		Verbose:           false,
@@ -71,5 +75,10 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("max retries cannot be negative")
 	}
 
+	// Check Connections is at least one
+	if c.Connections < 1 {
+		return fmt.Errorf("connections must be at least 1")
+	}
+
 	return nil
 }
@@ -16,7 +16,7 @@ import (
 
 // DownloadService handles the complete download workflow for podcast episodes.
 type DownloadService struct {
-	extractor       downloader.URLExtractor
+	sources         *downloader.Registry
 	fileDownloader  downloader.FileDownloader
 	imageDownloader *downloader.HTTPImageDownloader
 	showNotesSaver  *downloader.PlainTextShowNotesSaver
@@ -25,14 +25,14 @@ type DownloadService struct {
 }
 
 // NewDownloadService creates a new download service with all required components.
-func NewDownloadService(outputDir string, logger *log.Logger) *DownloadService {
+// Episode URLs are resolved with the given source registry.
+func NewDownloadService(outputDir string, sources *downloader.Registry, logger *log.Logger) *DownloadService {
 	// Create HTTP client with appropriate timeouts
-	metadataClient := downloader.NewHTTPClient(30 * time.Second)
 	downloadClient := &http.Client{Timeout: 1 * time.Hour}
 	imageClient := &http.Client{Timeout: 2 * time.Minute}
 
 	return &DownloadService{
-		extractor:       downloader.NewHTMLExtractor(metadataClient),
+		sources:         sources,
 		fileDownloader:  downloader.NewHTTPDownloader(downloadClient, false),
 		imageDownloader: downloader.NewHTTPImageDownloader(imageClient, 10*1024*1024),
 		showNotesSaver:  downloader.NewPlainTextShowNotesSaver(),
@@ -57,7 +57,7 @@ func (s *DownloadService) DownloadEpisode(ctx context.Context, url string, progr
 
 	// Step 1: Extract metadata
 	s.logger.Printf("Extracting metadata from: %s", url)
-	metadata, err := s.extractor.ExtractURL(ctx, url)
+	metadata, _, err := s.sources.Resolve(ctx, url)
 	if err != nil {
 		result.Error = fmt.Errorf("failed to extract metadata: %w", err)
 		result.Success = false
@@ -77,14 +77,25 @@ func (s *DownloadService) DownloadEpisode(ctx context.Context, url string, progr
 	}
 
 	// Step 3: Download audio file
-	audioPath := filepath.Join(podcastDir, "podcast.m4a")
+	audioFile := "podcast" + metadata.AudioExtension()
+	audioPath := filepath.Join(podcastDir, audioFile)
 	s.logger.Printf("Downloading audio to: %s", audioPath)
 
 	if progressCallback != nil {
 		progressCallback(10)
 	}
 
-	bytesWritten, err := s.fileDownloader.Download(ctx, metadata.AudioURL, audioPath, nil)
+	// Report audio progress within the 10-60% band
+	var audioProgress downloader.ProgressFunc
+	if progressCallback != nil {
+		audioProgress = func(p downloader.Progress) {
+			if percent := p.Percent(); percent >= 0 {
+				progressCallback(10 + int(percent*50))
+			}
+		}
+	}
+
+	bytesWritten, err := s.fileDownloader.Download(ctx, metadata.AudioURL, audioPath, audioProgress)
 	if err != nil {
 		result.Error = fmt.Errorf("audio download failed: %w", err)
 		result.Success = false
@@ -109,10 +120,9 @@ func (s *DownloadService) DownloadEpisode(ctx context.Context, url string, progr
 
 	// Step 5: Download cover image (optional, graceful degradation)
 	if metadata.CoverURL != "" {
-		coverPath := filepath.Join(podcastDir, "cover.jpg")
-		s.logger.Printf("Downloading cover to: %s", coverPath)
+		s.logger.Printf("Downloading cover to: %s", podcastDir)
 
-		_, err := s.imageDownloader.Download(ctx, metadata.CoverURL, coverPath, nil)
+		coverPath, err := s.imageDownloader.DownloadCover(ctx, metadata.CoverURL, podcastDir, nil)
 		if err != nil {
 			s.logger.Printf("Warning: Cover image download failed: %v (continuing anyway)", err)
 		} else {
@@ -145,7 +155,7 @@ func (s *DownloadService) DownloadEpisode(ctx context.Context, url string, progr
 
 	// Step 7: Save metadata file
 	metadataPath := filepath.Join(podcastDir, ".metadata.json")
-	if err := s.saveMetadataFile(metadataPath, url, metadata, result); err != nil {
+	if err := s.saveMetadataFile(metadataPath, url, audioFile, metadata, result); err != nil {
 		s.logger.Printf("Warning: Failed to save metadata file: %v", err)
 		// Don't fail the download if metadata saving fails
 	}
@@ -159,16 +169,16 @@ func (s *DownloadService) DownloadEpisode(ctx context.Context, url string, progr
 }
 
 // saveMetadataFile saves the .metadata.json file with download information.
-func (s *DownloadService) saveMetadataFile(path, url string, metadata *downloader.EpisodeMetadata, result *DownloadResult) error {
+func (s *DownloadService) saveMetadataFile(path, url, audioFile string, metadata *downloader.EpisodeMetadata, result *DownloadResult) error {
 	metadataFile := MetadataFile{
 		SourceURL:    url,
 		Title:        metadata.Title,
 		DownloadedAt: time.Now().Format(time.RFC3339),
-		AudioFile:    "podcast.m4a",
+		AudioFile:    audioFile,
 	}
 
 	if result.CoverPath != "" {
-		metadataFile.CoverFile = "cover.jpg"
+		metadataFile.CoverFile = filepath.Base(result.CoverPath)
 	}
 
 	if result.ShowNotesPath != "" {
@@ -4,20 +4,26 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"path/filepath"
 	"sync"
 	"time"
 
-	"github.com/meixg/podcast-reader/pkg/validator"
+	"github.com/meixg/podcast-reader/pkg/downloader"
 )
 
+// DefaultMaxConcurrentDownloads is the number of background downloads that run at once
+const DefaultMaxConcurrentDownloads = 3
+
 // Manager manages download tasks and coordinates with the downloader
 type Manager struct {
 	store           *Store
 	catalog         *Catalog
-	validator       validator.URLValidator
+	sources         *downloader.Registry
 	logger          *log.Logger
 	downloadService *DownloadService
+	wg              sync.WaitGroup
+	slots           chan struct{} // Semaphore bounding concurrent background downloads
 }
 
 // NewManager creates a new task manager
@@ -25,23 +31,40 @@ func NewManager(store *Store, catalog *Catalog, logger *log.Logger) *Manager {
 	return &Manager{
 		store:           store,
 		catalog:         catalog,
-		validator:       validator.NewXiaoyuzhouURLValidator(),
+		sources:         downloader.NewDefaultRegistry(&http.Client{Timeout: 30 * time.Second}),
 		logger:          logger,
 		downloadService: nil, // Will be set after creation with SetOutputDirectory
+		slots:           make(chan struct{}, DefaultMaxConcurrentDownloads),
+	}
+}
+
+// SetMaxConcurrentDownloads sets how many background downloads may run at once.
+// Must be called before any task is started.
+func (m *Manager) SetMaxConcurrentDownloads(n int) {
+	if n < 1 {
+		n = 1
 	}
+	m.slots = make(chan struct{}, n)
+}
+
+// Wait blocks until all background downloads have finished
+func (m *Manager) Wait() {
+	m.wg.Wait()
 }
 
 // SetOutputDirectory sets the output directory and initializes the download service
 func (m *Manager) SetOutputDirectory(outputDir string) {
-	m.downloadService = NewDownloadService(outputDir, m.logger)
+	m.downloadService = NewDownloadService(outputDir, m.sources, m.logger)
 }
 
 // SubmitTask submits a new download task
 func (m *Manager) SubmitTask(url string) (*DownloadTask, error) {
 	// Validate URL
-	valid, errMsg := m.validator.ValidateURL(url)
-	if !valid {
-		return nil, fmt.Errorf("invalid URL: %s", errMsg)
+	if _, err := m.sources.Lookup(url); err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if m.sources.IsShow(url) {
+		return nil, fmt.Errorf("invalid URL: %s is a show, not an episode", url)
 	}
 
 	// Check if URL already exists in catalog (already downloaded)
@@ -292,13 +315,17 @@ func (m *Manager) ExecuteDownloadWithRetry(task *DownloadTask) error {
 	return fmt.Errorf("download failed after %d attempts: %w", maxRetries, lastErr)
 }
 
-var wg sync.WaitGroup
-
-// BackgroundDownload launches a download in a goroutine with retry logic
+// BackgroundDownload launches a download in a goroutine with retry logic.
+// The task stays pending until one of the download slots is free.
 func (m *Manager) BackgroundDownload(task *DownloadTask) {
-	wg.Add(1)
+	m.wg.Add(1)
 	go func() {
-		defer wg.Done()
+		defer m.wg.Done()
+
+		// Wait for a free download slot
+		m.slots <- struct{}{}
+		defer func() { <-m.slots }()
+
 		m.logger.Printf("Starting background download for task %s", task.ID)
 
 		// Update status to in-progress
@@ -338,9 +365,11 @@ func (m *Manager) BackgroundDownload(task *DownloadTask) {
 // CreateAndStartTask creates a task and starts background download (T031)
 func (m *Manager) CreateAndStartTask(url string) (*DownloadTask, error) {
 	// Validate URL
-	valid, errMsg := m.validator.ValidateURL(url)
-	if !valid {
-		return nil, fmt.Errorf("invalid URL: %s", errMsg)
+	if _, err := m.sources.Lookup(url); err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if m.sources.IsShow(url) {
+		return nil, fmt.Errorf("invalid URL: %s is a show, not an episode", url)
 	}
 
 	// Check for duplicate URL (in-progress)
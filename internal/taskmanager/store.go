@@ -5,6 +5,12 @@ import (
 )
 
 // Store manages in-memory task storage with thread-safe access
+//
+// Store is deliberately not persisted. It only backs the Manager behind the
+// web/middleware handlers, which no binary serves, and the server image is
+// built from cmd, pkg and web alone (see the Dockerfile). The server's tasks
+// live in services.TaskService and survive restarts through
+// services.JournalTaskStore.
 type Store struct {
 	mu         sync.RWMutex
 	tasks      map[string]*DownloadTask // key: task ID
@@ -0,0 +1,56 @@
+package downloader
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/meixg/podcast-reader/pkg/mediainfo"
+)
+
+// TagAudio writes the episode's title, podcast name, publish date, show notes,
+// page URL, cover image and chapters into a downloaded M4A file, so players
+// don't show every episode as "podcast". Other formats are left unchanged.
+// coverPath may be empty or missing; only JPEG and PNG covers are embedded.
+func TagAudio(audioPath, coverPath string, metadata *EpisodeMetadata) error {
+	if !strings.EqualFold(filepath.Ext(audioPath), ".m4a") {
+		return nil
+	}
+
+	tags := mediainfo.MP4Tags{
+		Title:   strings.TrimSpace(metadata.Title),
+		Artist:  metadata.PodcastName,
+		Album:   metadata.PodcastName,
+		Comment: metadata.PageURL,
+	}
+	if !metadata.PublicationDate.IsZero() {
+		tags.Date = metadata.PublicationDate.Format("2006-01-02")
+	}
+	if metadata.ShowNotes != "" {
+		tags.Description = NewPlainTextShowNotesSaver().FormatHTMLToText(metadata.ShowNotes)
+		for _, chapter := range ParseChapters(metadata.ShowNotes) {
+			tags.Chapters = append(tags.Chapters, mediainfo.Chapter{
+				Start: time.Duration(chapter.Start) * time.Second,
+				Title: chapter.Title,
+			})
+		}
+	}
+	if coverPath != "" {
+		if cover, err := os.ReadFile(coverPath); err == nil && isJPEGOrPNG(cover) {
+			tags.Cover = cover
+		}
+	}
+
+	if err := mediainfo.WriteMP4Tags(audioPath, tags); err != nil {
+		return fmt.Errorf("写入音频标签失败: %w", err)
+	}
+	return nil
+}
+
+// isJPEGOrPNG reports whether data starts with a JPEG or PNG signature.
+func isJPEGOrPNG(data []byte) bool {
+	return bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}) || bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n"))
+}
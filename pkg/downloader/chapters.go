@@ -0,0 +1,81 @@
+package downloader
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/meixg/podcast-reader/pkg/models"
+)
+
+// minChapters is the fewest timestamp lines treated as a chapter list; a
+// single timestamp is more likely a mention than a table of contents.
+const minChapters = 2
+
+// lineBreakTags matches tags that end a line of show notes.
+var lineBreakTags = regexp.MustCompile(`(?i)<br\s*/?>|</(p|li|div|h[1-6]|blockquote|section)>`)
+
+// Chapter lines start or end with a timestamp such as "03:12", "1:02:03",
+// "[03:12]" or "（03:12）", optionally after a list bullet or number.
+var (
+	leadingTimestamp  = regexp.MustCompile(`^[\s•·*>\-]*(?:\d+[.、]\s*)?[\[【(（]?((?:\d{1,2}[:：])?\d{1,2}[:：]\d{2})[\]】)）]?[\s*]*[-–—|｜:：·、]?\s*(.+)$`)
+	trailingTimestamp = regexp.MustCompile(`^[\s•·*>\-]*(?:\d+[.、]\s+)?(.+?)\s*[-–—|｜]?\s*[\[【(（]?((?:\d{1,2}[:：])?\d{1,2}[:：]\d{2})[\]】)）]?$`)
+)
+
+// ParseChapters extracts a chapter list from show notes, which may be HTML
+// or plain text, using the lines that start or end with a timestamp.
+// Chapters are sorted by start time; nil is returned if there are fewer
+// than two.
+func ParseChapters(showNotes string) []models.Chapter {
+	html := lineBreakTags.ReplaceAllString(showNotes, "\n$0")
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[int]bool)
+	var chapters []models.Chapter
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		line = strings.TrimSpace(line)
+		var timestamp, title string
+		if m := leadingTimestamp.FindStringSubmatch(line); m != nil {
+			timestamp, title = m[1], m[2]
+		} else if m := trailingTimestamp.FindStringSubmatch(line); m != nil {
+			timestamp, title = m[2], m[1]
+		} else {
+			continue
+		}
+
+		start, ok := parseTimestamp(timestamp)
+		title = strings.TrimSpace(strings.Trim(title, "*"))
+		if !ok || title == "" || seen[start] {
+			continue
+		}
+		seen[start] = true
+		chapters = append(chapters, models.Chapter{Start: start, Title: title})
+	}
+
+	if len(chapters) < minChapters {
+		return nil
+	}
+	sort.SliceStable(chapters, func(i, j int) bool {
+		return chapters[i].Start < chapters[j].Start
+	})
+	return chapters
+}
+
+// parseTimestamp converts "MM:SS" or "H:MM:SS" to seconds.
+func parseTimestamp(s string) (int, bool) {
+	seconds := 0
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == ':' || r == '：' })
+	for i, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil || i > 0 && v >= 60 {
+			return 0, false
+		}
+		seconds = seconds*60 + v
+	}
+	return seconds, true
+}
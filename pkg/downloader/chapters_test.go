@@ -0,0 +1,47 @@
+package downloader
+
+import (
+	"testing"
+
+	"github.com/meixg/podcast-reader/pkg/models"
+)
+
+func TestParseChapters(t *testing.T) {
+	tests := []struct {
+		name      string
+		showNotes string
+		want      []models.Chapter
+	}{
+		{
+			"html paragraphs and line breaks",
+			`<p>本期嘉宾：张三</p><p>时间轴：</p><p>00:00 开场<br/>03:12 话题一：城市</p><p><strong>1:02:03</strong> 结语</p>`,
+			[]models.Chapter{{Start: 0, Title: "开场"}, {Start: 192, Title: "话题一：城市"}, {Start: 3723, Title: "结语"}},
+		},
+		{
+			"list items with brackets and separators",
+			`<ul><li>[05:30] - 第二部分</li><li>【01:15】第一部分</li><li>（10:00）｜第三部分</li></ul>`,
+			[]models.Chapter{{Start: 75, Title: "第一部分"}, {Start: 330, Title: "第二部分"}, {Start: 600, Title: "第三部分"}},
+		},
+		{
+			"plain text with trailing timestamps",
+			"• 开场 00:00\n• 聊聊电影 12:45\n1. 总结 (40:10)",
+			[]models.Chapter{{Start: 0, Title: "开场"}, {Start: 765, Title: "聊聊电影"}, {Start: 2410, Title: "总结"}},
+		},
+		{"single timestamp", `<p>我们在 03:12 提到的书</p>`, nil},
+		{"no timestamps", `<p>欢迎收听</p>`, nil},
+		{"invalid seconds", "01:75 第一\n02:99 第二", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseChapters(tt.showNotes)
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseChapters() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("chapter %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -2,10 +2,13 @@ package downloader
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 )
 
 // Define file download error types
@@ -17,16 +20,44 @@ var (
 	ErrInvalidAudio      = fmt.Errorf("音频文件无效")
 )
 
+// StatusError reports an unexpected HTTP status code returned by a server.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP %d", e.StatusCode)
+}
+
+// Temporary reports whether the request may succeed if it is repeated later.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusRequestTimeout ||
+		e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode >= 500
+}
+
+const (
+	// partSuffix is appended to the destination path while a download is in progress.
+	partSuffix = ".part"
+	// etagSuffix is appended to the partial file path to remember the ETag it was fetched with.
+	etagSuffix = ".etag"
+)
+
 // FileDownloader defines the interface for downloading files with progress tracking.
 type FileDownloader interface {
 	// Download fetches the audio file and writes it to the local filesystem.
-	Download(ctx context.Context, audioURL, filePath string, progress io.Writer) (bytesWritten int64, err error)
+	// progress is optional and receives throttled updates including the total size.
+	Download(ctx context.Context, audioURL, filePath string, progress ProgressFunc) (bytesWritten int64, err error)
 
 	// ValidateFile checks if the downloaded file is a valid audio file.
 	ValidateFile(filePath string) error
 }
 
 // HTTPDownloader implements FileDownloader with HTTP client and progress tracking.
+//
+// Data is written to "<filePath>.part" first. If a partial file is left over from
+// an interrupted download, the transfer resumes with an HTTP Range request and the
+// partial file is renamed to filePath only after it passes ValidateFile.
 type HTTPDownloader struct {
 	// client is the HTTP client to use for downloads
 	client *http.Client
@@ -42,49 +73,216 @@ func NewHTTPDownloader(client *http.Client, showProgress bool) *HTTPDownloader {
 	}
 }
 
+// PartPath returns the path of the partial file used while downloading filePath.
+func PartPath(filePath string) string {
+	return filePath + partSuffix
+}
+
+// HasPartial reports whether an interrupted download exists for filePath.
+func HasPartial(filePath string) bool {
+	if info, err := os.Stat(PartPath(filePath)); err == nil && info.Size() > 0 {
+		return true
+	}
+	return len(segmentFiles(filePath)) > 0
+}
+
 // Download fetches the audio file and writes it to the local filesystem.
-func (d *HTTPDownloader) Download(ctx context.Context, audioURL, filePath string, progress io.Writer) (int64, error) {
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "GET", audioURL, nil)
-	if err != nil {
-		return 0, fmt.Errorf("%w: %v", ErrConnectionRefused, err)
+// The returned byte count is the size of the complete file, including any
+// bytes that were already present from a previous attempt.
+func (d *HTTPDownloader) Download(ctx context.Context, audioURL, filePath string, progress ProgressFunc) (int64, error) {
+	partPath := PartPath(filePath)
+	etagPath := partPath + etagSuffix
+
+	// Determine how much has already been downloaded
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+	savedETag := ""
+	if data, err := os.ReadFile(etagPath); err == nil {
+		savedETag = strings.TrimSpace(string(data))
 	}
 
-	// Execute request
-	resp, err := d.client.Do(req)
+	resp, err := d.request(ctx, audioURL, offset, savedETag)
 	if err != nil {
-		return 0, fmt.Errorf("%w: %v", ErrNetworkTimeout, err)
+		return 0, err
 	}
 	defer resp.Body.Close()
 
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("下载失败: HTTP %d", resp.StatusCode)
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		// Make sure the server resumed exactly where we stopped
+		start, ok := parseContentRangeStart(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			resp.Body.Close()
+			return d.restart(ctx, audioURL, filePath, progress)
+		}
+	case resp.StatusCode == http.StatusOK:
+		// Server ignored the range (or the resource changed); start over
+		offset = 0
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The partial file may already hold the complete content
+		resp.Body.Close()
+		if d.ValidateFile(partPath) == nil {
+			if err := d.finish(filePath); err != nil {
+				return 0, err
+			}
+			return offset, nil
+		}
+		return d.restart(ctx, audioURL, filePath, progress)
+	default:
+		return 0, fmt.Errorf("下载失败: %w", &StatusError{StatusCode: resp.StatusCode})
+	}
+
+	// Only keep partial data around if the server lets us resume it later
+	resumable := resp.StatusCode == http.StatusPartialContent || resp.Header.Get("Accept-Ranges") == "bytes"
+
+	// Remember the ETag so a later resume can detect a changed resource
+	if etag := resp.Header.Get("ETag"); etag != "" && resumable {
+		os.WriteFile(etagPath, []byte(etag), 0644)
+	} else {
+		os.Remove(etagPath)
 	}
 
-	// Create output file
-	out, err := os.Create(filePath)
+	// Open partial file (append when resuming, truncate otherwise)
+	flags := os.O_CREATE | os.O_WRONLY
+	if offset > 0 {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+	}
+	out, err := os.OpenFile(partPath, flags, 0644)
 	if err != nil {
 		return 0, fmt.Errorf("%w: %v", ErrPermissionDenied, err)
 	}
-	defer out.Close()
 
-	// Track bytes written
-	var bytesWritten int64
+	// Total size is the remaining length plus what we already have
+	total := int64(-1)
+	if resp.StatusCode == http.StatusPartialContent {
+		if size, ok := parseContentRangeTotal(resp.Header.Get("Content-Range")); ok {
+			total = size
+		}
+	}
+	if total < 0 && resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
 
-	// Use progress writer if provided
+	// Use progress tracker if provided
 	var writer io.Writer = out
-	if progress != nil {
-		writer = io.MultiWriter(out, progress)
+	tracker := newProgressTracker(progress, offset, total)
+	if tracker != nil {
+		writer = io.MultiWriter(out, tracker)
 	}
 
 	// Copy with progress tracking
-	bytesWritten, err = io.Copy(writer, resp.Body)
+	written, err := io.Copy(writer, resp.Body)
+	tracker.Flush()
+	closeErr := out.Close()
 	if err != nil {
+		if !resumable {
+			RemovePartial(filePath)
+		}
 		return 0, fmt.Errorf("下载中断: %w", err)
 	}
+	if closeErr != nil {
+		return 0, fmt.Errorf("%w: %v", ErrDiskFull, closeErr)
+	}
+
+	if err := d.finish(filePath); err != nil {
+		return 0, err
+	}
+
+	return offset + written, nil
+}
+
+// request issues the GET request, asking for the remaining bytes when offset > 0.
+func (d *HTTPDownloader) request(ctx context.Context, audioURL string, offset int64, etag string) (*http.Response, error) {
+	// Create HTTP request
+	req, err := http.NewRequestWithContext(ctx, "GET", audioURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConnectionRefused, err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if etag != "" {
+			// Only honour the range if the resource is unchanged
+			req.Header.Set("If-Range", etag)
+		}
+	}
 
-	return bytesWritten, nil
+	// Execute request
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNetworkTimeout, err)
+	}
+	return resp, nil
+}
+
+// restart discards the partial file and downloads from byte zero.
+func (d *HTTPDownloader) restart(ctx context.Context, audioURL, filePath string, progress ProgressFunc) (int64, error) {
+	if err := RemovePartial(filePath); err != nil {
+		return 0, err
+	}
+	return d.Download(ctx, audioURL, filePath, progress)
+}
+
+// finish validates the partial file and atomically moves it to filePath.
+func (d *HTTPDownloader) finish(filePath string) error {
+	partPath := PartPath(filePath)
+	if err := d.ValidateFile(partPath); err != nil {
+		// A corrupt partial file cannot be resumed
+		RemovePartial(filePath)
+		return err
+	}
+	if err := os.Rename(partPath, filePath); err != nil {
+		return fmt.Errorf("%w: %v", ErrPermissionDenied, err)
+	}
+	os.Remove(partPath + etagSuffix)
+	return nil
+}
+
+// RemovePartial deletes any partial download state kept for filePath.
+func RemovePartial(filePath string) error {
+	partPath := PartPath(filePath)
+	paths := append([]string{partPath, partPath + etagSuffix}, segmentFiles(filePath)...)
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("删除临时文件失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseContentRangeStart extracts the first byte position from a
+// "bytes start-end/total" Content-Range header.
+func parseContentRangeStart(header string) (int64, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	dash := strings.Index(spec, "-")
+	if dash <= 0 {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(spec[:dash]), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return start, true
+}
+
+// parseContentRangeTotal extracts the complete length from a
+// "bytes start-end/total" Content-Range header.
+func parseContentRangeTotal(header string) (int64, bool) {
+	slash := strings.LastIndex(header, "/")
+	if slash == -1 {
+		return 0, false
+	}
+	total, err := strconv.ParseInt(strings.TrimSpace(header[slash+1:]), 10, 64)
+	if err != nil || total <= 0 {
+		return 0, false
+	}
+	return total, true
 }
 
 // ValidateFile checks if the downloaded file is a valid audio file.
@@ -109,9 +307,14 @@ func (d *HTTPDownloader) ValidateFile(filePath string) error {
 		return ErrInvalidAudio
 	}
 
-	if string(header[4:8]) != "ftyp" {
-		return fmt.Errorf("%w: 下载的文件不是有效的M4A音频", ErrInvalidAudio)
+	if string(header[4:8]) == "ftyp" {
+		return nil
 	}
 
-	return nil
+	// MP3 files (common in RSS feeds) start with an ID3 tag or an MPEG frame sync
+	if string(header[:3]) == "ID3" || (header[0] == 0xFF && header[1]&0xE0 == 0xE0) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: 下载的文件不是有效的M4A或MP3音频", ErrInvalidAudio)
 }
@@ -0,0 +1,261 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeM4A returns content that passes ValidateFile.
+func fakeM4A(size int) []byte {
+	data := make([]byte, size)
+	copy(data, []byte{0x00, 0x00, 0x00, 0x20, 'f', 't', 'y', 'p', 'M', '4', 'A', ' '})
+	for i := 12; i < size; i++ {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+func TestHTTPDownloader_Download_Fresh(t *testing.T) {
+	content := fakeM4A(4096)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "podcast.m4a", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	dest := filepath.Join(t.TempDir(), "podcast.m4a")
+	d := NewHTTPDownloader(server.Client(), false)
+
+	n, err := d.Download(context.Background(), server.URL, dest, nil)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if n != int64(len(content)) {
+		t.Errorf("bytes = %d, want %d", n, len(content))
+	}
+
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Error("downloaded content does not match")
+	}
+	if _, err := os.Stat(PartPath(dest)); !os.IsNotExist(err) {
+		t.Error("partial file should be removed after completion")
+	}
+}
+
+func TestHTTPDownloader_Download_Resume(t *testing.T) {
+	content := fakeM4A(8192)
+	var rangeHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeHeader = r.Header.Get("Range")
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "podcast.m4a", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	dest := filepath.Join(t.TempDir(), "podcast.m4a")
+	if err := os.WriteFile(PartPath(dest), content[:3000], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(PartPath(dest)+etagSuffix, []byte(`"v1"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var last Progress
+	d := NewHTTPDownloader(server.Client(), false)
+	n, err := d.Download(context.Background(), server.URL, dest, func(p Progress) { last = p })
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+
+	if rangeHeader != "bytes=3000-" {
+		t.Errorf("Range = %q, want bytes=3000-", rangeHeader)
+	}
+	if n != int64(len(content)) {
+		t.Errorf("bytes = %d, want %d", n, len(content))
+	}
+	if last.Downloaded != int64(len(content)) || last.Total != int64(len(content)) {
+		t.Errorf("progress = %d/%d, want %d/%d", last.Downloaded, last.Total, len(content), len(content))
+	}
+
+	got, _ := os.ReadFile(dest)
+	if !bytes.Equal(got, content) {
+		t.Error("resumed content does not match")
+	}
+}
+
+func TestHTTPDownloader_Download_ChangedResourceRestarts(t *testing.T) {
+	content := fakeM4A(4096)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "podcast.m4a", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	dest := filepath.Join(t.TempDir(), "podcast.m4a")
+	os.WriteFile(PartPath(dest), []byte(strings.Repeat("x", 1000)), 0644)
+	os.WriteFile(PartPath(dest)+etagSuffix, []byte(`"v1"`), 0644)
+
+	d := NewHTTPDownloader(server.Client(), false)
+	if _, err := d.Download(context.Background(), server.URL, dest, nil); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+
+	got, _ := os.ReadFile(dest)
+	if !bytes.Equal(got, content) {
+		t.Error("stale partial data was not discarded")
+	}
+}
+
+func TestHTTPDownloader_Download_InvalidFileNotRenamed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>not audio</html>"))
+	}))
+	defer server.Close()
+
+	dest := filepath.Join(t.TempDir(), "podcast.m4a")
+	d := NewHTTPDownloader(server.Client(), false)
+	if _, err := d.Download(context.Background(), server.URL, dest, nil); err == nil {
+		t.Fatal("expected validation error")
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("invalid file should not be moved into place")
+	}
+}
+
+func TestSegmentedDownloader_Download(t *testing.T) {
+	content := fakeM4A(5*minSegmentSize + 123)
+	var mu sync.Mutex
+	ranges := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges[r.Header.Get("Range")] = true
+		mu.Unlock()
+		http.ServeContent(w, r, "podcast.m4a", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	dest := filepath.Join(t.TempDir(), "podcast.m4a")
+	d := NewSegmentedDownloader(server.Client(), 4, false)
+
+	var mu2 sync.Mutex
+	var last Progress
+	n, err := d.Download(context.Background(), server.URL, dest, func(p Progress) {
+		mu2.Lock()
+		last = p
+		mu2.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if n != int64(len(content)) {
+		t.Errorf("bytes = %d, want %d", n, len(content))
+	}
+	if last.Downloaded != int64(len(content)) || last.Total != int64(len(content)) {
+		t.Errorf("progress = %d/%d, want %d/%d", last.Downloaded, last.Total, len(content), len(content))
+	}
+
+	got, _ := os.ReadFile(dest)
+	if !bytes.Equal(got, content) {
+		t.Error("joined content does not match")
+	}
+	if len(ranges) != 5 { // probe + 4 segments
+		t.Errorf("requests = %d, want 5", len(ranges))
+	}
+	if HasPartial(dest) {
+		t.Error("segment files should be removed after completion")
+	}
+}
+
+func TestSegmentedDownloader_Download_ShortSegment(t *testing.T) {
+	content := fakeM4A(4 * minSegmentSize)
+	var truncate atomic.Bool
+	truncate.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The last segment ends early without a Content-Length to catch it
+		if truncate.Load() && r.Header.Get("Range") == fmt.Sprintf("bytes=%d-%d", 3*minSegmentSize, len(content)-1) {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", 3*minSegmentSize, len(content)-1, len(content)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(content[3*minSegmentSize : 3*minSegmentSize+100])
+			return
+		}
+		http.ServeContent(w, r, "podcast.m4a", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	dest := filepath.Join(t.TempDir(), "podcast.m4a")
+	// A segment file left by an earlier attempt split into more segments
+	stale := PartPath(dest) + ".seg5of7"
+	if err := os.WriteFile(stale, []byte("stale"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewSegmentedDownloader(server.Client(), 4, false)
+	if _, err := d.Download(context.Background(), server.URL, dest, nil); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("Download() of a short segment error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("a short segment should not be joined into the file")
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("segment files of a different split should be removed")
+	}
+
+	// The retry resumes the short segment
+	truncate.Store(false)
+	if _, err := d.Download(context.Background(), server.URL, dest, nil); err != nil {
+		t.Fatalf("Download() retry error = %v", err)
+	}
+	got, _ := os.ReadFile(dest)
+	if !bytes.Equal(got, content) {
+		t.Error("resumed content does not match")
+	}
+}
+
+func TestSegmentedDownloader_Download_NoRangeSupport(t *testing.T) {
+	content := fakeM4A(3 * minSegmentSize)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	dest := filepath.Join(t.TempDir(), "podcast.m4a")
+	d := NewSegmentedDownloader(server.Client(), 4, false)
+	if _, err := d.Download(context.Background(), server.URL, dest, nil); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+
+	got, _ := os.ReadFile(dest)
+	if !bytes.Equal(got, content) {
+		t.Error("single-stream fallback content does not match")
+	}
+}
+
+func TestProgress_Remaining(t *testing.T) {
+	p := Progress{Downloaded: 500, Total: 1500, Rate: 100}
+	if got := p.Remaining(); got != 10*time.Second {
+		t.Errorf("Remaining() = %v, want 10s", got)
+	}
+	if got := p.Percent(); got < 0.33 || got > 0.34 {
+		t.Errorf("Percent() = %v, want ~0.333", got)
+	}
+
+	unknown := Progress{Downloaded: 500, Total: -1, Rate: 100}
+	if unknown.Remaining() != -1 || unknown.Percent() != -1 {
+		t.Error("unknown total should report -1")
+	}
+}
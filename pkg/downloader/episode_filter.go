@@ -0,0 +1,54 @@
+package downloader
+
+import (
+	"sort"
+	"time"
+)
+
+// EpisodeFilter selects which episodes of a show to download.
+// The zero value selects every episode.
+type EpisodeFilter struct {
+	Latest int       // Keep only the newest N episodes; 0 keeps all
+	Since  time.Time // Skip episodes published before Since, if set
+	Until  time.Time // Skip episodes published after Until, if set
+
+	// Skip reports whether an episode should be left out, e.g. because it
+	// was downloaded before. Optional.
+	Skip func(episode *EpisodeMetadata) bool
+}
+
+// Apply returns the selected episodes, newest first. Episodes without a
+// publication date are kept unless a date range is set.
+// Skipped episodes still count towards Latest, so "latest 5" never reaches
+// further back than the five newest episodes.
+func (f EpisodeFilter) Apply(episodes []*EpisodeMetadata) (selected []*EpisodeMetadata, skipped int) {
+	sorted := make([]*EpisodeMetadata, len(episodes))
+	copy(sorted, episodes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].PublicationDate.After(sorted[j].PublicationDate)
+	})
+
+	inRange := sorted[:0:0]
+	for _, episode := range sorted {
+		date := episode.PublicationDate
+		if !f.Since.IsZero() && (date.IsZero() || date.Before(f.Since)) {
+			continue
+		}
+		if !f.Until.IsZero() && (date.IsZero() || date.After(f.Until)) {
+			continue
+		}
+		inRange = append(inRange, episode)
+	}
+	if f.Latest > 0 && len(inRange) > f.Latest {
+		inRange = inRange[:f.Latest]
+	}
+
+	for _, episode := range inRange {
+		if f.Skip != nil && f.Skip(episode) {
+			skipped++
+			continue
+		}
+		selected = append(selected, episode)
+	}
+	return selected, skipped
+}
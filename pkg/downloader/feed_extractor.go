@@ -0,0 +1,363 @@
+package downloader
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Feed errors
+var (
+	ErrInvalidFeed     = errors.New("无效的播客订阅源")
+	ErrEpisodeNotFound = errors.New("订阅源中未找到该节目")
+)
+
+// feedGUIDMarker separates the feed URL from the episode GUID in a feed episode reference.
+const feedGUIDMarker = "#guid="
+
+// maxFeedSize limits how much of a feed document is read.
+const maxFeedSize = 32 * 1024 * 1024
+
+// Feed is a parsed podcast feed.
+type Feed struct {
+	Title       string             // Podcast name
+	Link        string             // Podcast website
+	Description string             // Podcast description
+	ImageURL    string             // Podcast artwork
+	Episodes    []*EpisodeMetadata // Episodes in feed order (usually newest first)
+}
+
+// FeedEpisodeURL builds the reference used to download a single feed episode:
+// the feed URL followed by "#guid=" and the escaped GUID of the item.
+func FeedEpisodeURL(feedURL, guid string) string {
+	return feedURL + feedGUIDMarker + url.QueryEscape(guid)
+}
+
+// ParseFeedEpisodeURL splits a reference built by FeedEpisodeURL into the
+// feed URL and the episode GUID.
+func ParseFeedEpisodeURL(ref string) (feedURL, guid string, ok bool) {
+	idx := strings.LastIndex(ref, feedGUIDMarker)
+	if idx <= 0 {
+		return "", "", false
+	}
+	guid, err := url.QueryUnescape(ref[idx+len(feedGUIDMarker):])
+	if err != nil || guid == "" {
+		return "", "", false
+	}
+	return ref[:idx], guid, true
+}
+
+// FeedExtractor implements URLExtractor for episodes of RSS 2.0 and Atom feeds.
+// Episode references have the form returned by FeedEpisodeURL.
+type FeedExtractor struct {
+	// client is the HTTP client to use for fetching feeds
+	client *http.Client
+}
+
+// NewFeedExtractor creates a new feed extractor.
+func NewFeedExtractor(client *http.Client) *FeedExtractor {
+	return &FeedExtractor{
+		client: client,
+	}
+}
+
+// ExtractURL fetches the feed and returns the metadata of the referenced episode.
+func (e *FeedExtractor) ExtractURL(ctx context.Context, ref string) (*EpisodeMetadata, error) {
+	feedURL, guid, ok := ParseFeedEpisodeURL(ref)
+	if !ok {
+		return nil, fmt.Errorf("%w: 缺少节目GUID: %s", ErrInvalidURL, ref)
+	}
+
+	feed, err := e.FetchFeed(ctx, feedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, episode := range feed.Episodes {
+		if episode.GUID == guid {
+			return episode, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrEpisodeNotFound, guid)
+}
+
+// FetchFeed downloads and parses the feed at feedURL.
+func (e *FeedExtractor) FetchFeed(ctx context.Context, feedURL string) (*Feed, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPageNotFound, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %w", ErrPageNotFound, &StatusError{StatusCode: resp.StatusCode})
+	}
+
+	feed, err := ParseFeed(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return nil, err
+	}
+	for _, episode := range feed.Episodes {
+		episode.PageURL = FeedEpisodeURL(feedURL, episode.GUID)
+	}
+	return feed, nil
+}
+
+// ParseFeed parses an RSS 2.0 (with iTunes extensions) or Atom document.
+func ParseFeed(r io.Reader) (*Feed, error) {
+	var doc struct {
+		XMLName xml.Name
+		rssChannel
+		atomFeed
+	}
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		// Most feeds are UTF-8; let the others through and hope for the best
+		return input, nil
+	}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+	}
+
+	switch doc.XMLName.Local {
+	case "rss":
+		return doc.rssChannel.toFeed(), nil
+	case "feed":
+		return doc.atomFeed.toFeed(), nil
+	default:
+		return nil, fmt.Errorf("%w: 不支持的根元素 <%s>", ErrInvalidFeed, doc.XMLName.Local)
+	}
+}
+
+// rssChannel mirrors the parts of an RSS 2.0 document used for podcasts.
+// A field without a namespace matches an element of any namespace with that
+// local name, so every namespaced element sharing a name with a plain RSS
+// element has its own field, declared before the plain one to match first.
+type rssChannel struct {
+	Channel struct {
+		ITunesImage itunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+		ITunesTitle string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd title"`
+		AtomLinks   []atomLink  `xml:"http://www.w3.org/2005/Atom link"`
+		Title       string      `xml:"title"`
+		Link        string      `xml:"link"`
+		Description string      `xml:"description"`
+		Image       struct {
+			URL string `xml:"url"`
+		} `xml:"image"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type rssItem struct {
+	ITunesImage    itunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	ITunesDuration string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ITunesEpisode  string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+	ITunesSummary  string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd summary"`
+	ITunesTitle    string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd title"`
+	Content        string      `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Title          string      `xml:"title"`
+	GUID           string      `xml:"guid"`
+	PubDate        string      `xml:"pubDate"`
+	Description    string      `xml:"description"`
+	Enclosure      struct {
+		URL    string `xml:"url,attr"`
+		Type   string `xml:"type,attr"`
+		Length int64  `xml:"length,attr"`
+	} `xml:"enclosure"`
+}
+
+func (c *rssChannel) toFeed() *Feed {
+	ch := &c.Channel
+	feed := &Feed{
+		Title:       firstNonEmpty(ch.Title, ch.ITunesTitle),
+		Link:        strings.TrimSpace(ch.Link),
+		Description: strings.TrimSpace(ch.Description),
+		ImageURL:    firstNonEmpty(ch.ITunesImage.Href, ch.Image.URL),
+	}
+	if feed.Link == "" {
+		// <atom:link rel="self"> is the feed itself, not the show's website
+		for _, link := range ch.AtomLinks {
+			if link.Rel == "alternate" {
+				feed.Link = link.Href
+				break
+			}
+		}
+	}
+
+	for _, item := range ch.Items {
+		if item.Enclosure.URL == "" {
+			// Not an audio episode
+			continue
+		}
+
+		episode := &EpisodeMetadata{
+			AudioURL:      strings.TrimSpace(item.Enclosure.URL),
+			AudioType:     item.Enclosure.Type,
+			CoverURL:      firstNonEmpty(item.ITunesImage.Href, feed.ImageURL),
+			ShowNotes:     firstNonEmpty(item.Content, item.Description, item.ITunesSummary),
+			Title:         firstNonEmpty(item.Title, item.ITunesTitle),
+			EpisodeNumber: strings.TrimSpace(item.ITunesEpisode),
+			PodcastName:   feed.Title,
+			GUID:          firstNonEmpty(item.GUID, item.Enclosure.URL),
+		}
+		if d, ok := parseITunesDuration(item.ITunesDuration); ok {
+			episode.Duration = d
+		}
+		if t, ok := parseFeedDate(item.PubDate); ok {
+			episode.PublicationDate = t
+		}
+		feed.Episodes = append(feed.Episodes, episode)
+	}
+	return feed
+}
+
+// atomFeed mirrors the parts of an Atom document used for podcasts.
+// As in rssChannel, iTunes elements are matched before the Atom ones.
+type atomFeed struct {
+	ITunesImage itunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	ITunesTitle string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd title"`
+	Title       string      `xml:"title"`
+	Subtitle    string      `xml:"subtitle"`
+	Logo        string      `xml:"logo"`
+	Links       []atomLink  `xml:"link"`
+	Entries     []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type atomEntry struct {
+	ITunesImage    itunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	ITunesDuration string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ITunesTitle    string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd title"`
+	ID             string      `xml:"id"`
+	Title          string      `xml:"title"`
+	Published      string      `xml:"published"`
+	Updated        string      `xml:"updated"`
+	Summary        string      `xml:"summary"`
+	Content        string      `xml:"content"`
+	Links          []atomLink  `xml:"link"`
+}
+
+func (f *atomFeed) toFeed() *Feed {
+	feed := &Feed{
+		Title:       firstNonEmpty(f.Title, f.ITunesTitle),
+		Description: strings.TrimSpace(f.Subtitle),
+		ImageURL:    firstNonEmpty(f.ITunesImage.Href, f.Logo),
+	}
+	for _, link := range f.Links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			feed.Link = link.Href
+			break
+		}
+	}
+
+	for _, entry := range f.Entries {
+		var enclosure *atomLink
+		for i := range entry.Links {
+			if entry.Links[i].Rel == "enclosure" {
+				enclosure = &entry.Links[i]
+				break
+			}
+		}
+		if enclosure == nil || enclosure.Href == "" {
+			continue
+		}
+
+		episode := &EpisodeMetadata{
+			AudioURL:    strings.TrimSpace(enclosure.Href),
+			AudioType:   enclosure.Type,
+			CoverURL:    firstNonEmpty(entry.ITunesImage.Href, feed.ImageURL),
+			ShowNotes:   firstNonEmpty(entry.Content, entry.Summary),
+			Title:       firstNonEmpty(entry.Title, entry.ITunesTitle),
+			PodcastName: feed.Title,
+			GUID:        firstNonEmpty(entry.ID, enclosure.Href),
+		}
+		if d, ok := parseITunesDuration(entry.ITunesDuration); ok {
+			episode.Duration = d
+		}
+		if t, ok := parseFeedDate(firstNonEmpty(entry.Published, entry.Updated)); ok {
+			episode.PublicationDate = t
+		}
+		feed.Episodes = append(feed.Episodes, episode)
+	}
+	return feed
+}
+
+// parseITunesDuration parses <itunes:duration>, which is either a number of
+// seconds or a "[[HH:]MM:]SS" clock value.
+func parseITunesDuration(s string) (time.Duration, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+
+	var seconds float64
+	for _, part := range strings.Split(s, ":") {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil || v < 0 {
+			return 0, false
+		}
+		seconds = seconds*60 + v
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
+
+// feedDateLayouts lists the date formats seen in pubDate and Atom timestamps.
+var feedDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseFeedDate parses an RFC 822 pubDate or an RFC 3339 Atom timestamp.
+func parseFeedDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range feedDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// firstNonEmpty returns the first argument that is not blank.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,192 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+  <channel>
+    <title>测试播客</title>
+    <link>https://example.com</link>
+    <itunes:image href="https://example.com/show.jpg"/>
+    <image><url>https://example.com/rss.jpg</url></image>
+    <item>
+      <title>第二期</title>
+      <guid isPermaLink="false">ep-2</guid>
+      <pubDate>Tue, 03 Sep 2024 08:00:00 +0800</pubDate>
+      <description><![CDATA[<p>简介</p>]]></description>
+      <content:encoded><![CDATA[<p>完整节目笔记</p>]]></content:encoded>
+      <enclosure url="https://cdn.example.com/ep2.mp3" type="audio/mpeg" length="1234"/>
+      <itunes:duration>1:02:03</itunes:duration>
+      <itunes:episode>2</itunes:episode>
+      <itunes:image href="https://example.com/ep2.jpg"/>
+    </item>
+    <item>
+      <title>第一期</title>
+      <pubDate>Mon, 2 Sep 2024 08:00:00 GMT</pubDate>
+      <description>Plain notes</description>
+      <enclosure url="https://cdn.example.com/ep1.m4a" type="audio/x-m4a"/>
+      <itunes:duration>3600</itunes:duration>
+    </item>
+    <item>
+      <title>Text only post</title>
+    </item>
+  </channel>
+</rss>`
+
+func TestParseFeed_RSS(t *testing.T) {
+	feed, err := ParseFeed(strings.NewReader(testRSS))
+	if err != nil {
+		t.Fatalf("ParseFeed() error = %v", err)
+	}
+	if feed.Title != "测试播客" || feed.ImageURL != "https://example.com/show.jpg" {
+		t.Errorf("feed = %q / %q", feed.Title, feed.ImageURL)
+	}
+	if len(feed.Episodes) != 2 {
+		t.Fatalf("got %d episodes, want 2 (items without enclosure are skipped)", len(feed.Episodes))
+	}
+
+	ep := feed.Episodes[0]
+	if ep.GUID != "ep-2" || ep.AudioURL != "https://cdn.example.com/ep2.mp3" || ep.AudioExtension() != ".mp3" {
+		t.Errorf("episode = %+v", ep)
+	}
+	if ep.CoverURL != "https://example.com/ep2.jpg" || ep.ShowNotes != "<p>完整节目笔记</p>" || ep.EpisodeNumber != "2" {
+		t.Errorf("episode = %+v", ep)
+	}
+	if ep.Duration != time.Hour+2*time.Minute+3*time.Second {
+		t.Errorf("Duration = %v", ep.Duration)
+	}
+	if want := time.Date(2024, 9, 3, 0, 0, 0, 0, time.UTC); !ep.PublicationDate.Equal(want) {
+		t.Errorf("PublicationDate = %v, want %v", ep.PublicationDate, want)
+	}
+
+	// Missing GUID falls back to the enclosure URL; missing artwork to the show's
+	ep = feed.Episodes[1]
+	if ep.GUID != "https://cdn.example.com/ep1.m4a" || ep.CoverURL != "https://example.com/show.jpg" || ep.AudioExtension() != ".m4a" {
+		t.Errorf("episode = %+v", ep)
+	}
+	if ep.Duration != time.Hour || ep.PodcastName != "测试播客" {
+		t.Errorf("episode = %+v", ep)
+	}
+}
+
+func TestParseFeed_RSSNamespacedElements(t *testing.T) {
+	const doc = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:atom="http://www.w3.org/2005/Atom">
+  <channel>
+    <title>Show full title</title>
+    <itunes:title>Show</itunes:title>
+    <link>https://example.com</link>
+    <atom:link rel="self" href="https://example.com/feed.xml" type="application/rss+xml"/>
+    <item>
+      <itunes:title>Ep1</itunes:title>
+      <title>Ep 1 full</title>
+      <enclosure url="https://cdn.example.com/ep1.mp3" type="audio/mpeg"/>
+    </item>
+    <item>
+      <itunes:title>Ep2</itunes:title>
+      <enclosure url="https://cdn.example.com/ep2.mp3" type="audio/mpeg"/>
+    </item>
+  </channel>
+</rss>`
+
+	feed, err := ParseFeed(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("ParseFeed() error = %v", err)
+	}
+	if feed.Title != "Show full title" || feed.Link != "https://example.com" {
+		t.Errorf("feed = %q / %q, want the plain title and link", feed.Title, feed.Link)
+	}
+	if len(feed.Episodes) != 2 {
+		t.Fatalf("got %d episodes, want 2", len(feed.Episodes))
+	}
+	if got := feed.Episodes[0].Title; got != "Ep 1 full" {
+		t.Errorf("Title = %q, want the plain title", got)
+	}
+	if got := feed.Episodes[1].Title; got != "Ep2" {
+		t.Errorf("Title without a plain title = %q, want the iTunes title", got)
+	}
+}
+
+func TestParseFeed_Atom(t *testing.T) {
+	atom := `<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Atom Show</title>
+  <link href="https://example.org/"/>
+  <entry>
+    <id>urn:uuid:1</id>
+    <title>Entry</title>
+    <published>2024-01-02T03:04:05Z</published>
+    <summary>Notes</summary>
+    <link rel="alternate" href="https://example.org/1"/>
+    <link rel="enclosure" href="https://example.org/1.mp3" type="audio/mpeg"/>
+  </entry>
+</feed>`
+	feed, err := ParseFeed(strings.NewReader(atom))
+	if err != nil {
+		t.Fatalf("ParseFeed() error = %v", err)
+	}
+	if feed.Title != "Atom Show" || feed.Link != "https://example.org/" || len(feed.Episodes) != 1 {
+		t.Fatalf("feed = %+v", feed)
+	}
+	ep := feed.Episodes[0]
+	if ep.GUID != "urn:uuid:1" || ep.AudioURL != "https://example.org/1.mp3" || ep.PublicationDate.Year() != 2024 {
+		t.Errorf("episode = %+v", ep)
+	}
+}
+
+func TestParseFeed_Invalid(t *testing.T) {
+	if _, err := ParseFeed(strings.NewReader("<html><body>not a feed</body></html>")); !errors.Is(err, ErrInvalidFeed) {
+		t.Errorf("ParseFeed(html) error = %v, want ErrInvalidFeed", err)
+	}
+}
+
+func TestParseITunesDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"90":      90 * time.Second,
+		"05:30":   5*time.Minute + 30*time.Second,
+		"1:00:00": time.Hour,
+	}
+	for in, want := range tests {
+		if got, ok := parseITunesDuration(in); !ok || got != want {
+			t.Errorf("parseITunesDuration(%q) = %v, %v, want %v", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"", "abc", "1:xx"} {
+		if _, ok := parseITunesDuration(in); ok {
+			t.Errorf("parseITunesDuration(%q) ok = true, want false", in)
+		}
+	}
+}
+
+func TestFeedExtractor_ExtractURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(testRSS))
+	}))
+	defer server.Close()
+
+	ref := FeedEpisodeURL(server.URL+"/feed.xml", "ep-2")
+	if feedURL, guid, ok := ParseFeedEpisodeURL(ref); !ok || feedURL != server.URL+"/feed.xml" || guid != "ep-2" {
+		t.Fatalf("ParseFeedEpisodeURL(%q) = %q, %q, %v", ref, feedURL, guid, ok)
+	}
+
+	e := NewFeedExtractor(server.Client())
+	ep, err := e.ExtractURL(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("ExtractURL() error = %v", err)
+	}
+	if ep.Title != "第二期" {
+		t.Errorf("Title = %q", ep.Title)
+	}
+
+	if _, err := e.ExtractURL(context.Background(), FeedEpisodeURL(server.URL, "missing")); !errors.Is(err, ErrEpisodeNotFound) {
+		t.Errorf("ExtractURL(missing) error = %v, want ErrEpisodeNotFound", err)
+	}
+}
@@ -0,0 +1,78 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// FeedSource downloads episodes listed in RSS 2.0 and Atom podcast feeds.
+// Single episodes are referenced with FeedEpisodeURL.
+type FeedSource struct {
+	extractor *FeedExtractor
+}
+
+// NewFeedSource creates a feed source that fetches feeds with client.
+func NewFeedSource(client *http.Client) *FeedSource {
+	return &FeedSource{
+		extractor: NewFeedExtractor(client),
+	}
+}
+
+// Name implements Source.
+func (s *FeedSource) Name() string {
+	return "feed"
+}
+
+// Match implements Source. It accepts feed episode references and URLs that
+// look like feeds; any other URL would need to be fetched to tell.
+func (s *FeedSource) Match(rawURL string) bool {
+	if _, _, ok := ParseFeedEpisodeURL(rawURL); ok {
+		rawURL = rawURL[:strings.LastIndex(rawURL, feedGUIDMarker)]
+		return isHTTPURL(rawURL)
+	}
+	if !isHTTPURL(rawURL) {
+		return false
+	}
+
+	u, _ := url.Parse(rawURL)
+	p := strings.ToLower(u.Path)
+	switch path.Ext(p) {
+	case ".xml", ".rss", ".atom":
+		return true
+	}
+	return strings.Contains(p, "feed") || strings.Contains(p, "rss") || strings.Contains(u.Host, "feeds.")
+}
+
+// IsShow implements Source. Any feed URL that isn't an episode reference is a show.
+func (s *FeedSource) IsShow(rawURL string) bool {
+	if _, _, ok := ParseFeedEpisodeURL(rawURL); ok {
+		return false
+	}
+	return s.Match(rawURL)
+}
+
+// Resolve implements Source for feed episode references.
+func (s *FeedSource) Resolve(ctx context.Context, ref string) (*EpisodeMetadata, error) {
+	return s.extractor.ExtractURL(ctx, ref)
+}
+
+// List implements Source by fetching the feed.
+func (s *FeedSource) List(ctx context.Context, feedURL string) ([]*EpisodeMetadata, error) {
+	if base, _, ok := ParseFeedEpisodeURL(feedURL); ok {
+		feedURL = base
+	}
+	feed, err := s.extractor.FetchFeed(ctx, feedURL)
+	if err != nil {
+		return nil, err
+	}
+	return feed.Episodes, nil
+}
+
+// isHTTPURL reports whether rawURL is an absolute http or https URL.
+func isHTTPURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -31,7 +31,7 @@ func (c *HTTPClient) Get(url string) (*goquery.Document, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+		return nil, fmt.Errorf("%w: %s", &StatusError{StatusCode: resp.StatusCode}, resp.Status)
 	}
 
 	// Parse HTML
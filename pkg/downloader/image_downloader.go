@@ -27,6 +27,20 @@ type ImageDownloader interface {
 	//   error - Error if download or validation fails
 	Download(ctx context.Context, imageURL string, destPath string, progress io.Writer) (int64, error)
 
+	// DownloadCover downloads a cover image into a podcast directory and saves
+	// it as cover.jpg, cover.png, cover.webp or cover.gif by its real format.
+	//
+	// Parameters:
+	//   ctx - Context for cancellation and timeout
+	//   imageURL - The URL of the image to download
+	//   dir - The podcast directory
+	//   progress - Optional progress writer (can be nil)
+	//
+	// Returns:
+	//   string - Path of the saved cover
+	//   error - Error if download or validation fails
+	DownloadCover(ctx context.Context, imageURL string, dir string, progress io.Writer) (string, error)
+
 	// ValidateImage validates that a file is a valid image format.
 	//
 	// Parameters:
@@ -145,11 +159,55 @@ func (d *HTTPImageDownloader) Download(ctx context.Context, imageURL string, des
 	return 0, lastErr
 }
 
+// coverExtensions maps detected image formats to the extension covers are saved with.
+var coverExtensions = map[string]string{
+	"jpeg": ".jpg",
+	"png":  ".png",
+	"webp": ".webp",
+	"gif":  ".gif",
+}
+
+// DownloadCover downloads a cover image into dir and names it after its real
+// format rather than the URL, which often says .jpg for PNG or WebP images.
+// Covers of other formats left by earlier downloads are removed so the scanner
+// doesn't pick a stale one.
+func (d *HTTPImageDownloader) DownloadCover(ctx context.Context, imageURL string, dir string, progress io.Writer) (string, error) {
+	tempPath := filepath.Join(dir, ".cover.download")
+	if _, err := d.Download(ctx, imageURL, tempPath, progress); err != nil {
+		return "", err
+	}
+
+	format, err := d.readFormat(tempPath)
+	if err != nil {
+		os.Remove(tempPath)
+		return "", fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	coverPath := filepath.Join(dir, "cover"+coverExtensions[format])
+	if err := os.Rename(tempPath, coverPath); err != nil {
+		os.Remove(tempPath)
+		return "", fmt.Errorf("保存封面失败: %w", err)
+	}
+	for _, ext := range coverExtensions {
+		if path := filepath.Join(dir, "cover"+ext); path != coverPath {
+			os.Remove(path)
+		}
+	}
+
+	return coverPath, nil
+}
+
 // ValidateImage validates that a file is a valid image using magic byte detection.
 func (d *HTTPImageDownloader) ValidateImage(filePath string) error {
+	_, err := d.readFormat(filePath)
+	return err
+}
+
+// readFormat detects the image format of a file from its magic bytes.
+func (d *HTTPImageDownloader) readFormat(filePath string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return fmt.Errorf("打开文件失败: %w", err)
+		return "", fmt.Errorf("打开文件失败: %w", err)
 	}
 	defer file.Close()
 
@@ -157,16 +215,16 @@ func (d *HTTPImageDownloader) ValidateImage(filePath string) error {
 	header := make([]byte, 12)
 	_, err = file.Read(header)
 	if err != nil {
-		return fmt.Errorf("读取文件头失败: %w", err)
+		return "", fmt.Errorf("读取文件头失败: %w", err)
 	}
 
 	// Detect format
 	format := d.detectFormat(header)
 	if format == "" {
-		return fmt.Errorf("无法识别的图片格式")
+		return "", fmt.Errorf("无法识别的图片格式")
 	}
 
-	return nil
+	return format, nil
 }
 
 // detectFormat identifies image format from binary header using magic bytes.
@@ -1,14 +1,91 @@
 package downloader
 
-import "time"
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/meixg/podcast-reader/pkg/models"
+	"github.com/meixg/podcast-reader/pkg/zhtime"
+)
 
 // EpisodeMetadata contains all extracted metadata for a podcast episode.
 type EpisodeMetadata struct {
-	AudioURL        string    // Direct URL to audio file (required)
-	CoverURL        string    // URL to cover image (optional)
-	ShowNotes       string    // Plain text show notes (optional)
-	Title           string    // Episode title (required)
-	EpisodeNumber   string    // Episode number if available
-	PodcastName     string    // Podcast/series name
-	PublicationDate time.Time // Publication date
+	AudioURL        string            // Direct URL to audio file (required)
+	AudioType       string            // MIME type of the audio file if known
+	CoverURL        string            // URL to cover image (optional)
+	CoverURLs       *models.CoverURLs // Cover image at several sizes, for sources that provide them
+	ShowNotes       string            // Plain text show notes (optional)
+	Title           string            // Episode title (required)
+	EpisodeNumber   string            // Episode number if available
+	PodcastName     string            // Podcast/series name
+	PublicationDate time.Time         // Publication date
+	Duration        time.Duration     // Episode length if known
+	GUID            string            // Unique episode ID within its feed or source, if known
+	PodcastID       string            // Source-specific podcast ID, if known
+	PageURL         string            // URL the episode was resolved from; can be resolved again
+
+	// PageMetadata holds the duration and publish time as displayed on the
+	// episode page, for sources that scrape them
+	PageMetadata *models.PodcastMetadata
+}
+
+// CoverDownloadURL returns the URL to download the cover image from: the
+// largest size the source offers, or CoverURL.
+func (m *EpisodeMetadata) CoverDownloadURL() string {
+	if url := m.CoverURLs.Largest(); url != "" {
+		return url
+	}
+	return m.CoverURL
+}
+
+// AudioExtension returns the file extension to save the audio file with:
+// ".mp3" for MPEG audio and ".m4a" otherwise.
+func (m *EpisodeMetadata) AudioExtension() string {
+	if strings.EqualFold(m.AudioType, "audio/mpeg") || strings.EqualFold(m.AudioType, "audio/mp3") {
+		return ".mp3"
+	}
+	if u, err := url.Parse(m.AudioURL); err == nil && strings.EqualFold(path.Ext(u.Path), ".mp3") {
+		return ".mp3"
+	}
+	return ".m4a"
+}
+
+// ToPodcastMetadata converts the episode metadata to the .metadata.json format.
+// Metadata scraped from the episode page is preferred when available.
+func (m *EpisodeMetadata) ToPodcastMetadata() *models.PodcastMetadata {
+	if m.PageMetadata != nil {
+		metadata := *m.PageMetadata
+		metadata.Chapters = ParseChapters(m.ShowNotes)
+		if m.GUID != "" {
+			metadata.EpisodeGUID = m.GUID
+		}
+		if m.PodcastID != "" {
+			metadata.PodcastID = m.PodcastID
+		}
+		if m.CoverURLs != nil {
+			metadata.CoverURLs = m.CoverURLs
+		}
+		return &metadata
+	}
+
+	metadata := models.NewPodcastMetadata()
+	metadata.Chapters = ParseChapters(m.ShowNotes)
+	metadata.EpisodeGUID = m.GUID
+	metadata.PodcastID = m.PodcastID
+	metadata.CoverURLs = m.CoverURLs
+	metadata.EpisodeTitle = m.Title
+	metadata.PodcastName = m.PodcastName
+	if m.Duration > 0 {
+		metadata.Duration = fmt.Sprintf("%d分钟", int((m.Duration+30*time.Second)/time.Minute))
+	}
+	if !m.PublicationDate.IsZero() {
+		published := m.PublicationDate
+		metadata.PublishTime = published.Format("2006-01-02")
+		metadata.PublishedAt = &published
+		metadata.PublishPrecision = string(zhtime.PrecisionExact)
+	}
+	return metadata
 }
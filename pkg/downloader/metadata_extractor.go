@@ -31,6 +31,16 @@ func (e *MetadataExtractor) ExtractMetadata(ctx context.Context, pageURL string)
 		return nil, fmt.Errorf("failed to fetch page: %w", err)
 	}
 
+	return e.extractDocument(doc), nil
+}
+
+// extractDocument extracts metadata from an already fetched podcast page
+// Uses the embedded __NEXT_DATA__ JSON when present, scraping the page otherwise
+func (e *MetadataExtractor) extractDocument(doc *goquery.Document) *models.PodcastMetadata {
+	if episode, err := extractEpisodeNextData(doc); err == nil {
+		return episode.ToPodcastMetadata()
+	}
+
 	metadata := models.NewPodcastMetadata()
 
 	// Extract combined info text and parse it
@@ -38,6 +48,7 @@ func (e *MetadataExtractor) ExtractMetadata(ctx context.Context, pageURL string)
 	if combinedInfo != "" {
 		// Parse the combined text to extract duration and publish time
 		metadata.Duration, metadata.PublishTime = e.parseInfoText(combinedInfo)
+		metadata.ResolvePublishedAt()
 	}
 
 	// Extract episode title
@@ -46,7 +57,7 @@ func (e *MetadataExtractor) ExtractMetadata(ctx context.Context, pageURL string)
 	// Extract podcast name
 	metadata.PodcastName = e.extractPodcastName(doc)
 
-	return metadata, nil
+	return metadata
 }
 
 // extractCombinedInfoText extracts the combined info text from elements with class containing "info"
@@ -79,7 +90,7 @@ func (e *MetadataExtractor) extractCombinedInfoText(doc *goquery.Document) strin
 // Expected format: "103分钟 ·2个月前35609·415" or similar
 func (e *MetadataExtractor) parseInfoText(text string) (duration, publishTime string) {
 	// Use regex to extract duration (e.g., "103分钟", "1小时15分钟")
-	durationRegex := regexp.MustCompile(`\d+\s*[小时分分钟]+\s*[分钟分]?`)
+	durationRegex := regexp.MustCompile(`\d+\s*小时(\s*\d+\s*分钟?)?|\d+\s*分钟?`)
 	durationMatch := durationRegex.FindString(text)
 	if durationMatch != "" {
 		duration = strings.TrimSpace(durationMatch)
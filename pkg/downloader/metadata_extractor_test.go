@@ -0,0 +1,23 @@
+package downloader
+
+import "testing"
+
+func TestMetadataExtractor_parseInfoText(t *testing.T) {
+	e := NewMetadataExtractor(nil)
+
+	tests := []struct {
+		text         string
+		wantDuration string
+		wantPublish  string
+	}{
+		{"103分钟 ·2个月前35609·415", "103分钟", "2个月前"},
+		{"1小时15分钟 · 3天前", "1小时15分钟", "3天前"},
+		{"2小时 · 刚刚发布", "2小时", "刚刚发布"},
+	}
+	for _, tt := range tests {
+		duration, publish := e.parseInfoText(tt.text)
+		if duration != tt.wantDuration || publish != tt.wantPublish {
+			t.Errorf("parseInfoText(%q) = %q, %q; want %q, %q", tt.text, duration, publish, tt.wantDuration, tt.wantPublish)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package downloader
+
+import (
+	"sync"
+	"time"
+)
+
+// progressInterval is the minimum time between two progress reports.
+const progressInterval = 250 * time.Millisecond
+
+// Progress describes the state of a running download.
+type Progress struct {
+	// Downloaded is the number of bytes present locally, including resumed bytes
+	Downloaded int64
+	// Total is the total file size in bytes (-1 if unknown)
+	Total int64
+	// Rate is the current transfer rate in bytes per second
+	Rate float64
+}
+
+// Percent returns the completion ratio in [0, 1], or -1 if the total is unknown.
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		return -1
+	}
+	if p.Downloaded >= p.Total {
+		return 1
+	}
+	return float64(p.Downloaded) / float64(p.Total)
+}
+
+// Remaining estimates the time left at the current rate. Returns -1 if unknown.
+func (p Progress) Remaining() time.Duration {
+	if p.Total <= 0 || p.Rate <= 0 {
+		return -1
+	}
+	left := p.Total - p.Downloaded
+	if left <= 0 {
+		return 0
+	}
+	return time.Duration(float64(left) / p.Rate * float64(time.Second))
+}
+
+// ProgressFunc receives progress updates during a download.
+type ProgressFunc func(Progress)
+
+// progressTracker is an io.Writer that turns written bytes into throttled
+// Progress reports with a smoothed transfer rate.
+type progressTracker struct {
+	mu         sync.Mutex
+	callback   ProgressFunc
+	downloaded int64
+	total      int64
+	rate       float64
+	lastReport time.Time
+	lastBytes  int64
+}
+
+// newProgressTracker creates a tracker starting at offset bytes. Returns nil if callback is nil.
+func newProgressTracker(callback ProgressFunc, offset, total int64) *progressTracker {
+	if callback == nil {
+		return nil
+	}
+	return &progressTracker{
+		callback:   callback,
+		downloaded: offset,
+		total:      total,
+		lastReport: time.Now(),
+		lastBytes:  offset,
+	}
+}
+
+// Write records len(p) downloaded bytes. Safe for concurrent use.
+func (t *progressTracker) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	t.downloaded += int64(len(p))
+	now := time.Now()
+	elapsed := now.Sub(t.lastReport)
+	if elapsed < progressInterval {
+		t.mu.Unlock()
+		return len(p), nil
+	}
+
+	// Exponential moving average keeps the rate from jumping around
+	instant := float64(t.downloaded-t.lastBytes) / elapsed.Seconds()
+	if t.rate == 0 {
+		t.rate = instant
+	} else {
+		t.rate = 0.3*instant + 0.7*t.rate
+	}
+	t.lastReport = now
+	t.lastBytes = t.downloaded
+	report := Progress{Downloaded: t.downloaded, Total: t.total, Rate: t.rate}
+	t.mu.Unlock()
+
+	t.callback(report)
+	return len(p), nil
+}
+
+// Flush sends a final report with the current byte count.
+func (t *progressTracker) Flush() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	report := Progress{Downloaded: t.downloaded, Total: t.total, Rate: t.rate}
+	t.mu.Unlock()
+	t.callback(report)
+}
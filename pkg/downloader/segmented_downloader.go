@@ -0,0 +1,329 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// minSegmentSize is the smallest byte range worth fetching on its own connection.
+const minSegmentSize = 1024 * 1024
+
+// SegmentedDownloader implements FileDownloader by splitting the file into byte
+// ranges that are fetched concurrently and joined in order. Servers that do not
+// support range requests are handled by a single-stream HTTPDownloader.
+type SegmentedDownloader struct {
+	// client is the HTTP client to use for downloads
+	client *http.Client
+	// connections is the number of segments fetched in parallel
+	connections int
+	// single handles servers without range support and final validation
+	single *HTTPDownloader
+}
+
+// NewSegmentedDownloader creates a downloader that uses up to connections parallel requests.
+func NewSegmentedDownloader(client *http.Client, connections int, showProgress bool) *SegmentedDownloader {
+	if connections < 1 {
+		connections = 1
+	}
+	return &SegmentedDownloader{
+		client:      client,
+		connections: connections,
+		single:      NewHTTPDownloader(client, showProgress),
+	}
+}
+
+// NewFileDownloader returns a SegmentedDownloader when more than one connection is
+// requested and a plain HTTPDownloader otherwise.
+func NewFileDownloader(client *http.Client, connections int, showProgress bool) FileDownloader {
+	if connections > 1 {
+		return NewSegmentedDownloader(client, connections, showProgress)
+	}
+	return NewHTTPDownloader(client, showProgress)
+}
+
+// segment is a byte range [start, end] of the remote file.
+type segment struct {
+	index int
+	start int64
+	end   int64
+	path  string
+}
+
+// Download fetches the audio file and writes it to the local filesystem.
+func (d *SegmentedDownloader) Download(ctx context.Context, audioURL, filePath string, progress ProgressFunc) (int64, error) {
+	// A single-stream partial download is resumed as it was started
+	if info, err := os.Stat(PartPath(filePath)); err == nil && info.Size() > 0 {
+		return d.single.Download(ctx, audioURL, filePath, progress)
+	}
+
+	size, etag, ok := d.probe(ctx, audioURL)
+	if !ok || d.connections < 2 || size < 2*minSegmentSize {
+		RemovePartial(filePath)
+		return d.single.Download(ctx, audioURL, filePath, progress)
+	}
+
+	// Segments from a different version of the file cannot be reused
+	etagPath := PartPath(filePath) + etagSuffix
+	if saved, err := os.ReadFile(etagPath); err == nil && strings.TrimSpace(string(saved)) != etag {
+		RemovePartial(filePath)
+	}
+	if etag != "" {
+		os.WriteFile(etagPath, []byte(etag), 0644)
+	}
+
+	segments := d.split(filePath, size)
+	removeStaleSegments(filePath, segments)
+
+	// Bytes from earlier attempts count towards progress
+	var existing int64
+	for _, seg := range segments {
+		if info, err := os.Stat(seg.path); err == nil {
+			existing += info.Size()
+		}
+	}
+
+	// The tracker is shared by all segments and safe for concurrent use
+	tracker := newProgressTracker(progress, existing, size)
+	var sharedProgress io.Writer
+	if tracker != nil {
+		sharedProgress = tracker
+	}
+
+	segCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The first failure is reported; the segments it cancels fail after it
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for i := range segments {
+		wg.Add(1)
+		go func(seg segment) {
+			defer wg.Done()
+			if err := d.fetchSegment(segCtx, audioURL, etag, seg, sharedProgress); err != nil {
+				once.Do(func() { firstErr = err })
+				cancel()
+			}
+		}(segments[i])
+	}
+	wg.Wait()
+	tracker.Flush()
+
+	if firstErr != nil && ctx.Err() == nil {
+		return 0, firstErr
+	}
+	if ctx.Err() != nil {
+		return 0, fmt.Errorf("下载中断: %w", ctx.Err())
+	}
+
+	if err := d.join(filePath, segments); err != nil {
+		return 0, err
+	}
+	if err := d.single.finish(filePath); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// ValidateFile checks if the downloaded file is a valid audio file.
+func (d *SegmentedDownloader) ValidateFile(filePath string) error {
+	return d.single.ValidateFile(filePath)
+}
+
+// probe asks for the first byte to learn the file size and whether ranges are supported.
+func (d *SegmentedDownloader) probe(ctx context.Context, audioURL string) (size int64, etag string, ok bool) {
+	req, err := http.NewRequestWithContext(ctx, "GET", audioURL, nil)
+	if err != nil {
+		return 0, "", false
+	}
+	req.Header.Set("Range", "bytes=0-0")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, "", false
+	}
+
+	// Content-Range: bytes 0-0/12345
+	size, ok = parseContentRangeTotal(resp.Header.Get("Content-Range"))
+	if !ok {
+		return 0, "", false
+	}
+
+	return size, resp.Header.Get("ETag"), true
+}
+
+// split divides the file into equally sized segments backed by their own files.
+func (d *SegmentedDownloader) split(filePath string, size int64) []segment {
+	n := int64(d.connections)
+	if maxSegments := size / minSegmentSize; n > maxSegments {
+		n = maxSegments
+	}
+
+	chunk := size / n
+	segments := make([]segment, 0, n)
+	for i := int64(0); i < n; i++ {
+		start := i * chunk
+		end := start + chunk - 1
+		if i == n-1 {
+			end = size - 1
+		}
+		segments = append(segments, segment{
+			index: int(i),
+			start: start,
+			end:   end,
+			path:  fmt.Sprintf("%s.seg%dof%d", PartPath(filePath), i, n),
+		})
+	}
+	return segments
+}
+
+// fetchSegment downloads (or resumes) a single byte range into its segment file.
+func (d *SegmentedDownloader) fetchSegment(ctx context.Context, audioURL, etag string, seg segment, progress io.Writer) error {
+	var have int64
+	if info, err := os.Stat(seg.path); err == nil {
+		have = info.Size()
+	}
+	length := seg.end - seg.start + 1
+	if have == length {
+		return nil
+	}
+	if have > length {
+		// Not written for this range; start the segment over
+		if err := os.Remove(seg.path); err != nil {
+			return fmt.Errorf("%w: %v", ErrPermissionDenied, err)
+		}
+		have = 0
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", audioURL, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrConnectionRefused, err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", seg.start+have, seg.end))
+	if etag != "" {
+		req.Header.Set("If-Range", etag)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNetworkTimeout, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("下载失败: 分段 %d 返回 %w", seg.index, &StatusError{StatusCode: resp.StatusCode})
+	}
+	if start, ok := parseContentRangeStart(resp.Header.Get("Content-Range")); !ok || start != seg.start+have {
+		return fmt.Errorf("下载失败: 分段 %d 的 Content-Range 不匹配", seg.index)
+	}
+
+	out, err := os.OpenFile(seg.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPermissionDenied, err)
+	}
+	defer out.Close()
+
+	var writer io.Writer = out
+	if progress != nil {
+		writer = io.MultiWriter(out, progress)
+	}
+
+	// Never write past the end of the segment, even if the server sends more
+	written, err := io.Copy(writer, io.LimitReader(resp.Body, length-have))
+	if err != nil {
+		return fmt.Errorf("下载中断: %w", err)
+	}
+	if written < length-have {
+		// The bytes received are kept, so a retry resumes the segment
+		return fmt.Errorf("下载中断: 分段 %d 缺少 %d 字节: %w", seg.index, length-have-written, io.ErrUnexpectedEOF)
+	}
+	return nil
+}
+
+// join concatenates the segment files in order into the partial file. Every
+// segment must hold exactly its byte range.
+func (d *SegmentedDownloader) join(filePath string, segments []segment) error {
+	for _, seg := range segments {
+		info, err := os.Stat(seg.path)
+		if err != nil {
+			return fmt.Errorf("无法打开分段文件: %w", err)
+		}
+		if length := seg.end - seg.start + 1; info.Size() != length {
+			return fmt.Errorf("下载失败: 分段 %d 大小为 %d 字节，应为 %d 字节: %w", seg.index, info.Size(), length, io.ErrUnexpectedEOF)
+		}
+	}
+
+	out, err := os.Create(PartPath(filePath))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPermissionDenied, err)
+	}
+
+	for _, seg := range segments {
+		in, err := os.Open(seg.path)
+		if err != nil {
+			out.Close()
+			return fmt.Errorf("无法打开分段文件: %w", err)
+		}
+		_, err = io.Copy(out, in)
+		in.Close()
+		if err != nil {
+			out.Close()
+			return fmt.Errorf("%w: %v", ErrDiskFull, err)
+		}
+	}
+
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("%w: %v", ErrDiskFull, err)
+	}
+
+	for _, seg := range segments {
+		os.Remove(seg.path)
+	}
+	return nil
+}
+
+// removeStaleSegments removes the segment files of filePath that are not
+// among segments, such as those left by a download split into a different
+// number of segments.
+func removeStaleSegments(filePath string, segments []segment) {
+	current := make(map[string]bool, len(segments))
+	for _, seg := range segments {
+		current[seg.path] = true
+	}
+	for _, path := range segmentFiles(filePath) {
+		if !current[path] {
+			os.Remove(path)
+		}
+	}
+}
+
+// segmentFiles lists the segment files left behind for filePath.
+func segmentFiles(filePath string) []string {
+	// Titles may contain glob metacharacters, so match the prefix by hand
+	prefix := filepath.Base(PartPath(filePath)) + ".seg"
+	dir := filepath.Dir(filePath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var matches []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), prefix) {
+			matches = append(matches, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return matches
+}
@@ -0,0 +1,150 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Source errors
+var (
+	ErrUnsupportedURL      = errors.New("不支持的URL")
+	ErrListingNotSupported = errors.New("该来源不支持列出节目")
+)
+
+// Source is a site or feed format that podcast episodes can be downloaded from.
+type Source interface {
+	// Name identifies the source, e.g. "xiaoyuzhou" or "feed".
+	Name() string
+
+	// Match reports whether the source handles the given episode or show URL.
+	Match(url string) bool
+
+	// IsShow reports whether url refers to a whole show rather than a single
+	// episode. Show URLs are expanded with List.
+	IsShow(url string) bool
+
+	// Resolve fetches the metadata of a single episode.
+	Resolve(ctx context.Context, url string) (*EpisodeMetadata, error)
+
+	// List returns the episodes of a show, newest first. Each episode's PageURL
+	// can be passed to Resolve. If only the newest episodes can be listed, they
+	// are returned with a *TruncatedListError.
+	List(ctx context.Context, showURL string) ([]*EpisodeMetadata, error)
+}
+
+// TruncatedListError is returned by List, together with the episodes it
+// could list, when a show has more episodes than its source lists.
+type TruncatedListError struct {
+	Listed int
+	Total  int
+}
+
+func (e *TruncatedListError) Error() string {
+	return fmt.Sprintf("节目共%d集，只能列出最近的%d集", e.Total, e.Listed)
+}
+
+// Registry looks up the source responsible for a URL.
+// Sources are tried in the order they were registered.
+type Registry struct {
+	mu      sync.RWMutex
+	sources []Source
+}
+
+// NewRegistry creates a registry with the given sources.
+func NewRegistry(sources ...Source) *Registry {
+	return &Registry{
+		sources: sources,
+	}
+}
+
+// NewDefaultRegistry creates a registry with all built-in sources.
+// Pages are fetched with client.
+func NewDefaultRegistry(client *http.Client) *Registry {
+	return NewRegistry(
+		NewXiaoyuzhouSource(client),
+		NewFeedSource(client),
+	)
+}
+
+// Register adds a source after the existing ones, so earlier sources keep precedence.
+func (r *Registry) Register(source Source) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sources = append(r.sources, source)
+}
+
+// Lookup returns the first source that matches url.
+func (r *Registry) Lookup(url string) (Source, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, source := range r.sources {
+		if source.Match(url) {
+			return source, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedURL, url)
+}
+
+// Get returns the source with the given name.
+func (r *Registry) Get(name string) (Source, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, source := range r.sources {
+		if source.Name() == name {
+			return source, true
+		}
+	}
+	return nil, false
+}
+
+// Names returns the names of all registered sources.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.sources))
+	for _, source := range r.sources {
+		names = append(names, source.Name())
+	}
+	return names
+}
+
+// IsShow reports whether url is a show URL of one of the registered sources.
+func (r *Registry) IsShow(url string) bool {
+	source, err := r.Lookup(url)
+	return err == nil && source.IsShow(url)
+}
+
+// List looks up the source for showURL and lists its episodes. Like
+// Source.List, it returns the episodes it could list with a
+// *TruncatedListError.
+func (r *Registry) List(ctx context.Context, showURL string) ([]*EpisodeMetadata, Source, error) {
+	source, err := r.Lookup(showURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	episodes, err := source.List(ctx, showURL)
+	var truncated *TruncatedListError
+	if err != nil && !errors.As(err, &truncated) {
+		return nil, source, err
+	}
+	return episodes, source, err
+}
+
+// Resolve looks up the source for url and fetches the episode metadata.
+func (r *Registry) Resolve(ctx context.Context, url string) (*EpisodeMetadata, Source, error) {
+	source, err := r.Lookup(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	metadata, err := source.Resolve(ctx, url)
+	if err != nil {
+		return nil, source, err
+	}
+	return metadata, source, nil
+}
@@ -0,0 +1,51 @@
+package downloader
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestRegistry_Lookup(t *testing.T) {
+	r := NewDefaultRegistry(http.DefaultClient)
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.xiaoyuzhoufm.com/episode/69392768281939cce65925d3", "xiaoyuzhou"},
+		{"https://www.xiaoyuzhoufm.com/podcast/5e280fab418a84a0461fa8c4", "xiaoyuzhou"},
+		{FeedEpisodeURL("https://example.com/show", "ep-1"), "feed"},
+		{"https://example.com/podcast/feed.xml", "feed"},
+		{"https://feeds.example.com/show", "feed"},
+	}
+	for _, tt := range tests {
+		source, err := r.Lookup(tt.url)
+		if err != nil {
+			t.Errorf("Lookup(%q) error = %v", tt.url, err)
+			continue
+		}
+		if source.Name() != tt.want {
+			t.Errorf("Lookup(%q) = %s, want %s", tt.url, source.Name(), tt.want)
+		}
+	}
+
+	for _, url := range []string{"https://example.com/episode/1", "ftp://example.com/feed.xml", "not a url"} {
+		if _, err := r.Lookup(url); !errors.Is(err, ErrUnsupportedURL) {
+			t.Errorf("Lookup(%q) error = %v, want ErrUnsupportedURL", url, err)
+		}
+	}
+
+	shows := map[string]bool{
+		"https://www.xiaoyuzhoufm.com/podcast/5e280fab418a84a0461fa8c4": true,
+		"https://www.xiaoyuzhoufm.com/episode/69392768281939cce65925d3": false,
+		"https://example.com/podcast/feed.xml":                          true,
+		FeedEpisodeURL("https://example.com/feed.xml", "ep-1"):          false,
+		"https://example.com/episode/1":                                 false,
+	}
+	for url, want := range shows {
+		if got := r.IsShow(url); got != want {
+			t.Errorf("IsShow(%q) = %v, want %v", url, got, want)
+		}
+	}
+}
@@ -59,7 +59,18 @@ func (e *HTMLExtractor) ExtractURL(ctx context.Context, pageURL string) (*Episod
 	// Fetch the page
 	doc, err := e.client.Get(pageURL)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrPageNotFound, err)
+		return nil, fmt.Errorf("%w: %w", ErrPageNotFound, err)
+	}
+
+	return e.extractDocument(doc)
+}
+
+// extractDocument extracts metadata from an already fetched episode page.
+// The page's embedded __NEXT_DATA__ JSON is preferred; the HTML selectors
+// below are only used when it is missing or has no audio URL.
+func (e *HTMLExtractor) extractDocument(doc *goquery.Document) (*EpisodeMetadata, error) {
+	if metadata, err := extractEpisodeNextData(doc); err == nil && metadata.AudioURL != "" {
+		return metadata, nil
 	}
 
 	// Create metadata struct
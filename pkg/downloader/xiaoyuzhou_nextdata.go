@@ -0,0 +1,178 @@
+package downloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/meixg/podcast-reader/pkg/models"
+)
+
+// xiaoyuzhouEpisodeURL is the page URL of a Xiaoyuzhou FM episode, by episode ID.
+const xiaoyuzhouEpisodeURL = "https://www.xiaoyuzhoufm.com/episode/"
+
+// nextData mirrors the parts of the __NEXT_DATA__ JSON embedded in Xiaoyuzhou FM pages.
+type nextData struct {
+	Props struct {
+		PageProps struct {
+			Podcast *xyzPodcast `json:"podcast"` // Podcast pages
+			Episode *xyzEpisode `json:"episode"` // Episode pages
+		} `json:"pageProps"`
+	} `json:"props"`
+}
+
+type xyzImage struct {
+	PicURL       string `json:"picUrl"`
+	LargePicURL  string `json:"largePicUrl"`
+	MiddlePicURL string `json:"middlePicUrl"`
+	SmallPicURL  string `json:"smallPicUrl"`
+	ThumbnailURL string `json:"thumbnailUrl"`
+}
+
+func (i *xyzImage) url() string {
+	if i == nil {
+		return ""
+	}
+	return firstNonEmpty(i.LargePicURL, i.PicURL, i.MiddlePicURL)
+}
+
+// sizes returns the image URLs by size, or nil if there are none.
+func (i *xyzImage) sizes() *models.CoverURLs {
+	if i == nil || i.url() == "" && i.SmallPicURL == "" && i.ThumbnailURL == "" {
+		return nil
+	}
+	return &models.CoverURLs{
+		Original:  i.PicURL,
+		Large:     i.LargePicURL,
+		Medium:    i.MiddlePicURL,
+		Small:     i.SmallPicURL,
+		Thumbnail: i.ThumbnailURL,
+	}
+}
+
+type xyzPodcast struct {
+	PID          string       `json:"pid"`
+	Title        string       `json:"title"`
+	Author       string       `json:"author"`
+	Description  string       `json:"description"`
+	Image        *xyzImage    `json:"image"`
+	EpisodeCount int          `json:"episodeCount"` // All episodes, not just the embedded ones
+	Episodes     []xyzEpisode `json:"episodes"`
+}
+
+type xyzEpisode struct {
+	EID         string    `json:"eid"`
+	PID         string    `json:"pid"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	ShowNotes   string    `json:"shownotes"`
+	Duration    int       `json:"duration"` // Seconds
+	PubDate     string    `json:"pubDate"`
+	Image       *xyzImage `json:"image"`
+	Enclosure   struct {
+		URL string `json:"url"`
+	} `json:"enclosure"`
+	Media struct {
+		MimeType string `json:"mimeType"`
+		Source   struct {
+			URL string `json:"url"`
+		} `json:"source"`
+	} `json:"media"`
+	Podcast *struct {
+		PID   string    `json:"pid"`
+		Title string    `json:"title"`
+		Image *xyzImage `json:"image"`
+	} `json:"podcast"`
+}
+
+// toEpisodeMetadata converts an episode from __NEXT_DATA__. Episodes without
+// audio get an empty AudioURL; podcastName and coverURL fill in missing values.
+func (e *xyzEpisode) toEpisodeMetadata(podcastName, coverURL string) *EpisodeMetadata {
+	episode := &EpisodeMetadata{
+		AudioURL:  firstNonEmpty(e.Enclosure.URL, e.Media.Source.URL),
+		AudioType: e.Media.MimeType,
+		CoverURL:  e.Image.url(),
+		CoverURLs: e.Image.sizes(),
+		ShowNotes: firstNonEmpty(e.ShowNotes, e.Description),
+		Title:     strings.TrimSpace(e.Title),
+		GUID:      e.EID,
+		PodcastID: e.PID,
+		Duration:  time.Duration(e.Duration) * time.Second,
+		PageURL:   xiaoyuzhouEpisodeURL + e.EID,
+	}
+	if e.Podcast != nil {
+		podcastName = firstNonEmpty(e.Podcast.Title, podcastName)
+		coverURL = firstNonEmpty(coverURL, e.Podcast.Image.url())
+		episode.PodcastID = firstNonEmpty(episode.PodcastID, e.Podcast.PID)
+		if episode.CoverURLs == nil {
+			episode.CoverURLs = e.Podcast.Image.sizes()
+		}
+	}
+	episode.PodcastName = podcastName
+	if episode.CoverURL == "" {
+		episode.CoverURL = coverURL
+	}
+	if t, ok := parseFeedDate(e.PubDate); ok {
+		episode.PublicationDate = t
+	}
+	return episode
+}
+
+// parseNextData decodes the __NEXT_DATA__ script of a Next.js page.
+func parseNextData(doc *goquery.Document) (*nextData, error) {
+	script := doc.Find("script#__NEXT_DATA__").First()
+	if script.Length() == 0 {
+		return nil, fmt.Errorf("%w: 页面缺少__NEXT_DATA__", ErrPageNotFound)
+	}
+
+	var data nextData
+	if err := json.Unmarshal([]byte(script.Text()), &data); err != nil {
+		return nil, fmt.Errorf("%w: 无法解析__NEXT_DATA__: %v", ErrPageNotFound, err)
+	}
+	return &data, nil
+}
+
+// extractEpisodeNextData extracts an episode page's metadata from __NEXT_DATA__.
+// It fails with ErrPageNotFound when the page doesn't embed the episode, so
+// callers can fall back to scraping the rendered HTML.
+func extractEpisodeNextData(doc *goquery.Document) (*EpisodeMetadata, error) {
+	data, err := parseNextData(doc)
+	if err != nil {
+		return nil, err
+	}
+	episode := data.Props.PageProps.Episode
+	if episode == nil || episode.EID == "" {
+		return nil, fmt.Errorf("%w: 页面中没有单集信息", ErrPageNotFound)
+	}
+	return episode.toEpisodeMetadata("", ""), nil
+}
+
+// extractShowEpisodes lists the episodes embedded in a podcast page, newest first.
+// The page only embeds the most recent episodes of long-running shows; the
+// rest can't be fetched without signing in, so a *TruncatedListError reports
+// them along with the embedded episodes.
+func extractShowEpisodes(doc *goquery.Document) ([]*EpisodeMetadata, error) {
+	data, err := parseNextData(doc)
+	if err != nil {
+		return nil, err
+	}
+	podcast := data.Props.PageProps.Podcast
+	if podcast == nil {
+		return nil, fmt.Errorf("%w: 页面中没有播客信息", ErrPageNotFound)
+	}
+
+	coverURL := podcast.Image.url()
+	episodes := make([]*EpisodeMetadata, 0, len(podcast.Episodes))
+	for i := range podcast.Episodes {
+		if podcast.Episodes[i].EID == "" {
+			continue
+		}
+		episodes = append(episodes, podcast.Episodes[i].toEpisodeMetadata(podcast.Title, coverURL))
+	}
+	if podcast.EpisodeCount > len(podcast.Episodes) {
+		return episodes, &TruncatedListError{Listed: len(episodes), Total: podcast.EpisodeCount}
+	}
+	return episodes, nil
+}
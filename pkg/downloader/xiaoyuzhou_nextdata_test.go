@@ -0,0 +1,169 @@
+package downloader
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/meixg/podcast-reader/pkg/models"
+)
+
+const testShowPage = `<html><head><title>Show</title></head><body>
+<script id="__NEXT_DATA__" type="application/json">{"props":{"pageProps":{"podcast":{
+  "pid":"p1","title":"测试播客","image":{"picUrl":"https://img.example.com/show.jpg"},
+  "episodes":[
+    {"eid":"e1","title":"第一期","duration":1830,"pubDate":"2024-01-05T08:00:00.000Z",
+     "enclosure":{"url":"https://media.example.com/e1.m4a"},"shownotes":"<p>notes</p>"},
+    {"eid":"e3","title":"第三期","duration":600,"pubDate":"2024-03-01T08:00:00.000Z",
+     "media":{"mimeType":"audio/mpeg","source":{"url":"https://media.example.com/e3.mp3"}},
+     "image":{"picUrl":"https://img.example.com/e3.jpg"}},
+    {"eid":"e2","title":"第二期","pubDate":"2024-02-01T08:00:00.000Z",
+     "enclosure":{"url":"https://media.example.com/e2.m4a"}}
+  ]}}}}</script></body></html>`
+
+func TestExtractShowEpisodes(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(testShowPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	episodes, err := extractShowEpisodes(doc)
+	if err != nil {
+		t.Fatalf("extractShowEpisodes() error = %v", err)
+	}
+	if len(episodes) != 3 {
+		t.Fatalf("got %d episodes, want 3", len(episodes))
+	}
+
+	first := episodes[0]
+	if first.PageURL != "https://www.xiaoyuzhoufm.com/episode/e1" || first.GUID != "e1" {
+		t.Errorf("PageURL = %q, GUID = %q", first.PageURL, first.GUID)
+	}
+	if first.PodcastName != "测试播客" || first.CoverURL != "https://img.example.com/show.jpg" {
+		t.Errorf("PodcastName = %q, CoverURL = %q", first.PodcastName, first.CoverURL)
+	}
+	if first.Duration != 1830*time.Second || first.ShowNotes != "<p>notes</p>" {
+		t.Errorf("Duration = %v, ShowNotes = %q", first.Duration, first.ShowNotes)
+	}
+	if got := first.PublicationDate.Format("2006-01-02"); got != "2024-01-05" {
+		t.Errorf("PublicationDate = %s", got)
+	}
+	if episodes[1].AudioExtension() != ".mp3" || episodes[1].CoverURL != "https://img.example.com/e3.jpg" {
+		t.Errorf("AudioExtension = %s, CoverURL = %q", episodes[1].AudioExtension(), episodes[1].CoverURL)
+	}
+
+	// Long-running shows embed only their newest episodes
+	long := strings.Replace(testShowPage, `"pid":"p1",`, `"pid":"p1","episodeCount":120,`, 1)
+	doc, err = goquery.NewDocumentFromReader(strings.NewReader(long))
+	if err != nil {
+		t.Fatal(err)
+	}
+	episodes, err = extractShowEpisodes(doc)
+	var truncated *TruncatedListError
+	if !errors.As(err, &truncated) || truncated.Listed != 3 || truncated.Total != 120 || len(episodes) != 3 {
+		t.Errorf("extractShowEpisodes(120 episodes) = %d episodes, %v", len(episodes), err)
+	}
+
+	if _, err := extractShowEpisodes(&goquery.Document{Selection: doc.Find("title")}); err == nil {
+		t.Error("extractShowEpisodes() without __NEXT_DATA__ succeeded")
+	}
+}
+
+func TestEpisodeFilter_Apply(t *testing.T) {
+	date := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	episodes := []*EpisodeMetadata{
+		{GUID: "jan", PublicationDate: date("2024-01-05")},
+		{GUID: "mar", PublicationDate: date("2024-03-01")},
+		{GUID: "feb", PublicationDate: date("2024-02-01")},
+	}
+	guids := func(episodes []*EpisodeMetadata) string {
+		var s []string
+		for _, e := range episodes {
+			s = append(s, e.GUID)
+		}
+		return strings.Join(s, ",")
+	}
+
+	tests := []struct {
+		name        string
+		filter      EpisodeFilter
+		want        string
+		wantSkipped int
+	}{
+		{"all", EpisodeFilter{}, "mar,feb,jan", 0},
+		{"latest", EpisodeFilter{Latest: 2}, "mar,feb", 0},
+		{"range", EpisodeFilter{Since: date("2024-01-10"), Until: date("2024-02-28")}, "feb", 0},
+		{"skip", EpisodeFilter{Latest: 2, Skip: func(e *EpisodeMetadata) bool { return e.GUID == "mar" }}, "feb", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, skipped := tt.filter.Apply(episodes)
+			if guids(got) != tt.want || skipped != tt.wantSkipped {
+				t.Errorf("Apply() = %s (%d skipped), want %s (%d skipped)", guids(got), skipped, tt.want, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+const testEpisodePage = `<html><head><title>旧标题</title>
+<meta property="og:audio" content="https://media.example.com/og.m4a"></head><body>
+<div class="avater-container"><img src="https://img.example.com/old.jpg"></div>
+<script id="__NEXT_DATA__" type="application/json">{"props":{"pageProps":{"episode":{
+  "eid":"e9","pid":"p1","title":" 第九期 ","duration":4512,"pubDate":"2024-05-20T12:30:00.000Z",
+  "shownotes":"<p>本期内容</p>","enclosure":{"url":"https://media.example.com/e9.m4a"},
+  "image":{"picUrl":"https://img.example.com/e9.jpg","largePicUrl":"https://img.example.com/e9@large.jpg",
+    "middlePicUrl":"https://img.example.com/e9@middle.jpg","thumbnailUrl":"https://img.example.com/e9@thumb.jpg"},
+  "podcast":{"pid":"p1","title":"测试播客"}}}}}</script></body></html>`
+
+func TestExtractEpisodeNextData(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(testEpisodePage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	episode, err := NewHTMLExtractor(nil).extractDocument(doc)
+	if err != nil {
+		t.Fatalf("extractDocument() error = %v", err)
+	}
+	if episode.GUID != "e9" || episode.PodcastID != "p1" || episode.Title != "第九期" || episode.PodcastName != "测试播客" {
+		t.Errorf("GUID = %q, PodcastID = %q, Title = %q, PodcastName = %q", episode.GUID, episode.PodcastID, episode.Title, episode.PodcastName)
+	}
+	if episode.AudioURL != "https://media.example.com/e9.m4a" || episode.Duration != 4512*time.Second || episode.ShowNotes != "<p>本期内容</p>" {
+		t.Errorf("AudioURL = %q, Duration = %v, ShowNotes = %q", episode.AudioURL, episode.Duration, episode.ShowNotes)
+	}
+	if episode.CoverURL != "https://img.example.com/e9@large.jpg" || episode.CoverURLs == nil || episode.CoverURLs.Thumbnail != "https://img.example.com/e9@thumb.jpg" {
+		t.Errorf("CoverURL = %q, CoverURLs = %+v", episode.CoverURL, episode.CoverURLs)
+	}
+	if got := episode.CoverDownloadURL(); got != "https://img.example.com/e9.jpg" {
+		t.Errorf("CoverDownloadURL() = %q, want the original size", got)
+	}
+	// The podcast ID and cover sizes are saved to .metadata.json, with or without page metadata
+	for _, pageMetadata := range []*models.PodcastMetadata{nil, {Duration: "75分钟"}} {
+		episode.PageMetadata = pageMetadata
+		if metadata := episode.ToPodcastMetadata(); metadata.PodcastID != "p1" || metadata.CoverURLs.Largest() != "https://img.example.com/e9.jpg" {
+			t.Errorf("ToPodcastMetadata() = %+v", metadata)
+		}
+	}
+	episode.PageMetadata = nil
+	if want := time.Date(2024, 5, 20, 12, 30, 0, 0, time.UTC); !episode.PublicationDate.Equal(want) {
+		t.Errorf("PublicationDate = %v, want %v", episode.PublicationDate, want)
+	}
+	if metadata := NewMetadataExtractor(nil).extractDocument(doc); metadata.Duration != "75分钟" || metadata.PublishTime != "2024-05-20" {
+		t.Errorf("page metadata = %+v", metadata)
+	}
+
+	// Without __NEXT_DATA__ the selectors are used
+	doc.Find("script#__NEXT_DATA__").Remove()
+	episode, err = NewHTMLExtractor(nil).extractDocument(doc)
+	if err != nil {
+		t.Fatalf("extractDocument() without __NEXT_DATA__ error = %v", err)
+	}
+	if episode.AudioURL != "https://media.example.com/og.m4a" || episode.CoverURL != "https://img.example.com/old.jpg" || episode.GUID != "" {
+		t.Errorf("fallback AudioURL = %q, CoverURL = %q, GUID = %q", episode.AudioURL, episode.CoverURL, episode.GUID)
+	}
+}
@@ -0,0 +1,90 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/meixg/podcast-reader/pkg/validator"
+	"github.com/meixg/podcast-reader/pkg/zhtime"
+)
+
+// XiaoyuzhouSource downloads episodes from Xiaoyuzhou FM episode pages and
+// lists the episodes of podcast (show) pages.
+type XiaoyuzhouSource struct {
+	client        Doer
+	validator     validator.URLValidator
+	showValidator validator.URLValidator
+	episodes      *HTMLExtractor
+	metadata      *MetadataExtractor
+}
+
+// NewXiaoyuzhouSource creates a Xiaoyuzhou FM source that fetches pages with client.
+func NewXiaoyuzhouSource(client *http.Client) *XiaoyuzhouSource {
+	doer := &HTTPClient{client: client}
+	return &XiaoyuzhouSource{
+		client:        doer,
+		validator:     validator.NewXiaoyuzhouURLValidator(),
+		showValidator: validator.NewXiaoyuzhouShowURLValidator(),
+		episodes:      NewHTMLExtractor(doer),
+		metadata:      NewMetadataExtractor(doer),
+	}
+}
+
+// Name implements Source.
+func (s *XiaoyuzhouSource) Name() string {
+	return "xiaoyuzhou"
+}
+
+// Match implements Source for Xiaoyuzhou FM episode and show URLs.
+func (s *XiaoyuzhouSource) Match(url string) bool {
+	valid, _ := s.validator.ValidateURL(url)
+	return valid || s.IsShow(url)
+}
+
+// IsShow implements Source for Xiaoyuzhou FM podcast pages.
+func (s *XiaoyuzhouSource) IsShow(url string) bool {
+	valid, _ := s.showValidator.ValidateURL(url)
+	return valid
+}
+
+// Resolve fetches the episode page once and extracts both the download
+// metadata and the duration and publish time shown on the page.
+func (s *XiaoyuzhouSource) Resolve(ctx context.Context, url string) (*EpisodeMetadata, error) {
+	doc, err := s.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPageNotFound, err)
+	}
+
+	episode, err := s.episodes.extractDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	episode.PageURL = url
+	episode.PageMetadata = s.metadata.extractDocument(doc)
+	if episode.PodcastName == "" {
+		episode.PodcastName = episode.PageMetadata.PodcastName
+	}
+	if episode.PublicationDate.IsZero() && episode.PageMetadata.PublishedAt != nil {
+		episode.PublicationDate = *episode.PageMetadata.PublishedAt
+	}
+	if episode.Duration == 0 {
+		if d, err := zhtime.ParseDuration(episode.PageMetadata.Duration); err == nil {
+			episode.Duration = d
+		}
+	}
+	return episode, nil
+}
+
+// List implements Source using the episode list embedded in the podcast page.
+func (s *XiaoyuzhouSource) List(ctx context.Context, showURL string) ([]*EpisodeMetadata, error) {
+	if !s.IsShow(showURL) {
+		return nil, fmt.Errorf("%w: %s", ErrListingNotSupported, showURL)
+	}
+
+	doc, err := s.client.Get(showURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPageNotFound, err)
+	}
+	return extractShowEpisodes(doc)
+}
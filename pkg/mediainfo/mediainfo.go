@@ -0,0 +1,69 @@
+// Package mediainfo reads technical information, such as the exact duration,
+// from downloaded audio files without decoding them.
+package mediainfo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnsupportedFormat is returned for files that are neither MP4 nor MP3.
+	ErrUnsupportedFormat = errors.New("unsupported audio format")
+	// ErrNoDuration is returned when a file doesn't contain enough information
+	// to compute its duration.
+	ErrNoDuration = errors.New("duration not found")
+)
+
+// contentTypes maps audio file extensions to their media types.
+var contentTypes = map[string]string{
+	".m4a": "audio/mp4",
+	".m4b": "audio/mp4",
+	".mp4": "audio/mp4",
+	".mp3": "audio/mpeg",
+}
+
+// ContentType returns the media type of an audio file from its extension,
+// or "application/octet-stream" if it isn't a known audio format.
+func ContentType(path string) string {
+	if contentType, ok := contentTypes[strings.ToLower(filepath.Ext(path))]; ok {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// Duration returns the duration of an .m4a/.mp4 or .mp3 file.
+func Duration(path string) (time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".m4a", ".mp4", ".m4b":
+		return MP4Duration(f, info.Size())
+	case ".mp3":
+		return MP3Duration(f, info.Size())
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedFormat, filepath.Ext(path))
+	}
+}
+
+// FormatDuration formats d as "MM:SS", or "H:MM:SS" from one hour on.
+func FormatDuration(d time.Duration) string {
+	seconds := int(d.Round(time.Second) / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
@@ -0,0 +1,128 @@
+package mediainfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// mp4Box builds a box with the given payload.
+func mp4Box(typ string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	b := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(b, uint32(8+len(body)))
+	copy(b[4:], typ)
+	return append(b, body...)
+}
+
+// mvhd builds a version 0 movie header.
+func mvhd(timescale, duration uint32) []byte {
+	payload := make([]byte, 100)
+	binary.BigEndian.PutUint32(payload[12:], timescale)
+	binary.BigEndian.PutUint32(payload[16:], duration)
+	binary.BigEndian.PutUint32(payload[96:], 2) // next_track_ID
+	return mp4Box("mvhd", payload)
+}
+
+func TestMP4Duration(t *testing.T) {
+	file := bytes.Join([][]byte{
+		mp4Box("ftyp", []byte("M4A \x00\x00\x00\x00")),
+		mp4Box("mdat", make([]byte, 64)),
+		mp4Box("moov", mvhd(44100, 44100*4512+22050)),
+	}, nil)
+
+	got, err := MP4Duration(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatalf("MP4Duration() error = %v", err)
+	}
+	if want := 4512*time.Second + 500*time.Millisecond; got != want {
+		t.Errorf("MP4Duration() = %v, want %v", got, want)
+	}
+
+	noMoov := mp4Box("ftyp", []byte("M4A "))
+	if _, err := MP4Duration(bytes.NewReader(noMoov), int64(len(noMoov))); !errors.Is(err, ErrNoDuration) {
+		t.Errorf("MP4Duration(no moov) error = %v, want ErrNoDuration", err)
+	}
+	truncated := file[:len(file)-10]
+	if _, err := MP4Duration(bytes.NewReader(truncated), int64(len(truncated))); err == nil {
+		t.Error("MP4Duration(truncated) succeeded")
+	}
+}
+
+// mp3Frames builds n MPEG-1 Layer III frames at 128 kbit/s and 44.1 kHz.
+func mp3Frames(n int) []byte {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+	return bytes.Repeat(frame, n)
+}
+
+func TestMP3Duration(t *testing.T) {
+	id3 := append([]byte("ID3\x03\x00\x00\x00\x00\x00\x0A"), make([]byte, 10)...)
+	cbr := append(append(id3, mp3Frames(1000)...), []byte("TAG")...)
+
+	want := time.Duration(1000*1152) * time.Second / 44100
+	got, err := MP3Duration(bytes.NewReader(cbr), 0)
+	if err != nil {
+		t.Fatalf("MP3Duration() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("MP3Duration(cbr) = %v, want %v", got, want)
+	}
+
+	// With the size known, a CBR file is estimated from its first frames.
+	// These frames leave out the padding real encoders add, so the
+	// estimate is a little short.
+	got, err = MP3Duration(bytes.NewReader(cbr), int64(len(cbr)))
+	if err != nil || got < want-100*time.Millisecond || got > want {
+		t.Errorf("MP3Duration(cbr, size) = %v, %v, want about %v", got, err, want)
+	}
+
+	// A bitrate change in the first frames means the whole file is walked
+	fast := make([]byte, 522)
+	copy(fast, []byte{0xFF, 0xFB, 0xA0, 0x00}) // 160 kbit/s
+	mixed := append(bytes.Repeat(fast, 4), mp3Frames(996)...)
+	got, err = MP3Duration(bytes.NewReader(mixed), int64(len(mixed)))
+	if err != nil || got != want {
+		t.Errorf("MP3Duration(mixed, size) = %v, %v, want %v", got, err, want)
+	}
+
+	// A Xing header in the first frame gives the frame count directly
+	vbr := mp3Frames(1)
+	copy(vbr[36:], "Xing\x00\x00\x00\x01")
+	binary.BigEndian.PutUint32(vbr[44:], 50000)
+	if got, err := MP3Duration(bytes.NewReader(vbr), int64(len(vbr))); err != nil || got != time.Duration(50000*1152)*time.Second/44100 {
+		t.Errorf("MP3Duration(vbr) = %v, %v", got, err)
+	}
+
+	if _, err := MP3Duration(bytes.NewReader([]byte("not audio")), 9); !errors.Is(err, ErrNoDuration) {
+		t.Errorf("MP3Duration(garbage) error = %v, want ErrNoDuration", err)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "episode.m4a")
+	if err := os.WriteFile(path, mp4Box("moov", mvhd(1000, 61000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Duration(path); err != nil || got != 61*time.Second {
+		t.Errorf("Duration() = %v, %v", got, err)
+	}
+	if _, err := Duration(filepath.Join(dir, "cover.jpg")); err == nil {
+		t.Error("Duration(missing file) succeeded")
+	}
+
+	for d, want := range map[time.Duration]string{
+		61 * time.Second:                        "01:01",
+		4512 * time.Second:                      "1:15:12",
+		59*time.Minute + 59600*time.Millisecond: "1:00:00",
+	} {
+		if got := FormatDuration(d); got != want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
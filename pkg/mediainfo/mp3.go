@@ -0,0 +1,192 @@
+package mediainfo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// mp3Bitrates holds bitrates in kbit/s, indexed by [table][layer-1][bitrate
+// index]. Table 0 is for MPEG-1, table 1 for MPEG-2 and 2.5.
+var mp3Bitrates = [2][3][15]int{
+	{ // MPEG-1
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	},
+	{ // MPEG-2 and 2.5
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	},
+}
+
+// mp3SampleRates holds MPEG-1 sample rates; MPEG-2 halves and 2.5 quarters them.
+var mp3SampleRates = [3]int{44100, 48000, 32000}
+
+// mp3Frame is a decoded MP3 frame header.
+type mp3Frame struct {
+	mpeg1      bool
+	layer      int // 1, 2 or 3
+	sampleRate int
+	bitrate    int // Bits per second
+	samples    int // Samples per frame
+	length     int // Frame length in bytes, including the header
+	mono       bool
+}
+
+// parseMP3Frame decodes a 4-byte frame header.
+func parseMP3Frame(h []byte) (mp3Frame, bool) {
+	if len(h) < 4 || h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return mp3Frame{}, false
+	}
+	version := h[1] >> 3 & 3 // 0: 2.5, 2: 2, 3: 1
+	layerBits := h[1] >> 1 & 3
+	bitrateIndex := int(h[2] >> 4)
+	rateIndex := int(h[2] >> 2 & 3)
+	if version == 1 || layerBits == 0 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return mp3Frame{}, false
+	}
+
+	f := mp3Frame{
+		mpeg1:      version == 3,
+		layer:      4 - int(layerBits),
+		sampleRate: mp3SampleRates[rateIndex],
+		mono:       h[3]>>6 == 3,
+	}
+	table := 1
+	switch version {
+	case 3:
+		table = 0
+	case 2:
+		f.sampleRate /= 2
+	case 0:
+		f.sampleRate /= 4
+	}
+	f.bitrate = mp3Bitrates[table][f.layer-1][bitrateIndex] * 1000
+	padding := int(h[2] >> 1 & 1)
+
+	switch {
+	case f.layer == 1:
+		f.samples = 384
+		f.length = (12*f.bitrate/f.sampleRate + padding) * 4
+	case f.layer == 3 && !f.mpeg1:
+		f.samples = 576
+		f.length = 72*f.bitrate/f.sampleRate + padding
+	default:
+		f.samples = 1152
+		f.length = 144*f.bitrate/f.sampleRate + padding
+	}
+	return f, f.length > 4
+}
+
+// vbrFrameCount reads the frame count from a Xing/Info or VBRI header in the
+// first frame of a VBR file.
+func vbrFrameCount(f mp3Frame, frame []byte) (int, bool) {
+	sideInfo := 32
+	switch {
+	case f.mpeg1 && f.mono:
+		sideInfo = 17
+	case !f.mpeg1 && f.mono:
+		sideInfo = 9
+	case !f.mpeg1:
+		sideInfo = 17
+	}
+	if x := 4 + sideInfo; len(frame) >= x+12 {
+		tag := string(frame[x : x+4])
+		if (tag == "Xing" || tag == "Info") && frame[x+7]&1 != 0 {
+			return int(binary.BigEndian.Uint32(frame[x+8 : x+12])), true
+		}
+	}
+	if len(frame) >= 36+18 && string(frame[36:40]) == "VBRI" {
+		return int(binary.BigEndian.Uint32(frame[36+14 : 36+18])), true
+	}
+	return 0, false
+}
+
+// cbrProbeFrames is how many frames at the start of a file without a VBR
+// header must share a bitrate for it to be treated as constant bitrate.
+const cbrProbeFrames = 16
+
+// MP3Duration computes the duration of an MP3 stream of size bytes. It uses
+// the frame count of a Xing/Info or VBRI header when present. Otherwise, if
+// the first frames all have the same bitrate, the stream is taken to be CBR
+// and the duration is estimated from its size, so a long episode isn't read
+// to the end. A size of 0 or less, or a bitrate that changes, makes it walk
+// every frame header, which is exact for both CBR and VBR streams.
+func MP3Duration(r io.Reader, size int64) (time.Duration, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	offset, err := skipID3v2(br)
+	if err != nil {
+		return 0, err
+	}
+
+	var samples, frames, start int64
+	sampleRate, bitrate := 0, 0
+	constant := true
+	for {
+		header, err := br.Peek(4)
+		if err != nil {
+			break
+		}
+		f, ok := parseMP3Frame(header)
+		if !ok {
+			if bytes.HasPrefix(header, []byte("TAG")) {
+				break // ID3v1 tag at the end
+			}
+			br.Discard(1) // Resynchronize
+			offset++
+			continue
+		}
+
+		if frames == 0 {
+			sampleRate, bitrate, start = f.sampleRate, f.bitrate, offset
+			if frame, _ := br.Peek(f.length); len(frame) > 0 {
+				if count, ok := vbrFrameCount(f, frame); ok && count > 0 {
+					return samplesDuration(int64(count)*int64(f.samples), sampleRate), nil
+				}
+			}
+		}
+		constant = constant && f.bitrate == bitrate
+		if frames == cbrProbeFrames && constant && size > start {
+			return time.Duration(size-start) * 8 * time.Second / time.Duration(bitrate), nil
+		}
+		if _, err := br.Discard(f.length); err != nil && !errors.Is(err, io.EOF) {
+			return 0, fmt.Errorf("read mp3 frame: %w", err)
+		}
+		offset += int64(f.length)
+		frames++
+		samples += int64(f.samples)
+	}
+
+	if frames == 0 {
+		return 0, fmt.Errorf("%w: no MP3 frames", ErrNoDuration)
+	}
+	return samplesDuration(samples, sampleRate), nil
+}
+
+// skipID3v2 skips an ID3v2 tag at the start of the stream and returns its
+// size.
+func skipID3v2(br *bufio.Reader) (int64, error) {
+	header, err := br.Peek(10)
+	if err != nil || string(header[:3]) != "ID3" {
+		return 0, nil
+	}
+	size := int(header[6]&0x7F)<<21 | int(header[7]&0x7F)<<14 | int(header[8]&0x7F)<<7 | int(header[9]&0x7F)
+	size += 10
+	if header[5]&0x10 != 0 {
+		size += 10 // Footer
+	}
+	if _, err := br.Discard(size); err != nil {
+		return 0, fmt.Errorf("%w: truncated ID3v2 tag", ErrNoDuration)
+	}
+	return int64(size), nil
+}
+
+func samplesDuration(samples int64, sampleRate int) time.Duration {
+	return time.Duration(samples) * time.Second / time.Duration(sampleRate)
+}
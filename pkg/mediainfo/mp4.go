@@ -0,0 +1,131 @@
+package mediainfo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// box is an MP4 (ISO base media) box located in a file.
+type box struct {
+	typ        string
+	offset     int64 // Start of the box header
+	size       int64 // Including the header
+	headerSize int64
+}
+
+// dataOffset returns where the box payload starts.
+func (b box) dataOffset() int64 {
+	return b.offset + b.headerSize
+}
+
+// end returns the offset just past the box.
+func (b box) end() int64 {
+	return b.offset + b.size
+}
+
+// readBoxes lists the boxes stored back to back in [start, end).
+func readBoxes(r io.ReaderAt, start, end int64) ([]box, error) {
+	var boxes []box
+	for offset := start; offset+8 <= end; {
+		var header [16]byte
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return nil, fmt.Errorf("read box header at %d: %w", offset, err)
+		}
+		b := box{
+			typ:        string(header[4:8]),
+			offset:     offset,
+			size:       int64(binary.BigEndian.Uint32(header[:4])),
+			headerSize: 8,
+		}
+		switch b.size {
+		case 0: // Box extends to the end of its container
+			b.size = end - offset
+		case 1: // 64-bit size follows the type
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return nil, fmt.Errorf("read box size at %d: %w", offset, err)
+			}
+			b.size = int64(binary.BigEndian.Uint64(header[8:16]))
+			b.headerSize = 16
+		}
+		if b.size < b.headerSize || b.end() > end {
+			return nil, fmt.Errorf("invalid %q box size %d at %d", b.typ, b.size, offset)
+		}
+		boxes = append(boxes, b)
+		offset = b.end()
+	}
+	return boxes, nil
+}
+
+// findBox returns the first box of the given type, or false.
+func findBox(boxes []box, typ string) (box, bool) {
+	for _, b := range boxes {
+		if b.typ == typ {
+			return b, true
+		}
+	}
+	return box{}, false
+}
+
+// childBoxes lists the boxes inside a container box.
+func childBoxes(r io.ReaderAt, parent box) ([]box, error) {
+	return readBoxes(r, parent.dataOffset(), parent.end())
+}
+
+// MP4Duration reads the duration of an MP4/M4A file of the given size from
+// its movie header (moov/mvhd).
+func MP4Duration(r io.ReaderAt, size int64) (time.Duration, error) {
+	top, err := readBoxes(r, 0, size)
+	if err != nil {
+		return 0, err
+	}
+	moov, ok := findBox(top, "moov")
+	if !ok {
+		return 0, fmt.Errorf("%w: no moov box", ErrNoDuration)
+	}
+	children, err := childBoxes(r, moov)
+	if err != nil {
+		return 0, err
+	}
+	mvhd, ok := findBox(children, "mvhd")
+	if !ok {
+		return 0, fmt.Errorf("%w: no mvhd box", ErrNoDuration)
+	}
+
+	data := make([]byte, 32)
+	n, err := r.ReadAt(data, mvhd.dataOffset())
+	if err != nil && err != io.EOF {
+		return 0, fmt.Errorf("read mvhd: %w", err)
+	}
+	timescale, duration, err := parseMvhd(data[:n])
+	if err != nil {
+		return 0, err
+	}
+	if duration == 0 || duration == 1<<32-1 || duration == 1<<64-1 {
+		return 0, fmt.Errorf("%w: mvhd has no duration", ErrNoDuration)
+	}
+	seconds := duration / uint64(timescale)
+	remainder := duration % uint64(timescale)
+	return time.Duration(seconds)*time.Second + time.Duration(remainder)*time.Second/time.Duration(timescale), nil
+}
+
+// parseMvhd reads the timescale and duration from an mvhd payload: version(1)
+// flags(3), then creation and modification times, timescale and duration,
+// 32-bit in version 0 and 64-bit (except timescale) in version 1.
+func parseMvhd(data []byte) (timescale uint32, duration uint64, err error) {
+	switch {
+	case len(data) >= 20 && data[0] == 0:
+		timescale = binary.BigEndian.Uint32(data[12:16])
+		duration = uint64(binary.BigEndian.Uint32(data[16:20]))
+	case len(data) >= 32 && data[0] == 1:
+		timescale = binary.BigEndian.Uint32(data[20:24])
+		duration = binary.BigEndian.Uint64(data[24:32])
+	default:
+		return 0, 0, fmt.Errorf("%w: truncated mvhd box", ErrNoDuration)
+	}
+	if timescale == 0 {
+		return 0, 0, fmt.Errorf("%w: mvhd has no timescale", ErrNoDuration)
+	}
+	return timescale, duration, nil
+}
@@ -0,0 +1,439 @@
+package mediainfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Chapter is a chapter marker written to an MP4 file.
+type Chapter struct {
+	Start time.Duration
+	Title string
+}
+
+// maxNeroChapters is the most chapters a chpl box can hold.
+const maxNeroChapters = 255
+
+// chapterTimescale is the media timescale of the chapter track (milliseconds).
+const chapterTimescale = 1000
+
+// chapterTrack is a QuickTime chapter track added to a movie. Its text samples
+// are appended to the file in their own mdat box.
+type chapterTrack struct {
+	id      uint32
+	samples []byte
+}
+
+// setNeroChapters returns the moov box with moov/udta/chpl set to chapters.
+// Nero chapters are read by most non-Apple players.
+func setNeroChapters(moov []byte, chapters []Chapter) ([]byte, error) {
+	if len(chapters) > maxNeroChapters {
+		chapters = chapters[:maxNeroChapters]
+	}
+	// version 1, flags, reserved, chapter count
+	payload := []byte{1, 0, 0, 0, 0, 0, 0, 0, byte(len(chapters))}
+	for _, c := range chapters {
+		title := truncateUTF8(c.Title, 255)
+		payload = binary.BigEndian.AppendUint64(payload, uint64(c.Start/100)) // 100ns units
+		payload = append(payload, byte(len(title)))
+		payload = append(payload, title...)
+	}
+
+	return rewriteChild(moov, 8, "udta", func(udta []byte) ([]byte, error) {
+		if udta == nil {
+			udta = makeBox("udta")
+		}
+		return rewriteChild(udta, 8, "chpl", func([]byte) ([]byte, error) {
+			return makeBox("chpl", payload), nil
+		})
+	})
+}
+
+// setChapterTrack replaces any chapter track of the movie with a text track
+// holding chapters, referenced from the first audio track through tref/chap.
+// Apple players only read chapters from such a track. The chunk offset of the
+// new track is left zero until setOffset is called.
+func setChapterTrack(moov []byte, chapters []Chapter) ([]byte, *chapterTrack, error) {
+	moov, err := removeChapterTracks(moov)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mvhd, ok, err := findPath(moov, "mvhd")
+	if err != nil || !ok {
+		return nil, nil, fmt.Errorf("%w: no mvhd box", ErrNoDuration)
+	}
+	header := moov[mvhd.dataOffset():mvhd.end()]
+	timescale, duration, err := parseMvhd(header)
+	if err != nil {
+		return nil, nil, err
+	}
+	// next_track_ID is the last field of mvhd
+	nextIDOffset := len(header) - 4
+	if nextIDOffset < 20 {
+		return nil, nil, fmt.Errorf("truncated mvhd box")
+	}
+	id := binary.BigEndian.Uint32(header[nextIDOffset:])
+	if id == 0 || id == math.MaxUint32 {
+		return nil, nil, fmt.Errorf("invalid next track ID %d", id)
+	}
+	binary.BigEndian.PutUint32(header[nextIDOffset:], id+1)
+
+	movieLength := time.Duration(duration) * time.Second / time.Duration(timescale)
+	trak, samples := chapterTrak(id, chapters, duration, movieLength)
+
+	// Reference the chapter track from the audio track
+	audioTrak := -1
+	children, err := childrenOf(moov, 8)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, child := range children {
+		if child.typ != "trak" {
+			continue
+		}
+		if handler := trakHandler(moov[child.offset:child.end()]); handler == "soun" || audioTrak < 0 {
+			audioTrak = i
+			if handler == "soun" {
+				break
+			}
+		}
+	}
+	if audioTrak < 0 {
+		return nil, nil, fmt.Errorf("no track to attach chapters to")
+	}
+
+	i := 0
+	moov, err = mapChildren(moov, 8, func(child []byte, typ string) ([]byte, error) {
+		defer func() { i++ }()
+		if i != audioTrak {
+			return child, nil
+		}
+		return addChapterRef(child, id)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	moov, err = setBoxSize(append(moov, trak...))
+	if err != nil {
+		return nil, nil, err
+	}
+	return moov, &chapterTrack{id: id, samples: samples}, nil
+}
+
+// setOffset points the chapter track's single chunk at offset in the file.
+func (t *chapterTrack) setOffset(moov []byte, offset int64) error {
+	if offset > math.MaxUint32 {
+		return fmt.Errorf("chapter samples beyond 4 GiB")
+	}
+	children, err := childrenOf(moov, 8)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		trak := moov[child.offset:child.end()]
+		if child.typ != "trak" || trakID(trak) != t.id {
+			continue
+		}
+		stco, ok, err := findPath(trak, "mdia", "minf", "stbl", "stco")
+		if err != nil || !ok {
+			return fmt.Errorf("chapter track has no stco box")
+		}
+		binary.BigEndian.PutUint32(trak[stco.dataOffset()+8:], uint32(offset))
+		return nil
+	}
+	return fmt.Errorf("chapter track %d not found", t.id)
+}
+
+// isChapterMdat reports whether the mdat box holds exactly the samples of a
+// chapter track of moov, as appended by an earlier WriteMP4Tags.
+func isChapterMdat(moov []byte, mdat box) bool {
+	children, err := childrenOf(moov, 8)
+	if err != nil {
+		return false
+	}
+	for _, id := range chapterTrackIDs(moov, children) {
+		for _, child := range children {
+			trak := moov[child.offset:child.end()]
+			if child.typ != "trak" || trakID(trak) != id {
+				continue
+			}
+			offset, size, ok := singleChunk(trak)
+			if ok && offset == mdat.dataOffset() && size == mdat.size-mdat.headerSize {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// singleChunk returns the offset and size of the samples of a track stored
+// in one chunk.
+func singleChunk(trak []byte) (offset, size int64, ok bool) {
+	stco, found, err := findPath(trak, "mdia", "minf", "stbl", "stco")
+	if err != nil || !found {
+		return 0, 0, false
+	}
+	table := trak[stco.dataOffset():stco.end()]
+	if len(table) < 12 || binary.BigEndian.Uint32(table[4:]) != 1 {
+		return 0, 0, false
+	}
+	offset = int64(binary.BigEndian.Uint32(table[8:]))
+
+	stsz, found, err := findPath(trak, "mdia", "minf", "stbl", "stsz")
+	if err != nil || !found {
+		return 0, 0, false
+	}
+	sizes := trak[stsz.dataOffset():stsz.end()]
+	if len(sizes) < 12 {
+		return 0, 0, false
+	}
+	sampleSize := int64(binary.BigEndian.Uint32(sizes[4:]))
+	count := int(binary.BigEndian.Uint32(sizes[8:]))
+	if sampleSize != 0 {
+		return offset, sampleSize * int64(count), true
+	}
+	if count > (len(sizes)-12)/4 {
+		return 0, 0, false
+	}
+	for i := 0; i < count; i++ {
+		size += int64(binary.BigEndian.Uint32(sizes[12+i*4:]))
+	}
+	return offset, size, true
+}
+
+// chapterTrackIDs returns the IDs of the tracks referenced by tref/chap boxes
+// of the children of moov.
+func chapterTrackIDs(moov []byte, children []box) []uint32 {
+	var ids []uint32
+	for _, child := range children {
+		if child.typ != "trak" {
+			continue
+		}
+		chap, ok, err := findPath(moov[child.offset:child.end()], "tref", "chap")
+		if err != nil || !ok {
+			continue
+		}
+		refs := moov[child.offset+chap.dataOffset() : child.offset+chap.end()]
+		for j := 0; j+4 <= len(refs); j += 4 {
+			ids = append(ids, binary.BigEndian.Uint32(refs[j:]))
+		}
+	}
+	return ids
+}
+
+// removeChapterTracks drops the tracks referenced by tref/chap boxes and the
+// references themselves, so chapters can be rewritten.
+func removeChapterTracks(moov []byte) ([]byte, error) {
+	children, err := childrenOf(moov, 8)
+	if err != nil {
+		return nil, err
+	}
+	chapterIDs := map[uint32]bool{}
+	for _, id := range chapterTrackIDs(moov, children) {
+		chapterIDs[id] = true
+	}
+	if len(chapterIDs) == 0 {
+		return moov, nil
+	}
+
+	return mapChildren(moov, 8, func(child []byte, typ string) ([]byte, error) {
+		if typ != "trak" {
+			return child, nil
+		}
+		if chapterIDs[trakID(child)] {
+			return nil, nil
+		}
+		return mapChildren(child, 8, func(grandchild []byte, typ string) ([]byte, error) {
+			if typ != "tref" {
+				return grandchild, nil
+			}
+			tref, err := mapChildren(grandchild, 8, func(ref []byte, typ string) ([]byte, error) {
+				if typ == "chap" {
+					return nil, nil
+				}
+				return ref, nil
+			})
+			if err != nil || len(tref) == 8 {
+				return nil, err // Drop an empty tref
+			}
+			return tref, nil
+		})
+	})
+}
+
+// addChapterRef adds a tref/chap reference to id to a trak box, placing a new
+// tref right after tkhd, or first if there is none.
+func addChapterRef(trak []byte, id uint32) ([]byte, error) {
+	chap := makeBox("chap", binary.BigEndian.AppendUint32(nil, id))
+	if _, ok, err := findPath(trak, "tref"); err != nil {
+		return nil, err
+	} else if ok {
+		return rewriteChild(trak, 8, "tref", func(tref []byte) ([]byte, error) {
+			return setBoxSize(append(tref, chap...))
+		})
+	}
+	if _, ok, err := findPath(trak, "tkhd"); err != nil {
+		return nil, err
+	} else if !ok {
+		return setBoxSize(append(append(append([]byte{}, trak[:8]...), makeBox("tref", chap)...), trak[8:]...))
+	}
+	return mapChildren(trak, 8, func(child []byte, typ string) ([]byte, error) {
+		if typ == "tkhd" {
+			return append(append([]byte{}, child...), makeBox("tref", chap)...), nil
+		}
+		return child, nil
+	})
+}
+
+// chapterTrak builds the chapter text track and its samples. Each sample
+// lasts until the next chapter starts; the last one until the movie ends.
+func chapterTrak(id uint32, chapters []Chapter, movieDuration uint64, movieLength time.Duration) (trak, samples []byte) {
+	var stts, stsz []byte
+	for i, c := range chapters {
+		end := movieLength
+		if i+1 < len(chapters) {
+			end = chapters[i+1].Start
+		}
+		length := uint32(max((end-c.Start)/time.Millisecond, 1))
+
+		title := truncateUTF8(c.Title, math.MaxUint16)
+		sample := binary.BigEndian.AppendUint16(nil, uint16(len(title)))
+		sample = append(sample, title...)
+		sample = append(sample, makeBox("encd", []byte{0, 0, 1, 0})...) // UTF-8
+		samples = append(samples, sample...)
+
+		stts = binary.BigEndian.AppendUint32(stts, 1)
+		stts = binary.BigEndian.AppendUint32(stts, length)
+		stsz = binary.BigEndian.AppendUint32(stsz, uint32(len(sample)))
+	}
+	count := uint32(len(chapters))
+	mediaDuration := uint32(min(movieLength/time.Millisecond, math.MaxUint32))
+
+	tkhd := make([]byte, 84)
+	binary.BigEndian.PutUint32(tkhd[12:], id)
+	binary.BigEndian.PutUint32(tkhd[20:], uint32(min(movieDuration, math.MaxUint32)))
+	copy(tkhd[40:], identityMatrix())
+
+	mdhd := make([]byte, 24)
+	binary.BigEndian.PutUint32(mdhd[12:], chapterTimescale)
+	binary.BigEndian.PutUint32(mdhd[16:], mediaDuration)
+	binary.BigEndian.PutUint16(mdhd[20:], 0x55C4) // "und"
+
+	textEntry := append(make([]byte, 6), 0, 1) // reserved, data reference index
+	textEntry = append(textEntry,
+		0, 0, 0, 1, // display flags
+		0, 0, // justification
+		0, 0, 0, 0, // background color
+		0, 0, 0, 0, 0, 0, 0, 0, // default text box
+		0, 0, 0, 0, // start and end char
+		0, 1, // font ID
+		0, 0, // face style, font size
+		0, 0, 0, 0, // foreground color
+	)
+	textEntry = append(textEntry, makeBox("ftab", []byte{0, 1, 0, 1, 0})...)
+
+	gmin := []byte{0, 0, 0, 0, 0, 0x40, 0x80, 0, 0x80, 0, 0x80, 0, 0, 0, 0, 0}
+	text := []byte{
+		0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0x40, 0, 0, 0,
+	}
+	trak = makeBox("trak",
+		makeBox("tkhd", tkhd),
+		makeBox("mdia",
+			makeBox("mdhd", mdhd),
+			makeBox("hdlr", make([]byte, 8), []byte("text"), make([]byte, 13)),
+			makeBox("minf",
+				makeBox("gmhd", makeBox("gmin", gmin), makeBox("text", text)),
+				makeBox("dinf", makeBox("dref", []byte{0, 0, 0, 0, 0, 0, 0, 1}, makeBox("url ", []byte{0, 0, 0, 1}))),
+				makeBox("stbl",
+					makeBox("stsd", []byte{0, 0, 0, 0, 0, 0, 0, 1}, makeBox("text", textEntry)),
+					makeBox("stts", make([]byte, 4), binary.BigEndian.AppendUint32(nil, count), stts),
+					makeBox("stsc", []byte{0, 0, 0, 0, 0, 0, 0, 1}, binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, 1), count), []byte{0, 0, 0, 1}),
+					makeBox("stsz", make([]byte, 8), binary.BigEndian.AppendUint32(nil, count), stsz),
+					makeBox("stco", []byte{0, 0, 0, 0, 0, 0, 0, 1}, make([]byte, 4)),
+				),
+			),
+		),
+	)
+	return trak, samples
+}
+
+// identityMatrix returns the transformation matrix of an untransformed track.
+func identityMatrix() []byte {
+	var m []byte
+	for _, v := range []uint32{0x00010000, 0, 0, 0, 0x00010000, 0, 0, 0, 0x40000000} {
+		m = binary.BigEndian.AppendUint32(m, v)
+	}
+	return m
+}
+
+// trakID returns the track ID from the tkhd box of a trak box.
+func trakID(trak []byte) uint32 {
+	tkhd, ok, err := findPath(trak, "tkhd")
+	if err != nil || !ok {
+		return 0
+	}
+	data := trak[tkhd.dataOffset():tkhd.end()]
+	idOffset := 12 // After version, flags and 32-bit times
+	if len(data) > 0 && data[0] == 1 {
+		idOffset = 20
+	}
+	if len(data) < idOffset+4 {
+		return 0
+	}
+	return binary.BigEndian.Uint32(data[idOffset:])
+}
+
+// trakHandler returns the handler type of a trak box, e.g. "soun".
+func trakHandler(trak []byte) string {
+	hdlr, ok, err := findPath(trak, "mdia", "hdlr")
+	if err != nil || !ok || hdlr.size < hdlr.headerSize+12 {
+		return ""
+	}
+	return string(trak[hdlr.dataOffset()+8 : hdlr.dataOffset()+12])
+}
+
+// childrenOf lists the child boxes of box b, which start at headerSize.
+func childrenOf(b []byte, headerSize int) ([]box, error) {
+	return readBoxes(bytes.NewReader(b), int64(headerSize), int64(len(b)))
+}
+
+// findPath follows a path of container boxes below box b and returns the
+// last box, with offsets relative to b.
+func findPath(b []byte, path ...string) (box, bool, error) {
+	current := box{size: int64(len(b)), headerSize: 8}
+	for _, typ := range path {
+		children, err := readBoxes(bytes.NewReader(b), current.dataOffset(), current.end())
+		if err != nil {
+			return box{}, false, err
+		}
+		var ok bool
+		if current, ok = findBox(children, typ); !ok {
+			return box{}, false, nil
+		}
+	}
+	return current, true, nil
+}
+
+// mapChildren rebuilds box b, whose children start at headerSize, replacing
+// each child with the result of fn. Returning nil drops the child.
+func mapChildren(b []byte, headerSize int, fn func(child []byte, typ string) ([]byte, error)) ([]byte, error) {
+	children, err := childrenOf(b, headerSize)
+	if err != nil {
+		return nil, err
+	}
+	out := append([]byte{}, b[:headerSize]...)
+	for _, child := range children {
+		replacement, err := fn(b[child.offset:child.end()], child.typ)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, replacement...)
+	}
+	return setBoxSize(out)
+}
@@ -0,0 +1,375 @@
+package mediainfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// maxMoovSize bounds the movie box read into memory when rewriting tags.
+const maxMoovSize = 64 << 20
+
+// MP4Tags are the iTunes-style metadata items written to an M4A file.
+// Empty fields leave the existing item untouched.
+type MP4Tags struct {
+	Title       string // ©nam
+	Artist      string // ©ART
+	Album       string // ©alb
+	Date        string // ©day, e.g. "2024-05-20"
+	Description string // desc and ldes
+	Comment     string // ©cmt
+	Cover       []byte // covr; JPEG or PNG
+
+	// Chapters replace the file's chapters, both as a Nero chpl box and as a
+	// QuickTime chapter track; nil leaves existing chapters alone
+	Chapters []Chapter
+}
+
+// ilst item keys; © is byte 0xA9 in MP4 box types.
+const (
+	keyTitle       = "\xa9nam"
+	keyArtist      = "\xa9ART"
+	keyAlbum       = "\xa9alb"
+	keyDate        = "\xa9day"
+	keyComment     = "\xa9cmt"
+	keyDescription = "desc"
+	keyLongDesc    = "ldes"
+	keyCover       = "covr"
+)
+
+// Data atom type indicators.
+const (
+	dataTypeUTF8 = 1
+	dataTypeJPEG = 13
+	dataTypePNG  = 14
+)
+
+// maxDescLength is the length players expect of the short desc item.
+const maxDescLength = 255
+
+// WriteMP4Tags sets the metadata items in moov/udta/meta/ilst of an MP4/M4A
+// file, creating the boxes if needed, and optionally its chapters. Other
+// items are kept. The file is
+// rewritten through a temporary file, and chunk offsets in stco/co64 boxes
+// are shifted when the movie box sits before the media data.
+func WriteMP4Tags(path string, tags MP4Tags) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	top, err := readBoxes(f, 0, info.Size())
+	if err != nil {
+		return err
+	}
+	moov, ok := findBox(top, "moov")
+	if !ok {
+		return fmt.Errorf("%w: no moov box", ErrUnsupportedFormat)
+	}
+	if moov.size > maxMoovSize {
+		return fmt.Errorf("moov box too large: %d bytes", moov.size)
+	}
+
+	oldMoov := make([]byte, moov.size)
+	if _, err := f.ReadAt(oldMoov, moov.offset); err != nil {
+		return fmt.Errorf("read moov: %w", err)
+	}
+	if moov.headerSize != 8 {
+		oldMoov = makeBox("moov", oldMoov[moov.headerSize:])
+	}
+	newMoov, err := setMoovTags(oldMoov, tags)
+	if err != nil {
+		return err
+	}
+	// The chapter samples of an earlier rewrite are dropped with their track
+	// rather than left behind, so rewriting chapters doesn't grow the file
+	end := info.Size()
+	if len(tags.Chapters) > 0 {
+		if last := top[len(top)-1]; last.typ == "mdat" && isChapterMdat(oldMoov, last) {
+			end = last.offset
+			top = top[:len(top)-1]
+		}
+	}
+
+	var chapters *chapterTrack
+	if len(tags.Chapters) > 0 {
+		if newMoov, err = setNeroChapters(newMoov, tags.Chapters); err != nil {
+			return err
+		}
+		if newMoov, chapters, err = setChapterTrack(newMoov, tags.Chapters); err != nil {
+			return err
+		}
+	}
+	delta := int64(len(newMoov)) - moov.size
+	if err := shiftChunkOffsets(newMoov, moov.end(), delta); err != nil {
+		return err
+	}
+	// Chapter samples go in a new mdat box appended to the file
+	var lastBoxSize []byte
+	if chapters != nil {
+		if err := chapters.setOffset(newMoov, end+delta+8); err != nil {
+			return err
+		}
+		if last := top[len(top)-1]; last.typ != "moov" {
+			// A last box sized 0 would extend over the new mdat
+			header := make([]byte, 4)
+			if _, err := f.ReadAt(header, last.offset); err != nil {
+				return err
+			}
+			if binary.BigEndian.Uint32(header) == 0 {
+				if last.size > math.MaxUint32 {
+					return fmt.Errorf("cannot append chapters after a %d byte %q box", last.size, last.typ)
+				}
+				lastBoxSize = binary.BigEndian.AppendUint32(nil, uint32(last.size))
+			}
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tags-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, io.NewSectionReader(f, 0, moov.offset)); err != nil {
+		tmp.Close()
+		return fmt.Errorf("copy audio: %w", err)
+	}
+	if _, err := tmp.Write(newMoov); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write moov: %w", err)
+	}
+	if _, err := io.Copy(tmp, io.NewSectionReader(f, moov.end(), end-moov.end())); err != nil {
+		tmp.Close()
+		return fmt.Errorf("copy audio: %w", err)
+	}
+	if chapters != nil {
+		if _, err := tmp.Write(makeBox("mdat", chapters.samples)); err != nil {
+			tmp.Close()
+			return fmt.Errorf("write chapters: %w", err)
+		}
+	}
+	if lastBoxSize != nil {
+		last := top[len(top)-1]
+		if last.offset > moov.offset {
+			last.offset += delta
+		}
+		if _, err := tmp.WriteAt(lastBoxSize, last.offset); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	f.Close()
+	return os.Rename(tmp.Name(), path)
+}
+
+// setMoovTags returns a copy of the moov box with the tags applied.
+func setMoovTags(moov []byte, tags MP4Tags) ([]byte, error) {
+	return rewriteChild(moov, 8, "udta", func(udta []byte) ([]byte, error) {
+		if udta == nil {
+			udta = makeBox("udta")
+		}
+		return rewriteChild(udta, 8, "meta", func(meta []byte) ([]byte, error) {
+			if meta == nil {
+				// meta is a full box and needs an iTunes metadata handler
+				meta = makeBox("meta", make([]byte, 4), makeBox("hdlr",
+					make([]byte, 8), []byte("mdirappl"), make([]byte, 9)))
+			}
+			return rewriteChild(meta, 12, "ilst", func(ilst []byte) ([]byte, error) {
+				return setItems(ilst, tags)
+			})
+		})
+	})
+}
+
+// rewriteChild replaces the first child box of type typ in box b, whose
+// children start at headerSize, with the result of fn. fn receives nil if
+// there is no such child and the new child is appended.
+func rewriteChild(b []byte, headerSize int, typ string, fn func([]byte) ([]byte, error)) ([]byte, error) {
+	r := bytes.NewReader(b)
+	children, err := readBoxes(r, int64(headerSize), int64(len(b)))
+	if err != nil {
+		return nil, err
+	}
+
+	var old []byte
+	start, end := len(b), len(b)
+	if child, ok := findBox(children, typ); ok {
+		start, end = int(child.offset), int(child.end())
+		old = b[start:end]
+		if child.headerSize != 8 {
+			// Normalize a 64-bit header so callers can assume 8 bytes
+			old = makeBox(typ, b[child.dataOffset():end])
+		}
+	}
+	child, err := fn(old)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(b)-(end-start)+len(child))
+	out = append(out, b[:start]...)
+	out = append(out, child...)
+	out = append(out, b[end:]...)
+	return setBoxSize(out)
+}
+
+// setItems returns the ilst box with the tag items replaced.
+func setItems(ilst []byte, tags MP4Tags) ([]byte, error) {
+	items := map[string][]byte{}
+	text := func(key, value string) {
+		if value != "" {
+			items[key] = dataItem(key, dataTypeUTF8, []byte(value))
+		}
+	}
+	text(keyTitle, tags.Title)
+	text(keyArtist, tags.Artist)
+	text(keyAlbum, tags.Album)
+	text(keyDate, tags.Date)
+	text(keyComment, tags.Comment)
+	if tags.Description != "" {
+		text(keyDescription, truncateUTF8(tags.Description, maxDescLength))
+		text(keyLongDesc, tags.Description)
+	}
+	if len(tags.Cover) > 0 {
+		dataType := uint32(dataTypeJPEG)
+		if bytes.HasPrefix(tags.Cover, []byte("\x89PNG")) {
+			dataType = dataTypePNG
+		}
+		items[keyCover] = dataItem(keyCover, dataType, tags.Cover)
+	}
+
+	out := makeBox("ilst")
+	if ilst != nil {
+		existing, err := readBoxes(bytes.NewReader(ilst), 8, int64(len(ilst)))
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range existing {
+			if _, replaced := items[item.typ]; !replaced {
+				out = append(out, ilst[item.offset:item.end()]...)
+			}
+		}
+	}
+	// Write items in a fixed order so output is deterministic
+	for _, key := range []string{keyTitle, keyArtist, keyAlbum, keyDate, keyDescription, keyLongDesc, keyComment, keyCover} {
+		out = append(out, items[key]...)
+	}
+	return setBoxSize(out)
+}
+
+// dataItem builds an ilst item holding a single data atom.
+func dataItem(key string, dataType uint32, value []byte) []byte {
+	header := make([]byte, 8) // type indicator, locale
+	binary.BigEndian.PutUint32(header, dataType)
+	return makeBox(key, makeBox("data", header, value))
+}
+
+// makeBox builds a box with a 32-bit size header.
+func makeBox(typ string, payload ...[]byte) []byte {
+	b := append([]byte{0, 0, 0, 0}, typ...)
+	for _, p := range payload {
+		b = append(b, p...)
+	}
+	binary.BigEndian.PutUint32(b, uint32(len(b)))
+	return b
+}
+
+// setBoxSize updates the 32-bit size header of b to its length.
+func setBoxSize(b []byte) ([]byte, error) {
+	if int64(len(b)) > math.MaxUint32 {
+		return nil, fmt.Errorf("box too large: %d bytes", len(b))
+	}
+	binary.BigEndian.PutUint32(b, uint32(len(b)))
+	return b, nil
+}
+
+// shiftChunkOffsets adds delta to every chunk offset at or after from in the
+// stco and co64 boxes of the moov box, in place.
+func shiftChunkOffsets(moov []byte, from, delta int64) error {
+	if delta == 0 {
+		return nil
+	}
+	r := bytes.NewReader(moov)
+	var walk func(parent box) error
+	walk = func(parent box) error {
+		children, err := childBoxes(r, parent)
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			switch child.typ {
+			case "trak", "mdia", "minf", "stbl":
+				if err := walk(child); err != nil {
+					return err
+				}
+			case "stco", "co64":
+				if err := shiftTable(moov[child.dataOffset():child.end()], child.typ == "co64", from, delta); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return walk(box{typ: "moov", size: int64(len(moov)), headerSize: 8})
+}
+
+// shiftTable updates the entries of an stco (32-bit) or co64 table.
+func shiftTable(data []byte, wide bool, from, delta int64) error {
+	if len(data) < 8 {
+		return fmt.Errorf("truncated chunk offset box")
+	}
+	count := int(binary.BigEndian.Uint32(data[4:8]))
+	width := 4
+	if wide {
+		width = 8
+	}
+	if count > (len(data)-8)/width {
+		return fmt.Errorf("truncated chunk offset box")
+	}
+	for i := 0; i < count; i++ {
+		entry := data[8+i*width:]
+		if wide {
+			offset := int64(binary.BigEndian.Uint64(entry))
+			if offset >= from {
+				binary.BigEndian.PutUint64(entry, uint64(offset+delta))
+			}
+			continue
+		}
+		offset := int64(binary.BigEndian.Uint32(entry))
+		if offset < from {
+			continue
+		}
+		if offset+delta > math.MaxUint32 {
+			return fmt.Errorf("chunk offset overflows stco after adding tags")
+		}
+		binary.BigEndian.PutUint32(entry, uint32(offset+delta))
+	}
+	return nil
+}
+
+// truncateUTF8 shortens s to at most n bytes without splitting a character.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
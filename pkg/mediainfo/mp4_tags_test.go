@@ -0,0 +1,219 @@
+package mediainfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testM4A builds a file whose single chunk offset points at the mdat payload.
+func testM4A(moovFirst bool) []byte {
+	ftyp := mp4Box("ftyp", []byte("M4A \x00\x00\x00\x00"))
+	mdat := mp4Box("mdat", []byte("AUDIO"))
+	stco := func(offset uint32) []byte {
+		payload := make([]byte, 12)
+		binary.BigEndian.PutUint32(payload[4:], 1)
+		binary.BigEndian.PutUint32(payload[8:], offset)
+		tkhd := make([]byte, 84)
+		binary.BigEndian.PutUint32(tkhd[12:], 1)
+		hdlr := append(make([]byte, 8), "soun\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"...)
+		return mp4Box("moov", mvhd(1000, 90000),
+			mp4Box("trak", mp4Box("tkhd", tkhd),
+				mp4Box("mdia", mp4Box("hdlr", hdlr), mp4Box("minf", mp4Box("stbl", mp4Box("stco", payload))))))
+	}
+
+	if moovFirst {
+		moovSize := len(stco(0))
+		return bytes.Join([][]byte{ftyp, stco(uint32(len(ftyp) + moovSize + 8)), mdat}, nil)
+	}
+	return bytes.Join([][]byte{ftyp, mdat, stco(uint32(len(ftyp) + 8))}, nil)
+}
+
+// chunkData returns the 5 bytes the first chunk offset points at.
+func chunkData(t *testing.T, file []byte) string {
+	t.Helper()
+	i := bytes.Index(file, []byte("stco"))
+	if i < 0 {
+		t.Fatal("no stco box")
+	}
+	offset := binary.BigEndian.Uint32(file[i+12:])
+	return string(file[offset : offset+5])
+}
+
+func TestWriteMP4Tags(t *testing.T) {
+	for _, moovFirst := range []bool{true, false} {
+		path := filepath.Join(t.TempDir(), "podcast.m4a")
+		if err := os.WriteFile(path, testM4A(moovFirst), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		cover := append([]byte("\xff\xd8\xff\xe0"), make([]byte, 100)...)
+		err := WriteMP4Tags(path, MP4Tags{
+			Title:       "第九期",
+			Album:       "测试播客",
+			Date:        "2024-05-20",
+			Description: "本期内容",
+			Comment:     "https://www.xiaoyuzhoufm.com/episode/e9",
+			Cover:       cover,
+			Chapters:    []Chapter{{0, "开场"}, {192 * time.Second, "话题一"}},
+		})
+		if err != nil {
+			t.Fatalf("WriteMP4Tags(moovFirst=%v) error = %v", moovFirst, err)
+		}
+		// Writing again replaces items and chapters and keeps the others
+		chapters := []Chapter{{0, "开场"}, {75 * time.Second, "话题一"}, {80 * time.Second, "话题二"}}
+		if err := WriteMP4Tags(path, MP4Tags{Title: "第九期（修订）", Chapters: chapters}); err != nil {
+			t.Fatalf("WriteMP4Tags() again error = %v", err)
+		}
+		// The chapter samples of the earlier writes are replaced, not kept
+		before, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteMP4Tags(path, MP4Tags{Title: "第九期（修订）", Chapters: chapters}); err != nil {
+			t.Fatalf("WriteMP4Tags() a third time error = %v", err)
+		}
+
+		file, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(file)) != before.Size() {
+			t.Errorf("moovFirst=%v: rewriting the same tags changed the size from %d to %d", moovFirst, before.Size(), len(file))
+		}
+		if n := bytes.Count(file, []byte("mdat")); n != 2 {
+			t.Errorf("moovFirst=%v: %d mdat boxes, want the audio and one for chapters", moovFirst, n)
+		}
+		if got := chunkData(t, file); got != "AUDIO" {
+			t.Errorf("moovFirst=%v: chunk offset points at %q", moovFirst, got)
+		}
+		if d, err := MP4Duration(bytes.NewReader(file), int64(len(file))); err != nil || d != 90*time.Second {
+			t.Errorf("moovFirst=%v: MP4Duration() = %v, %v", moovFirst, d, err)
+		}
+
+		checkChapters(t, file, chapters)
+
+		items, err := readMP4Tags(bytes.NewReader(file), int64(len(file)))
+		if err != nil {
+			t.Fatalf("readMP4Tags() error = %v", err)
+		}
+		want := map[string]string{
+			keyTitle:       "第九期（修订）",
+			keyAlbum:       "测试播客",
+			keyDate:        "2024-05-20",
+			keyDescription: "本期内容",
+			keyComment:     "https://www.xiaoyuzhoufm.com/episode/e9",
+			keyCover:       string(cover),
+		}
+		for key, value := range want {
+			if string(items[key]) != value {
+				t.Errorf("moovFirst=%v: item %q = %q, want %q", moovFirst, key, items[key], value)
+			}
+		}
+	}
+}
+
+// checkChapters verifies the Nero chapters and the chapter track of file.
+func checkChapters(t *testing.T, file []byte, want []Chapter) {
+	t.Helper()
+	top, err := childrenOf(file, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moovBox, _ := findBox(top, "moov")
+	moov := file[moovBox.offset:moovBox.end()]
+
+	chpl, ok, err := findPath(moov, "udta", "chpl")
+	if err != nil || !ok {
+		t.Fatalf("no chpl box: %v", err)
+	}
+	data := moov[chpl.dataOffset():chpl.end()]
+	if int(data[8]) != len(want) {
+		t.Fatalf("chpl has %d chapters, want %d", data[8], len(want))
+	}
+	pos := 9
+	for _, c := range want {
+		start := time.Duration(binary.BigEndian.Uint64(data[pos:])) * 100
+		title := string(data[pos+9 : pos+9+int(data[pos+8])])
+		if start != c.Start || title != c.Title {
+			t.Errorf("chpl chapter = %v %q, want %v %q", start, title, c.Start, c.Title)
+		}
+		pos += 9 + len(title)
+	}
+
+	children, err := childrenOf(moov, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var traks [][]byte
+	for _, child := range children {
+		if child.typ == "trak" {
+			traks = append(traks, moov[child.offset:child.end()])
+		}
+	}
+	if len(traks) != 2 || trakHandler(traks[1]) != "text" {
+		t.Fatalf("got %d tracks, want the audio and one chapter track", len(traks))
+	}
+	chap, ok, _ := findPath(traks[0], "tref", "chap")
+	if !ok || binary.BigEndian.Uint32(traks[0][chap.dataOffset():]) != trakID(traks[1]) {
+		t.Error("audio track doesn't reference the chapter track")
+	}
+	stco, _, _ := findPath(traks[1], "mdia", "minf", "stbl", "stco")
+	offset := binary.BigEndian.Uint32(traks[1][stco.dataOffset()+8:])
+	length := binary.BigEndian.Uint16(file[offset:])
+	if got := string(file[offset+2 : offset+2+uint32(length)]); got != want[0].Title {
+		t.Errorf("first chapter sample = %q, want %q", got, want[0].Title)
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	if got := truncateUTF8("本期内容", 7); got != "本期" {
+		t.Errorf("truncateUTF8() = %q, want %q", got, "本期")
+	}
+}
+
+// readMP4Tags returns the text items and cover of an MP4/M4A file written
+// by WriteMP4Tags.
+func readMP4Tags(r io.ReaderAt, size int64) (map[string][]byte, error) {
+	items := map[string][]byte{}
+	top, err := readBoxes(r, 0, size)
+	if err != nil {
+		return nil, err
+	}
+	current, ok := findBox(top, "moov")
+	for _, next := range []struct {
+		typ    string
+		header int64
+	}{{"udta", 0}, {"meta", 0}, {"ilst", 4}} {
+		if !ok {
+			return items, nil
+		}
+		children, err := readBoxes(r, current.dataOffset()+next.header, current.end())
+		if err != nil {
+			return nil, err
+		}
+		current, ok = findBox(children, next.typ)
+	}
+	if !ok {
+		return items, nil
+	}
+
+	entries, err := childBoxes(r, current)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		data := make([]byte, entry.size-entry.headerSize)
+		if _, err := r.ReadAt(data, entry.dataOffset()); err != nil {
+			return nil, err
+		}
+		if len(data) >= 16 && string(data[4:8]) == "data" {
+			items[entry.typ] = data[16:]
+		}
+	}
+	return items, nil
+}
@@ -4,17 +4,27 @@ import "time"
 
 // DownloadedEpisode represents a downloaded podcast episode with metadata
 type DownloadedEpisode struct {
-	ID             string           `json:"id"`
-	Title          string           `json:"title"`
-	PodcastName    string           `json:"podcastName"`
-	Duration       string           `json:"duration"`
-	FileSize       int64            `json:"fileSize"`
-	DownloadDate   time.Time        `json:"downloadDate"`
-	ShowNotes      string           `json:"showNotes"`
-	FilePath       string           `json:"filePath"`
-	CoverImagePath string           `json:"coverImagePath,omitempty"`
-	SourceURL      string           `json:"sourceUrl,omitempty"`
-	Metadata       *PodcastMetadata `json:"metadata,omitempty"`
+	ID              string           `json:"id"`
+	Title           string           `json:"title"`
+	PodcastName     string           `json:"podcastName"`
+	Duration        string           `json:"duration"`        // Formatted as "MM:SS" or "H:MM:SS"; empty if unknown
+	DurationSeconds int              `json:"durationSeconds"` // From the audio file, else the episode page
+	FileSize        int64            `json:"fileSize"`
+	DownloadDate    time.Time        `json:"downloadDate"`
+	PublishedAt     *time.Time       `json:"publishedAt,omitempty"` // From metadata; see PodcastMetadata.PublishPrecision
+	ShowNotes       string           `json:"showNotes"`
+	FilePath        string           `json:"filePath"`
+	CoverImagePath  string           `json:"coverImagePath,omitempty"`
+	SourceURL       string           `json:"sourceUrl,omitempty"`
+	Metadata        *PodcastMetadata `json:"metadata,omitempty"`
+	Match           *SearchMatch     `json:"match,omitempty"` // Set for search results
+}
+
+// SearchMatch describes why an episode matched a search query
+type SearchMatch struct {
+	Score   float64 `json:"score"`
+	Title   string  `json:"title"`   // HTML-escaped title with matches wrapped in <mark>
+	Snippet string  `json:"snippet"` // HTML-escaped show notes excerpt with matches wrapped in <mark>
 }
 
 // PaginatedEpisodes represents a paginated response of episodes
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LibraryRescanResult reports what a rescan of the downloads directory found
+type LibraryRescanResult struct {
+	Added     int       `json:"added"`   // Episodes that were not in the library
+	Updated   int       `json:"updated"` // Episodes whose files changed
+	Removed   int       `json:"removed"` // Episodes whose audio file is gone
+	Total     int       `json:"total"`
+	ScannedAt time.Time `json:"scannedAt"`
+}
@@ -1,15 +1,69 @@
 package models
 
-import "time"
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/meixg/podcast-reader/pkg/zhtime"
+)
 
 // PodcastMetadata represents extracted metadata for a podcast episode
 type PodcastMetadata struct {
 	Duration     string    `json:"duration"`      // Duration as displayed on page (e.g., "231分钟", "1小时15分钟")
-	PublishTime  string    `json:"publish_time"`  // Relative publish time (e.g., "刚刚发布", "2个月前")
+	PublishTime  string    `json:"publish_time"`  // Publish time as displayed on page (e.g., "刚刚发布", "2个月前")
 	EpisodeTitle string    `json:"episode_title"` // Title of the episode
 	PodcastName  string    `json:"podcast_name"`  // Name of the podcast series
 	SourceURL    string    `json:"source_url"`    // Original page URL (e.g., https://www.xiaoyuzhoufm.com/episode/...)
 	ExtractedAt  time.Time `json:"extracted_at"`  // Timestamp when metadata was extracted
+
+	// PublishedAt is the absolute publish time; PublishPrecision states how
+	// exact it is ("exact" for structured page data, else the unit of PublishTime)
+	PublishedAt      *time.Time `json:"published_at,omitempty"`
+	PublishPrecision string     `json:"publish_precision,omitempty"`
+
+	// EpisodeGUID is the ID of the episode at its source: the Xiaoyuzhou
+	// episode ID or the GUID of the feed item
+	EpisodeGUID string `json:"episode_guid,omitempty"`
+
+	// PodcastID is the ID of the podcast at its source, such as the
+	// Xiaoyuzhou podcast ID
+	PodcastID string `json:"podcast_id,omitempty"`
+
+	// CoverURLs holds the cover image at the sizes its source offers
+	CoverURLs *CoverURLs `json:"cover_urls,omitempty"`
+
+	// Chapters parsed from timestamp lines in the show notes
+	Chapters []Chapter `json:"chapters,omitempty"`
+}
+
+// CoverURLs holds the URLs of a cover image at the sizes a source offers.
+// Sizes the source doesn't offer are empty.
+type CoverURLs struct {
+	Original  string `json:"original,omitempty"`
+	Large     string `json:"large,omitempty"`
+	Medium    string `json:"medium,omitempty"`
+	Small     string `json:"small,omitempty"`
+	Thumbnail string `json:"thumbnail,omitempty"`
+}
+
+// Largest returns the URL of the largest size available, or "" if there is none
+func (c *CoverURLs) Largest() string {
+	if c == nil {
+		return ""
+	}
+	for _, url := range []string{c.Original, c.Large, c.Medium, c.Small, c.Thumbnail} {
+		if url != "" {
+			return url
+		}
+	}
+	return ""
+}
+
+// Chapter is a chapter marker of an episode
+type Chapter struct {
+	Start int    `json:"start"` // Seconds from the beginning of the episode
+	Title string `json:"title"`
 }
 
 // EpisodeWithMetadata represents a podcast episode including its metadata for API responses
@@ -25,6 +79,21 @@ func NewPodcastMetadata() *PodcastMetadata {
 	}
 }
 
+// ResolvePublishedAt sets PublishedAt from PublishTime, counting relative
+// times back from ExtractedAt. It does nothing if PublishedAt is already set
+// or PublishTime can't be parsed.
+func (m *PodcastMetadata) ResolvePublishedAt() {
+	if m == nil || m.PublishedAt != nil || m.PublishTime == "" || m.ExtractedAt.IsZero() {
+		return
+	}
+	t, precision, err := zhtime.ResolvePublishTime(m.PublishTime, m.ExtractedAt)
+	if err != nil {
+		return
+	}
+	m.PublishedAt = &t
+	m.PublishPrecision = string(precision)
+}
+
 // IsEmpty returns true if the metadata has no meaningful content
 func (m *PodcastMetadata) IsEmpty() bool {
 	if m == nil {
@@ -32,3 +101,37 @@ func (m *PodcastMetadata) IsEmpty() bool {
 	}
 	return m.Duration == "" && m.PublishTime == "" && m.EpisodeTitle == "" && m.PodcastName == ""
 }
+
+// SourceKey identifies the episode by its ID at the source, so it stays the
+// same wherever the episode is downloaded to. Xiaoyuzhou episode IDs are
+// unique on their own; feed item GUIDs only within their feed, so they are
+// qualified by the feed URL. Feed downloads made before EpisodeGUID was saved
+// carry the GUID in the "#guid=" fragment of SourceURL. It returns "" if the
+// source ID is unknown.
+func (m *PodcastMetadata) SourceKey() string {
+	if m == nil || m.SourceURL == "" {
+		return ""
+	}
+	u, err := url.Parse(m.SourceURL)
+	if err != nil {
+		return ""
+	}
+
+	host := u.Hostname()
+	if host == "xiaoyuzhoufm.com" || strings.HasSuffix(host, ".xiaoyuzhoufm.com") {
+		if id, ok := strings.CutPrefix(strings.TrimSuffix(u.Path, "/"), "/episode/"); ok && id != "" && !strings.Contains(id, "/") {
+			return "xiaoyuzhou:" + id
+		}
+	}
+
+	guid := m.EpisodeGUID
+	if guid == "" {
+		fragment, _ := url.ParseQuery(u.EscapedFragment())
+		guid = fragment.Get("guid")
+	}
+	if guid == "" {
+		return ""
+	}
+	u.Fragment, u.RawFragment = "", ""
+	return "guid:" + u.String() + "#" + guid
+}
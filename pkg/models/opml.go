@@ -0,0 +1,54 @@
+package models
+
+// OPMLImportMode selects what importing an OPML file does with each show
+type OPMLImportMode string
+
+const (
+	// OPMLImportSubscribe subscribes to each show so new episodes are downloaded
+	OPMLImportSubscribe OPMLImportMode = "subscribe"
+	// OPMLImportDownload queues a batch download of each show right away
+	OPMLImportDownload OPMLImportMode = "download"
+)
+
+// OPMLImportStatus is the outcome of importing one OPML entry
+type OPMLImportStatus string
+
+const (
+	OPMLImportSubscribed OPMLImportStatus = "subscribed"
+	OPMLImportQueued     OPMLImportStatus = "queued"  // Download task is created in the background
+	OPMLImportSkipped    OPMLImportStatus = "skipped" // Already subscribed or queued
+	OPMLImportFailed     OPMLImportStatus = "failed"
+)
+
+// OPMLImportItem reports what happened to one show or episode of an OPML file
+type OPMLImportItem struct {
+	Title          string           `json:"title,omitempty"`
+	URL            string           `json:"url"`
+	Status         OPMLImportStatus `json:"status"`
+	SubscriptionID string           `json:"subscriptionId,omitempty"`
+	Error          string           `json:"error,omitempty"`
+}
+
+// OPMLImportResult is the response of importing an OPML file
+type OPMLImportResult struct {
+	Subscribed int              `json:"subscribed"`
+	Queued     int              `json:"queued"`
+	Skipped    int              `json:"skipped"`
+	Failed     int              `json:"failed"`
+	Items      []OPMLImportItem `json:"items"`
+}
+
+// Add records the outcome of an entry and updates the counts
+func (r *OPMLImportResult) Add(item OPMLImportItem) {
+	switch item.Status {
+	case OPMLImportSubscribed:
+		r.Subscribed++
+	case OPMLImportQueued:
+		r.Queued++
+	case OPMLImportSkipped:
+		r.Skipped++
+	case OPMLImportFailed:
+		r.Failed++
+	}
+	r.Items = append(r.Items, item)
+}
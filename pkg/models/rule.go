@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// EpisodeRule decides which episodes of a show are downloaded automatically.
+// Empty fields don't restrict anything; an empty rule accepts every episode.
+type EpisodeRule struct {
+	IncludeKeywords []string `json:"includeKeywords,omitempty"` // Title must contain one of them (case-insensitive)
+	ExcludeKeywords []string `json:"excludeKeywords,omitempty"` // Title must contain none of them (case-insensitive)
+	IncludePattern  string   `json:"includePattern,omitempty"`  // Regular expression the title must match
+	ExcludePattern  string   `json:"excludePattern,omitempty"`  // Regular expression the title must not match
+	MinDuration     string   `json:"minDuration,omitempty"`     // e.g. "20分钟" or "20m"
+	MaxDuration     string   `json:"maxDuration,omitempty"`     // e.g. "2小时" or "2h"
+	MaxAge          string   `json:"maxAge,omitempty"`          // Skip episodes published longer ago, e.g. "7天"
+	KeepLatest      int      `json:"keepLatest,omitempty"`      // Only the newest N matching episodes of the show
+}
+
+// RuleDecision is the outcome of evaluating a rule against one episode
+type RuleDecision struct {
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	PublishedAt *time.Time `json:"publishedAt,omitempty"`
+	Duration    int        `json:"duration,omitempty"` // Seconds, if known
+	Selected    bool       `json:"selected"`
+	Reason      string     `json:"reason,omitempty"` // Why the episode was rejected
+}
+
+// DryRunRequest represents the request body for previewing a rule
+type DryRunRequest struct {
+	URL   string       `json:"url,omitempty"`
+	Rules *EpisodeRule `json:"rules,omitempty"`
+}
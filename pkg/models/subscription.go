@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Subscription is a show or feed that is checked periodically for new episodes
+type Subscription struct {
+	ID           string     `json:"id"`
+	URL          string     `json:"url"`
+	Title        string     `json:"title,omitempty"`
+	Source       string     `json:"source,omitempty"` // Name of the source that handles the URL
+	Enabled      bool       `json:"enabled"`
+	PollInterval int        `json:"pollInterval"` // Seconds between checks
+	CreatedAt    time.Time  `json:"createdAt"`
+	LastChecked  *time.Time `json:"lastChecked,omitempty"`
+	LastError    string     `json:"lastError,omitempty"`
+	NextCheck    *time.Time `json:"nextCheck,omitempty"`
+
+	// Rules selects which new episodes are downloaded; nil downloads all of them
+	Rules *EpisodeRule `json:"rules,omitempty"`
+
+	// Episodes queued by the subscription
+	LastNewEpisodes int `json:"lastNewEpisodes"` // Found by the last check
+	TotalEpisodes   int `json:"totalEpisodes"`   // Queued since subscribing
+}
+
+// CreateSubscriptionRequest represents the request body for creating a subscription
+type CreateSubscriptionRequest struct {
+	URL          string `json:"url"`
+	PollInterval int    `json:"pollInterval,omitempty"` // Seconds; defaults to one hour
+	// Backfill is how many of the newest existing episodes to download on the
+	// first check; later episodes are downloaded if they pass Rules
+	Backfill int          `json:"backfill,omitempty"`
+	Rules    *EpisodeRule `json:"rules,omitempty"`
+}
+
+// UpdateSubscriptionRequest represents the request body for updating a subscription.
+// Fields that are omitted are left unchanged.
+type UpdateSubscriptionRequest struct {
+	Title        *string `json:"title,omitempty"`
+	Enabled      *bool   `json:"enabled,omitempty"`
+	PollInterval *int    `json:"pollInterval,omitempty"`
+	// Rules replaces the subscription's rules; an empty rule accepts every episode
+	Rules *EpisodeRule `json:"rules,omitempty"`
+}
@@ -11,23 +11,64 @@ const (
 	TaskStatusExtractingMetadata TaskStatus = "extracting_metadata"
 	TaskStatusCompleted          TaskStatus = "completed"
 	TaskStatusFailed             TaskStatus = "failed"
+	TaskStatusPaused             TaskStatus = "paused"
+	TaskStatusCancelled          TaskStatus = "cancelled"
+)
+
+// TaskKind distinguishes episode downloads from tasks that group them
+type TaskKind string
+
+const (
+	// TaskKindBatch is a show download that fans out into one task per episode
+	TaskKindBatch TaskKind = "batch"
 )
 
 // DownloadTask represents a download operation with status tracking
 type DownloadTask struct {
 	ID           string     `json:"id"`
 	URL          string     `json:"url"`
+	Source       string     `json:"source,omitempty"` // Name of the source that handles the URL
 	Status       TaskStatus `json:"status"`
 	CreatedAt    time.Time  `json:"createdAt"`
 	CompletedAt  *time.Time `json:"completedAt,omitempty"`
 	Progress     *int       `json:"progress,omitempty"`
 	ErrorMessage string     `json:"errorMessage,omitempty"`
 	EpisodeID    string     `json:"episodeId,omitempty"`
+	FilePath     string     `json:"filePath,omitempty"` // Destination of the audio file once known
+
+	// Batch grouping; a batch task's status and progress summarize its children
+	Kind     TaskKind `json:"kind,omitempty"`     // Empty for single episode downloads
+	ParentID string   `json:"parentId,omitempty"` // Batch the episode task belongs to
+	ChildIDs []string `json:"childIds,omitempty"` // Episode tasks of a batch
+	Skipped  int      `json:"skipped,omitempty"`  // Episodes of a batch left out as already downloaded
+	Unlisted int      `json:"unlisted,omitempty"` // Older episodes of a batch's show its source can't list
+
+	// Scheduling information while the task waits for a free download slot
+	Priority      int  `json:"priority,omitempty"`
+	QueuePosition *int `json:"queuePosition,omitempty"`
+
+	// Retry bookkeeping; NextRetryAt is set while a failed attempt waits to be retried
+	Attempt     int        `json:"attempt,omitempty"`
+	MaxAttempts int        `json:"maxAttempts,omitempty"`
+	NextRetryAt *time.Time `json:"nextRetryAt,omitempty"`
+
+	// Transfer statistics while the audio file is downloading
+	BytesDownloaded int64   `json:"bytesDownloaded,omitempty"`
+	TotalBytes      int64   `json:"totalBytes,omitempty"`
+	Speed           float64 `json:"speed,omitempty"` // Bytes per second
+	ETA             *int    `json:"eta,omitempty"`   // Estimated seconds remaining
 }
 
 // CreateTaskRequest represents the request body for creating a task
 type CreateTaskRequest struct {
-	URL string `json:"url"`
+	URL      string `json:"url"`
+	Priority int    `json:"priority,omitempty"` // Higher values are downloaded first
+
+	// Episode selection when URL is a show; ignored for episode URLs
+	Latest       int    `json:"latest,omitempty"`       // Only the newest N episodes
+	Since        string `json:"since,omitempty"`        // Published on or after, YYYY-MM-DD or RFC 3339
+	Until        string `json:"until,omitempty"`        // Published on or before, YYYY-MM-DD or RFC 3339
+	SkipExisting *bool  `json:"skipExisting,omitempty"` // Skip downloaded episodes; defaults to true
 }
 
 // APIError represents a standard error response
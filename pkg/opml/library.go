@@ -0,0 +1,101 @@
+package opml
+
+import (
+	"sort"
+	"time"
+
+	"github.com/meixg/podcast-reader/pkg/downloader"
+	"github.com/meixg/podcast-reader/pkg/models"
+)
+
+// LibraryTitle is the title of exported libraries.
+const LibraryTitle = "Podcast Reader"
+
+// Library builds a document of the shows in a library: one outline per
+// podcast name, with the source URL of each downloaded episode as a child.
+// A show gets an xmlUrl when its feed is known, from episodes downloaded from
+// a feed or from subscriptions, which are matched by title. Subscriptions
+// without downloaded episodes are listed too.
+func Library(episodes []models.DownloadedEpisode, subscriptions []*models.Subscription) *Document {
+	type show struct {
+		outline Outline
+		latest  time.Time
+	}
+	shows := make(map[string]*show)
+	var order []string
+	for _, episode := range episodes {
+		name := episode.PodcastName
+		s, exists := shows[name]
+		if !exists {
+			s = &show{outline: Outline{Text: name, Title: name}}
+			shows[name] = s
+			order = append(order, name)
+		}
+
+		date := episode.DownloadDate
+		if episode.PublishedAt != nil {
+			date = *episode.PublishedAt
+		}
+		if date.After(s.latest) {
+			s.latest = date
+		}
+		if episode.SourceURL == "" {
+			continue
+		}
+		if feedURL, _, ok := downloader.ParseFeedEpisodeURL(episode.SourceURL); ok && s.outline.XMLURL == "" {
+			s.outline.Type = "rss"
+			s.outline.XMLURL = feedURL
+		}
+		s.outline.Outlines = append(s.outline.Outlines, Outline{
+			Text: episode.Title,
+			Type: "link",
+			URL:  episode.SourceURL,
+		})
+	}
+
+	for _, subscription := range subscriptions {
+		name := subscription.Title
+		if name == "" {
+			name = subscription.URL
+		}
+		s, exists := shows[name]
+		if !exists {
+			s = &show{outline: Outline{Text: name, Title: name}}
+			shows[name] = s
+			order = append(order, name)
+		}
+		setShowURL(&s.outline, subscription)
+	}
+
+	// Most recently updated shows first, like the episode list
+	sort.SliceStable(order, func(i, j int) bool {
+		return shows[order[i]].latest.After(shows[order[j]].latest)
+	})
+	doc := &Document{
+		Title:       LibraryTitle,
+		DateCreated: time.Now(),
+	}
+	for _, name := range order {
+		doc.Outlines = append(doc.Outlines, shows[name].outline)
+	}
+	return doc
+}
+
+// feedSource tells feed URLs apart for subscriptions that were never checked.
+// It only matches URLs, so it needs no HTTP client.
+var feedSource = downloader.NewFeedSource(nil)
+
+// setShowURL records the URL of a subscribed show on its outline: feeds as
+// the xmlUrl apps subscribe to, show pages as the htmlUrl.
+func setShowURL(outline *Outline, subscription *models.Subscription) {
+	isFeed := subscription.Source == feedSource.Name() ||
+		subscription.Source == "" && feedSource.IsShow(subscription.URL)
+	if isFeed {
+		outline.Type = "rss"
+		outline.XMLURL = subscription.URL
+		return
+	}
+	if outline.HTMLURL == "" {
+		outline.HTMLURL = subscription.URL
+	}
+}
@@ -0,0 +1,124 @@
+// Package opml reads and writes OPML 2.0 subscription lists, the format
+// podcast apps use to import and export the shows they follow.
+package opml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrInvalidOPML is returned for documents that aren't OPML.
+var ErrInvalidOPML = errors.New("无效的OPML文件")
+
+// ContentType is the media type OPML documents are served with.
+const ContentType = "text/x-opml; charset=utf-8"
+
+// maxSize limits how much of an OPML document is read.
+const maxSize = 8 * 1024 * 1024
+
+// Document is an OPML document.
+type Document struct {
+	Title       string
+	DateCreated time.Time
+	Outlines    []Outline
+}
+
+// Outline is an entry of an OPML document. Podcast apps use type "rss" with
+// XMLURL for shows; type "link" with URL is used for single episodes.
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	URL      string    `xml:"url,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// Name returns the title of the outline, falling back to its text.
+func (o Outline) Name() string {
+	if o.Title != "" {
+		return o.Title
+	}
+	return o.Text
+}
+
+// Parse reads an OPML document.
+func Parse(r io.Reader) (*Document, error) {
+	var doc xmlOPML
+	decoder := xml.NewDecoder(io.LimitReader(r, maxSize))
+	decoder.Strict = false
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		// Apps write UTF-8; let the others through and hope for the best
+		return input, nil
+	}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOPML, err)
+	}
+
+	document := &Document{
+		Title:    doc.Head.Title,
+		Outlines: doc.Body.Outlines,
+	}
+	if t, err := time.Parse(time.RFC1123Z, doc.Head.DateCreated); err == nil {
+		document.DateCreated = t
+	}
+	return document, nil
+}
+
+// Write writes the document as OPML 2.0.
+func (d *Document) Write(w io.Writer) error {
+	doc := xmlOPML{
+		Version: "2.0",
+		Head:    xmlHead{Title: d.Title},
+		Body:    xmlBody{Outlines: d.Outlines},
+	}
+	if !d.DateCreated.IsZero() {
+		doc.Head.DateCreated = d.DateCreated.Format(time.RFC1123Z)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("encode OPML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// All returns every outline of the document, parents before their children.
+func (d *Document) All() []Outline {
+	var all []Outline
+	var walk func(outlines []Outline)
+	walk = func(outlines []Outline) {
+		for _, outline := range outlines {
+			all = append(all, outline)
+			walk(outline.Outlines)
+		}
+	}
+	walk(d.Outlines)
+	return all
+}
+
+// XML structure of the document.
+type xmlOPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    xmlHead  `xml:"head"`
+	Body    xmlBody  `xml:"body"`
+}
+
+type xmlHead struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type xmlBody struct {
+	Outlines []Outline `xml:"outline"`
+}
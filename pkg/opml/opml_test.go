@@ -0,0 +1,81 @@
+package opml
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meixg/podcast-reader/pkg/downloader"
+	"github.com/meixg/podcast-reader/pkg/models"
+)
+
+func TestParse(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="1.0">
+  <head><title>Pocket Casts Feeds</title></head>
+  <body>
+    <outline text="feeds">
+      <outline type="rss" text="节目A" xmlUrl="https://example.com/a/feed.xml" />
+      <outline type="rss" text="Show &amp; Tell" title="Show and Tell" xmlUrl="https://example.com/b.rss" />
+    </outline>
+  </body>
+</opml>`
+	doc, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	var feeds []string
+	for _, outline := range doc.All() {
+		if outline.XMLURL != "" {
+			feeds = append(feeds, outline.Name()+" "+outline.XMLURL)
+		}
+	}
+	want := []string{"节目A https://example.com/a/feed.xml", "Show and Tell https://example.com/b.rss"}
+	if strings.Join(feeds, "|") != strings.Join(want, "|") {
+		t.Errorf("feeds = %q, want %q", feeds, want)
+	}
+
+	if _, err := Parse(strings.NewReader(`<rss version="2.0"></rss>`)); !errors.Is(err, ErrInvalidOPML) {
+		t.Errorf("Parse(rss) error = %v, want ErrInvalidOPML", err)
+	}
+}
+
+func TestLibrary(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC) }
+	feedURL := "https://example.com/a/feed.xml"
+	episodes := []models.DownloadedEpisode{
+		{Title: "A1", PodcastName: "节目A", DownloadDate: day(1), SourceURL: downloader.FeedEpisodeURL(feedURL, "a1")},
+		{Title: "B1", PodcastName: "节目B", DownloadDate: day(3), SourceURL: "https://www.xiaoyuzhoufm.com/episode/b1"},
+		{Title: "A2", PodcastName: "节目A", DownloadDate: day(2), SourceURL: downloader.FeedEpisodeURL(feedURL, "a2")},
+	}
+	subscriptions := []*models.Subscription{
+		{Title: "节目B", URL: "https://www.xiaoyuzhoufm.com/podcast/b", Source: "xiaoyuzhou"},
+		{URL: "https://example.com/c.rss"},
+	}
+
+	doc := Library(episodes, subscriptions)
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	doc, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(doc.Outlines) != 3 {
+		t.Fatalf("outlines = %+v, want 3 shows", doc.Outlines)
+	}
+	b, a, c := doc.Outlines[0], doc.Outlines[1], doc.Outlines[2]
+	if b.Text != "节目B" || b.HTMLURL != "https://www.xiaoyuzhoufm.com/podcast/b" || b.XMLURL != "" || len(b.Outlines) != 1 {
+		t.Errorf("show B = %+v", b)
+	}
+	if a.Text != "节目A" || a.XMLURL != feedURL || len(a.Outlines) != 2 || a.Outlines[1].URL != episodes[2].SourceURL {
+		t.Errorf("show A = %+v", a)
+	}
+	if c.XMLURL != "https://example.com/c.rss" || len(c.Outlines) != 0 {
+		t.Errorf("subscription without episodes = %+v", c)
+	}
+}
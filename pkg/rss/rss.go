@@ -0,0 +1,153 @@
+// Package rss writes RSS 2.0 podcast feeds with the iTunes tags podcast apps
+// rely on.
+package rss
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ContentType is the media type feeds are served with.
+const ContentType = "application/rss+xml; charset=utf-8"
+
+// itunesNS is the namespace of the itunes: tags.
+const itunesNS = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+
+// Channel is a podcast feed.
+type Channel struct {
+	Title       string
+	Link        string // Website of the podcast
+	Description string
+	Language    string // e.g. "zh-cn"
+	Author      string
+	ImageURL    string
+	Items       []Item // Usually newest first
+}
+
+// Item is an episode of a feed.
+type Item struct {
+	Title       string
+	Link        string // Web page of the episode
+	Description string
+	GUID        string // Must not change once published, or apps list the episode twice
+	PubDate     time.Time
+	Duration    time.Duration
+	ImageURL    string
+	Enclosure   Enclosure
+}
+
+// Enclosure is the audio file of an episode.
+type Enclosure struct {
+	URL    string
+	Length int64
+	Type   string
+}
+
+// Write writes the channel as an RSS 2.0 document.
+func (c *Channel) Write(w io.Writer) error {
+	doc := xmlRSS{
+		Version: "2.0",
+		Itunes:  itunesNS,
+		Channel: xmlChannel{
+			Title:       c.Title,
+			Link:        c.Link,
+			Description: c.Description,
+			Language:    c.Language,
+			Author:      c.Author,
+			Image:       image(c.ImageURL),
+		},
+	}
+	for _, item := range c.Items {
+		x := xmlItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			GUID:        xmlGUID{IsPermaLink: "false", Value: item.GUID},
+			Image:       image(item.ImageURL),
+			Enclosure: xmlEnclosure{
+				URL:    item.Enclosure.URL,
+				Length: item.Enclosure.Length,
+				Type:   item.Enclosure.Type,
+			},
+		}
+		if !item.PubDate.IsZero() {
+			x.PubDate = item.PubDate.Format(time.RFC1123Z)
+		}
+		if item.Duration > 0 {
+			x.Duration = formatDuration(item.Duration)
+		}
+		doc.Channel.Items = append(doc.Channel.Items, x)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("encode feed: %w", err)
+	}
+	return nil
+}
+
+// image returns the itunes:image tag for url, or nil if url is empty.
+func image(url string) *xmlImage {
+	if url == "" {
+		return nil
+	}
+	return &xmlImage{Href: url}
+}
+
+// formatDuration formats d as "H:MM:SS", the itunes:duration form every app
+// understands.
+func formatDuration(d time.Duration) string {
+	seconds := int(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// XML structure of the feed. The itunes: prefix is written literally and
+// declared on the root element.
+type xmlRSS struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Itunes  string     `xml:"xmlns:itunes,attr"`
+	Channel xmlChannel `xml:"channel"`
+}
+
+type xmlChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Language    string    `xml:"language,omitempty"`
+	Author      string    `xml:"itunes:author,omitempty"`
+	Image       *xmlImage `xml:"itunes:image"`
+	Items       []xmlItem `xml:"item"`
+}
+
+type xmlItem struct {
+	Title       string       `xml:"title"`
+	Link        string       `xml:"link,omitempty"`
+	Description string       `xml:"description,omitempty"`
+	GUID        xmlGUID      `xml:"guid"`
+	PubDate     string       `xml:"pubDate,omitempty"`
+	Duration    string       `xml:"itunes:duration,omitempty"`
+	Image       *xmlImage    `xml:"itunes:image"`
+	Enclosure   xmlEnclosure `xml:"enclosure"`
+}
+
+type xmlGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type xmlImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type xmlEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
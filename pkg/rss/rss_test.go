@@ -0,0 +1,61 @@
+package rss
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/meixg/podcast-reader/pkg/downloader"
+)
+
+func TestChannelWrite(t *testing.T) {
+	published := time.Date(2024, 4, 10, 8, 0, 0, 0, time.UTC)
+	channel := &Channel{
+		Title:    "罗永浩的十字路口",
+		Link:     "http://localhost:8080/",
+		Language: "zh-cn",
+		ImageURL: "http://localhost:8080/api/episodes/abc/cover",
+		Items: []Item{{
+			Title:       "第1期 <开场> & 介绍",
+			Description: "时间轴\n00:00 开场",
+			GUID:        "https://www.xiaoyuzhoufm.com/episode/123",
+			PubDate:     published,
+			Duration:    75*time.Minute + 3*time.Second,
+			Enclosure: Enclosure{
+				URL:    "http://localhost:8080/api/episodes/abc/audio",
+				Length: 1234,
+				Type:   "audio/mp4",
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := channel.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	for _, want := range []string{
+		`xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"`,
+		`<guid isPermaLink="false">https://www.xiaoyuzhoufm.com/episode/123</guid>`,
+		`<itunes:duration>1:15:03</itunes:duration>`,
+		`<pubDate>Wed, 10 Apr 2024 08:00:00 +0000</pubDate>`,
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("feed is missing %s:\n%s", want, buf.String())
+		}
+	}
+
+	// The feed reads back with the parser used for subscriptions
+	feed, err := downloader.ParseFeed(&buf)
+	if err != nil {
+		t.Fatalf("ParseFeed() error = %v", err)
+	}
+	if feed.Title != channel.Title || feed.ImageURL != channel.ImageURL || len(feed.Episodes) != 1 {
+		t.Fatalf("ParseFeed() = %+v", feed)
+	}
+	episode := feed.Episodes[0]
+	if episode.Title != "第1期 <开场> & 介绍" || episode.GUID != channel.Items[0].GUID ||
+		episode.AudioURL != channel.Items[0].Enclosure.URL || episode.Duration != channel.Items[0].Duration ||
+		!episode.PublicationDate.Equal(published) {
+		t.Errorf("episode = %+v", episode)
+	}
+}
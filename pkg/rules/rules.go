@@ -0,0 +1,174 @@
+// Package rules decides which episodes of a show are downloaded automatically.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/meixg/podcast-reader/pkg/downloader"
+	"github.com/meixg/podcast-reader/pkg/models"
+	"github.com/meixg/podcast-reader/pkg/zhtime"
+)
+
+// ErrInvalidRule is returned by Compile for rules with malformed fields.
+var ErrInvalidRule = errors.New("invalid rule")
+
+// Matcher is a compiled models.EpisodeRule.
+type Matcher struct {
+	include     []string
+	exclude     []string
+	includeRE   *regexp.Regexp
+	excludeRE   *regexp.Regexp
+	minDuration time.Duration
+	maxDuration time.Duration
+	maxAge      time.Duration
+	keepLatest  int
+}
+
+// Compile validates a rule and prepares it for evaluation.
+// A nil rule compiles to a matcher that accepts every episode.
+func Compile(rule *models.EpisodeRule) (*Matcher, error) {
+	m := &Matcher{}
+	if rule == nil {
+		return m, nil
+	}
+
+	m.include = normalizeKeywords(rule.IncludeKeywords)
+	m.exclude = normalizeKeywords(rule.ExcludeKeywords)
+
+	var err error
+	if rule.IncludePattern != "" {
+		if m.includeRE, err = regexp.Compile(rule.IncludePattern); err != nil {
+			return nil, fmt.Errorf("%w: includePattern: %v", ErrInvalidRule, err)
+		}
+	}
+	if rule.ExcludePattern != "" {
+		if m.excludeRE, err = regexp.Compile(rule.ExcludePattern); err != nil {
+			return nil, fmt.Errorf("%w: excludePattern: %v", ErrInvalidRule, err)
+		}
+	}
+	if m.minDuration, err = parseDuration("minDuration", rule.MinDuration); err != nil {
+		return nil, err
+	}
+	if m.maxDuration, err = parseDuration("maxDuration", rule.MaxDuration); err != nil {
+		return nil, err
+	}
+	if m.maxDuration > 0 && m.minDuration > m.maxDuration {
+		return nil, fmt.Errorf("%w: minDuration is longer than maxDuration", ErrInvalidRule)
+	}
+	if m.maxAge, err = parseDuration("maxAge", rule.MaxAge); err != nil {
+		return nil, err
+	}
+	if rule.KeepLatest < 0 {
+		return nil, fmt.Errorf("%w: keepLatest must not be negative", ErrInvalidRule)
+	}
+	m.keepLatest = rule.KeepLatest
+	return m, nil
+}
+
+// Evaluate decides for each episode of a show whether it should be
+// downloaded. Decisions are returned newest first. Checks that need a
+// duration or publication date pass when the episode doesn't have one.
+func (m *Matcher) Evaluate(episodes []*downloader.EpisodeMetadata, now time.Time) []models.RuleDecision {
+	sorted, _ := downloader.EpisodeFilter{}.Apply(episodes)
+
+	decisions := make([]models.RuleDecision, 0, len(sorted))
+	kept := 0
+	for _, episode := range sorted {
+		decision := models.RuleDecision{
+			Title: episode.Title,
+			URL:   episode.PageURL,
+		}
+		if !episode.PublicationDate.IsZero() {
+			published := episode.PublicationDate
+			decision.PublishedAt = &published
+		}
+		if episode.Duration > 0 {
+			decision.Duration = int(episode.Duration.Seconds())
+		}
+
+		decision.Reason = m.reject(episode, now)
+		if decision.Reason == "" && m.keepLatest > 0 && kept >= m.keepLatest {
+			decision.Reason = fmt.Sprintf("not among the latest %d episodes", m.keepLatest)
+		}
+		if decision.Reason == "" {
+			decision.Selected = true
+			kept++
+		}
+		decisions = append(decisions, decision)
+	}
+	return decisions
+}
+
+// reject returns why a single episode fails the rule, or "" if it passes.
+func (m *Matcher) reject(episode *downloader.EpisodeMetadata, now time.Time) string {
+	title := strings.ToLower(episode.Title)
+	if len(m.include) > 0 && !containsAny(title, m.include) {
+		return "title contains none of the include keywords"
+	}
+	if keyword := firstContained(title, m.exclude); keyword != "" {
+		return fmt.Sprintf("title contains excluded keyword %q", keyword)
+	}
+	if m.includeRE != nil && !m.includeRE.MatchString(episode.Title) {
+		return "title does not match the include pattern"
+	}
+	if m.excludeRE != nil && m.excludeRE.MatchString(episode.Title) {
+		return "title matches the exclude pattern"
+	}
+
+	if episode.Duration > 0 {
+		if m.minDuration > 0 && episode.Duration < m.minDuration {
+			return fmt.Sprintf("shorter than %v", m.minDuration)
+		}
+		if m.maxDuration > 0 && episode.Duration > m.maxDuration {
+			return fmt.Sprintf("longer than %v", m.maxDuration)
+		}
+	}
+	if m.maxAge > 0 && !episode.PublicationDate.IsZero() && now.Sub(episode.PublicationDate) > m.maxAge {
+		return fmt.Sprintf("published more than %v ago", m.maxAge)
+	}
+	return ""
+}
+
+// parseDuration parses an optional rule duration with zhtime.
+func parseDuration(field, value string) (time.Duration, error) {
+	if strings.TrimSpace(value) == "" {
+		return 0, nil
+	}
+	d, err := zhtime.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s: %v", ErrInvalidRule, field, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%w: %s must not be negative", ErrInvalidRule, field)
+	}
+	return d, nil
+}
+
+// normalizeKeywords lower-cases keywords and drops blank ones.
+func normalizeKeywords(keywords []string) []string {
+	var result []string
+	for _, keyword := range keywords {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+			result = append(result, keyword)
+		}
+	}
+	return result
+}
+
+func containsAny(s string, keywords []string) bool {
+	return firstContained(s, keywords) != ""
+}
+
+// firstContained returns the first keyword contained in s.
+func firstContained(s string, keywords []string) string {
+	for _, keyword := range keywords {
+		if strings.Contains(s, keyword) {
+			return keyword
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package rules
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/meixg/podcast-reader/pkg/downloader"
+	"github.com/meixg/podcast-reader/pkg/models"
+)
+
+func TestMatcher_Evaluate(t *testing.T) {
+	now := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(d int) time.Time { return now.AddDate(0, 0, -d) }
+	episodes := []*downloader.EpisodeMetadata{
+		{Title: "Vol.40 访谈：城市与建筑", PageURL: "e40", Duration: 80 * time.Minute, PublicationDate: daysAgo(1)},
+		{Title: "Vol.39 周末闲聊", PageURL: "e39", Duration: 50 * time.Minute, PublicationDate: daysAgo(8)},
+		{Title: "预告：下期节目", PageURL: "trailer", Duration: 2 * time.Minute, PublicationDate: daysAgo(2)},
+		{Title: "Vol.38 访谈：电影", PageURL: "e38", Duration: 65 * time.Minute, PublicationDate: daysAgo(15)},
+		{Title: "Vol.37 访谈：音乐", PageURL: "e37", PublicationDate: daysAgo(60)},
+	}
+
+	tests := []struct {
+		name string
+		rule *models.EpisodeRule
+		want []string
+	}{
+		{"nil rule", nil, []string{"e40", "trailer", "e39", "e38", "e37"}},
+		{"include keyword", &models.EpisodeRule{IncludeKeywords: []string{"访谈"}}, []string{"e40", "e38", "e37"}},
+		{"exclude keyword", &models.EpisodeRule{ExcludeKeywords: []string{"预告", "闲聊"}}, []string{"e40", "e38", "e37"}},
+		{"patterns", &models.EpisodeRule{IncludePattern: `^Vol\.\d+`, ExcludePattern: `电影$`}, []string{"e40", "e39", "e37"}},
+		{"min duration", &models.EpisodeRule{MinDuration: "10分钟"}, []string{"e40", "e39", "e38", "e37"}},
+		{"max duration", &models.EpisodeRule{MaxDuration: "1小时"}, []string{"trailer", "e39", "e37"}},
+		{"max age", &models.EpisodeRule{MaxAge: "10天"}, []string{"e40", "trailer", "e39"}},
+		{"keep latest", &models.EpisodeRule{ExcludeKeywords: []string{"预告"}, KeepLatest: 2}, []string{"e40", "e39"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Compile(tt.rule)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			var got []string
+			for _, decision := range m.Evaluate(episodes, now) {
+				if decision.Selected {
+					got = append(got, decision.URL)
+				} else if decision.Reason == "" {
+					t.Errorf("%s rejected without a reason", decision.URL)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("selected %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("selected %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCompile_InvalidRules(t *testing.T) {
+	invalid := []*models.EpisodeRule{
+		{IncludePattern: "("},
+		{MinDuration: "很久"},
+		{MinDuration: "2小时", MaxDuration: "1小时"},
+		{MaxAge: "forever"},
+		{KeepLatest: -1},
+	}
+	for _, rule := range invalid {
+		if _, err := Compile(rule); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("Compile(%+v) error = %v, want ErrInvalidRule", rule, err)
+		}
+	}
+}
@@ -41,6 +41,9 @@ func (s *MetadataScanner) ReadMetadata(dir string) (*models.PodcastMetadata, err
 		return nil, fmt.Errorf("failed to parse metadata file: %w", err)
 	}
 
+	// Files written before publish times were resolved only have the relative text
+	metadata.ResolvePublishedAt()
+
 	return &metadata, nil
 }
 
@@ -7,8 +7,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/meixg/podcast-reader/pkg/mediainfo"
 	"github.com/meixg/podcast-reader/pkg/models"
+	"github.com/meixg/podcast-reader/pkg/zhtime"
 )
 
 // Scanner scans the downloads directory for podcast episodes
@@ -29,6 +32,37 @@ func NewScanner(downloadsDir string) *Scanner {
 func (s *Scanner) ScanEpisodes() ([]models.DownloadedEpisode, error) {
 	var episodes []models.DownloadedEpisode
 
+	err := s.walkAudioFiles(func(path string, info os.FileInfo) {
+		episode, err := s.parseEpisode(path, info)
+		if err != nil {
+			// Log error but continue scanning
+			fmt.Printf("Error parsing episode %s: %v\n", path, err)
+			return
+		}
+		episodes = append(episodes, episode)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return episodes, nil
+}
+
+// AudioFiles lists the audio files in the downloads directory without
+// parsing them, so callers can tell which episodes changed
+func (s *Scanner) AudioFiles() (map[string]os.FileInfo, error) {
+	files := make(map[string]os.FileInfo)
+	err := s.walkAudioFiles(func(path string, info os.FileInfo) {
+		files[path] = info
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// walkAudioFiles calls fn for every audio file in the downloads directory
+func (s *Scanner) walkAudioFiles(fn func(path string, info os.FileInfo)) error {
 	err := filepath.Walk(s.downloadsDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -45,29 +79,42 @@ func (s *Scanner) ScanEpisodes() ([]models.DownloadedEpisode, error) {
 			return nil
 		}
 
-		episode, err := s.parseEpisode(path, info)
-		if err != nil {
-			// Log error but continue scanning
-			fmt.Printf("Error parsing episode %s: %v\n", path, err)
-			return nil
-		}
-
-		episodes = append(episodes, episode)
+		fn(path, info)
 		return nil
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to scan directory: %w", err)
+		return fmt.Errorf("failed to scan directory: %w", err)
 	}
+	return nil
+}
 
-	return episodes, nil
+// ScanEpisode reads the episode of a single audio file
+func (s *Scanner) ScanEpisode(audioPath string) (models.DownloadedEpisode, error) {
+	info, err := os.Stat(audioPath)
+	if err != nil {
+		return models.DownloadedEpisode{}, err
+	}
+	return s.parseEpisode(audioPath, info)
+}
+
+// Fingerprint summarizes the size and modification time of the audio file
+// and of the files next to it that an episode is read from. It changes
+// whenever the episode needs to be parsed again.
+func (s *Scanner) Fingerprint(audioPath string, info os.FileInfo) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%x-%x", info.Size(), info.ModTime().UnixNano())
+	dir := filepath.Dir(audioPath)
+	for _, name := range episodeFiles {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			fmt.Fprintf(&b, ";%s-%x-%x", name, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return b.String()
 }
 
 // parseEpisode extracts episode metadata from a file
 func (s *Scanner) parseEpisode(audioPath string, info os.FileInfo) (models.DownloadedEpisode, error) {
-	// Generate ID from file path
-	id := s.generateID(audioPath)
-
 	// Get podcast name from parent directory
 	podcastName := filepath.Base(filepath.Dir(audioPath))
 
@@ -85,10 +132,9 @@ func (s *Scanner) parseEpisode(audioPath string, info os.FileInfo) (models.Downl
 	metadata, _ := s.metadataScanner.ReadMetadata(dir)
 
 	episode := models.DownloadedEpisode{
-		ID:             id,
+		ID:             s.generateID(audioPath, metadata),
 		Title:          title,
 		PodcastName:    podcastName,
-		Duration:       "00:00", // TODO: Extract from audio metadata
 		FileSize:       info.Size(),
 		DownloadDate:   info.ModTime(),
 		ShowNotes:      showNotes,
@@ -109,19 +155,63 @@ func (s *Scanner) parseEpisode(audioPath string, info os.FileInfo) (models.Downl
 	if metadata != nil && metadata.SourceURL != "" {
 		episode.SourceURL = metadata.SourceURL
 	}
+	if metadata != nil {
+		episode.PublishedAt = metadata.PublishedAt
+	}
+
+	if duration := s.episodeDuration(audioPath, metadata); duration > 0 {
+		episode.DurationSeconds = int(duration.Round(time.Second) / time.Second)
+		episode.Duration = mediainfo.FormatDuration(duration)
+	}
 
 	return episode, nil
 }
 
-// generateID creates a unique ID from the file path
-func (s *Scanner) generateID(path string) string {
-	hash := md5.Sum([]byte(path))
+// episodeDuration reads the exact duration from the audio file, falling back
+// to the duration shown on the episode page
+func (s *Scanner) episodeDuration(audioPath string, metadata *models.PodcastMetadata) time.Duration {
+	if d, err := mediainfo.Duration(audioPath); err == nil {
+		return d
+	}
+	if metadata != nil && metadata.Duration != "" {
+		if d, err := zhtime.ParseDuration(metadata.Duration); err == nil {
+			return d
+		}
+	}
+	return 0
+}
+
+// generateID creates a unique ID for an episode from its ID at the source,
+// so it survives moving or renaming the downloads directory. Episodes
+// without a known source ID fall back to PathID.
+func (s *Scanner) generateID(path string, metadata *models.PodcastMetadata) string {
+	if key := metadata.SourceKey(); key != "" {
+		return hashID(key)
+	}
+	return PathID(path)
+}
+
+// PathID returns the ID of an episode derived from the path of its audio
+// file. It is used for episodes without a known source ID, and was the ID
+// of every episode before IDs were derived from the source.
+func PathID(path string) string {
+	return hashID(path)
+}
+
+// hashID returns the MD5 of s in hex, the format of episode IDs
+func hashID(s string) string {
+	hash := md5.Sum([]byte(s))
 	return hex.EncodeToString(hash[:])
 }
 
+// coverNames are the cover images looked for, in order of preference
+var coverNames = []string{"cover.jpg", "cover.png", "cover.webp", "cover.gif"}
+
+// episodeFiles are the files next to the audio an episode is read from
+var episodeFiles = append([]string{metadataFileName, "shownotes.txt"}, coverNames...)
+
 // findCoverImage looks for a cover image in the directory
 func (s *Scanner) findCoverImage(dir string) string {
-	coverNames := []string{"cover.jpg", "cover.png", "cover.webp"}
 	for _, name := range coverNames {
 		path := filepath.Join(dir, name)
 		if _, err := os.Stat(path); err == nil {
@@ -0,0 +1,108 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// Snippet sizes, in runes.
+const (
+	snippetBefore = 30
+	snippetLength = 120
+)
+
+// Fragment is a piece of text with the parts that matched a query.
+type Fragment struct {
+	Text    string
+	Matches [][2]int // Byte ranges of Text, sorted and not overlapping
+}
+
+// HTML returns the fragment escaped for HTML with matches wrapped in <mark>.
+func (f Fragment) HTML() string {
+	return f.format("<mark>", "</mark>", html.EscapeString)
+}
+
+// Format returns the fragment with matches wrapped in open and close, e.g.
+// terminal color codes.
+func (f Fragment) Format(open, close string) string {
+	return f.format(open, close, func(s string) string { return s })
+}
+
+func (f Fragment) format(open, close string, escape func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range f.Matches {
+		b.WriteString(escape(f.Text[last:m[0]]))
+		b.WriteString(open)
+		b.WriteString(escape(f.Text[m[0]:m[1]]))
+		b.WriteString(close)
+		last = m[1]
+	}
+	b.WriteString(escape(f.Text[last:]))
+	return b.String()
+}
+
+// highlight marks the tokens of text that are query terms.
+func highlight(text string, terms map[string]bool) Fragment {
+	var matches [][2]int
+	for _, token := range indexTokens(text) {
+		if !terms[token.Text] {
+			continue
+		}
+		// Bigrams overlap; merge them into one range
+		if n := len(matches); n > 0 && token.Start <= matches[n-1][1] {
+			matches[n-1][1] = max(matches[n-1][1], token.End)
+			continue
+		}
+		matches = append(matches, [2]int{token.Start, token.End})
+	}
+	return Fragment{Text: text, Matches: matches}
+}
+
+// snippet returns an excerpt of text around its first match, or its
+// beginning if nothing matches. Cut-off ends are marked with "…".
+func snippet(text string, terms map[string]bool) Fragment {
+	text = strings.Join(strings.Fields(text), " ")
+	full := highlight(text, terms)
+
+	start := 0
+	if len(full.Matches) > 0 {
+		start = retreatRunes(text, full.Matches[0][0], snippetBefore)
+	}
+	end := advanceRunes(text, start, snippetLength)
+
+	prefix, suffix := "", ""
+	if start > 0 {
+		prefix = "…"
+	}
+	if end < len(text) {
+		suffix = "…"
+	}
+	fragment := Fragment{Text: prefix + text[start:end] + suffix}
+	shift := len(prefix) - start
+	for _, m := range full.Matches {
+		if m[0] >= start && m[1] <= end {
+			fragment.Matches = append(fragment.Matches, [2]int{m[0] + shift, m[1] + shift})
+		}
+	}
+	return fragment
+}
+
+// advanceRunes returns the byte offset n runes after start, or len(s).
+func advanceRunes(s string, start, n int) int {
+	for i := 0; i < n && start < len(s); i++ {
+		_, size := utf8.DecodeRuneInString(s[start:])
+		start += size
+	}
+	return start
+}
+
+// retreatRunes returns the byte offset n runes before end, or 0.
+func retreatRunes(s string, end, n int) int {
+	for i := 0; i < n && end > 0; i++ {
+		_, size := utf8.DecodeLastRuneInString(s[:end])
+		end -= size
+	}
+	return end
+}
@@ -0,0 +1,168 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// Field weights: a match in the title counts more than one in the show notes.
+const (
+	titleWeight   = 3
+	podcastWeight = 2
+	notesWeight   = 1
+)
+
+// Document is an episode as indexed.
+type Document struct {
+	ID          string
+	Title       string
+	PodcastName string
+	ShowNotes   string
+}
+
+// Result is a document matching a query.
+type Result struct {
+	ID      string
+	Score   float64
+	Title   Fragment // The title with the matching terms marked
+	Snippet Fragment // The show notes around the first match
+}
+
+// Index is an inverted index of documents. It is safe for concurrent use.
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[string]*Document
+	postings map[string]map[string]int // Term -> document ID -> weighted count
+}
+
+// NewIndex creates an empty index.
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[string]*Document),
+		postings: make(map[string]map[string]int),
+	}
+}
+
+// Len returns the number of indexed documents.
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// Add indexes a document, replacing any document with the same ID.
+func (idx *Index) Add(doc Document) {
+	counts := make(map[string]int)
+	for _, field := range []struct {
+		text   string
+		weight int
+	}{
+		{doc.Title, titleWeight},
+		{doc.PodcastName, podcastWeight},
+		{doc.ShowNotes, notesWeight},
+	} {
+		for _, token := range indexTokens(field.text) {
+			counts[token.Text] += field.weight
+		}
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(doc.ID)
+	idx.docs[doc.ID] = &doc
+	for term, count := range counts {
+		posting, exists := idx.postings[term]
+		if !exists {
+			posting = make(map[string]int)
+			idx.postings[term] = posting
+		}
+		posting[doc.ID] = count
+	}
+}
+
+// Remove drops a document from the index.
+func (idx *Index) Remove(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(id)
+}
+
+// remove drops a document. Callers must hold idx.mu.
+func (idx *Index) remove(id string) {
+	doc, exists := idx.docs[id]
+	if !exists {
+		return
+	}
+	delete(idx.docs, id)
+	for _, text := range []string{doc.Title, doc.PodcastName, doc.ShowNotes} {
+		for _, token := range indexTokens(text) {
+			if posting, ok := idx.postings[token.Text]; ok {
+				delete(posting, id)
+				if len(posting) == 0 {
+					delete(idx.postings, token.Text)
+				}
+			}
+		}
+	}
+}
+
+// Search returns the documents containing every term of the query, best
+// matches first. Terms are weighted by rarity, and a match in the title or
+// podcast name counts more than one in the show notes.
+func (idx *Index) Search(query string) []Result {
+	terms := make(map[string]bool)
+	for _, token := range Tokenize(query) {
+		terms[token.Text] = true
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	scores := make(map[string]float64)
+	first := true
+	for term := range terms {
+		posting := idx.postings[term]
+		if len(posting) == 0 {
+			return nil
+		}
+		idf := math.Log(1 + float64(len(idx.docs))/float64(len(posting)))
+		for id, count := range posting {
+			if _, candidate := scores[id]; !first && !candidate {
+				continue
+			}
+			// Saturate repeated terms so long show notes don't dominate
+			scores[id] += idf * float64(count) / (float64(count) + 1.2)
+		}
+		if !first {
+			// Keep only the documents that also contain this term
+			for id := range scores {
+				if _, ok := posting[id]; !ok {
+					delete(scores, id)
+				}
+			}
+		}
+		first = false
+	}
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		doc := idx.docs[id]
+		results = append(results, Result{
+			ID:      id,
+			Score:   score,
+			Title:   highlight(doc.Title, terms),
+			Snippet: snippet(doc.ShowNotes, terms),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	return results
+}
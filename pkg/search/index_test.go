@@ -0,0 +1,82 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	var got []string
+	for _, token := range Tokenize("第1期：ＡＩ与Podcast 创业，你") {
+		got = append(got, token.Text)
+	}
+	want := []string{"第", "1", "期", "ai", "与", "podcast", "创业", "你"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Tokenize() = %q, want %q", got, want)
+	}
+
+	tokens := Tokenize("人工智能")
+	if len(tokens) != 3 || tokens[1].Text != "工智" || tokens[1].Start != 3 || tokens[1].End != 9 {
+		t.Errorf("Tokenize(人工智能) = %+v", tokens)
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	idx := NewIndex()
+	idx.Add(Document{ID: "a", Title: "聊聊人工智能", PodcastName: "科技早知道", ShowNotes: "本期我们讨论 AI 的未来"})
+	idx.Add(Document{ID: "b", Title: "城市漫步", PodcastName: "随便聊聊", ShowNotes: "开场之后，我们聊到了人工智能 & 城市规划。" + strings.Repeat("其他内容", 50)})
+	idx.Add(Document{ID: "c", Title: "Startup stories", PodcastName: "Tech Talk", ShowNotes: "Interview with an AI founder"})
+
+	ids := func(results []Result) string {
+		var ids []string
+		for _, r := range results {
+			ids = append(ids, r.ID)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	// A title match ranks above a show notes match
+	results := idx.Search("人工智能")
+	if got := ids(results); got != "a,b" {
+		t.Fatalf("Search(人工智能) = %s, want a,b", got)
+	}
+	if got := results[0].Title.HTML(); got != "聊聊<mark>人工智能</mark>" {
+		t.Errorf("title = %q", got)
+	}
+	if got := results[1].Snippet.HTML(); !strings.HasPrefix(got, "开场之后，我们聊到了<mark>人工智能</mark> &amp; 城市规划。") || !strings.HasSuffix(got, "…") {
+		t.Errorf("snippet = %q", got)
+	}
+
+	// Every term must match; Latin words match case-insensitively
+	if got := ids(idx.Search("ai 未来")); got != "a" {
+		t.Errorf("Search(ai 未来) = %s, want a", got)
+	}
+	if got := ids(idx.Search("AI")); got != "a,c" && got != "c,a" {
+		t.Errorf("Search(AI) = %s, want a and c", got)
+	}
+	if got := idx.Search("量子"); len(got) != 0 {
+		t.Errorf("Search(量子) = %s, want nothing", ids(got))
+	}
+
+	// A single CJK character matches inside longer runs
+	results = idx.Search("智")
+	if got := ids(results); got != "a,b" {
+		t.Errorf("Search(智) = %s, want a,b", got)
+	}
+	if len(results) > 0 && results[0].Title.HTML() != "聊聊人工<mark>智</mark>能" {
+		t.Errorf("title = %q", results[0].Title.HTML())
+	}
+	if got := ids(idx.Search("城")); got != "b" {
+		t.Errorf("Search(城) = %s, want b", got)
+	}
+
+	// Re-adding replaces the document; removing drops it
+	idx.Add(Document{ID: "a", Title: "量子计算"})
+	if got := ids(idx.Search("人工智能")); got != "b" {
+		t.Errorf("after replacing a: %s, want b", got)
+	}
+	idx.Remove("b")
+	if got := idx.Search("人工智能"); len(got) != 0 || idx.Len() != 2 {
+		t.Errorf("after removing b: %s, %d documents", ids(got), idx.Len())
+	}
+}
@@ -0,0 +1,105 @@
+// Package search is an in-memory full-text index of episodes. Chinese,
+// Japanese and Korean text has no spaces between words, so it is indexed as
+// overlapping pairs of characters (bigrams) and single characters, so that
+// a one-character query matches too; other text is indexed by word.
+package search
+
+import "unicode"
+
+// Token is a term of a text with its position.
+type Token struct {
+	Text  string // Normalized term
+	Start int    // Byte offset of the term in the original text
+	End   int
+}
+
+// Tokenize splits text into lowercase words and CJK bigrams. A CJK run of a
+// single character yields that character. Full-width letters and digits are
+// folded to their ASCII forms.
+func Tokenize(text string) []Token {
+	return tokenize(text, false)
+}
+
+// indexTokens is Tokenize plus every character of the CJK runs it splits
+// into bigrams, ordered by position. Documents are indexed with these so a
+// single-character query, which Tokenize leaves as is, finds them.
+func indexTokens(text string) []Token {
+	return tokenize(text, true)
+}
+
+func tokenize(text string, unigrams bool) []Token {
+	var tokens []Token
+	var word []rune
+	wordStart := 0
+	var cjk []rune
+	var cjkOffsets []int
+
+	flushWord := func(end int) {
+		if len(word) > 0 {
+			tokens = append(tokens, Token{Text: string(word), Start: wordStart, End: end})
+			word = word[:0]
+		}
+	}
+	flushCJK := func(end int) {
+		switch len(cjk) {
+		case 0:
+			return
+		case 1:
+			tokens = append(tokens, Token{Text: string(cjk), Start: cjkOffsets[0], End: end})
+		default:
+			for i := range cjk {
+				charEnd := end
+				if i+1 < len(cjk) {
+					charEnd = cjkOffsets[i+1]
+				}
+				if unigrams {
+					tokens = append(tokens, Token{Text: string(cjk[i]), Start: cjkOffsets[i], End: charEnd})
+				}
+				if i+1 < len(cjk) {
+					tokenEnd := end
+					if i+2 < len(cjk) {
+						tokenEnd = cjkOffsets[i+2]
+					}
+					tokens = append(tokens, Token{Text: string(cjk[i : i+2]), Start: cjkOffsets[i], End: tokenEnd})
+				}
+			}
+		}
+		cjk = cjk[:0]
+		cjkOffsets = cjkOffsets[:0]
+	}
+
+	for offset, r := range text {
+		r = normalize(r)
+		switch {
+		case isCJK(r):
+			flushWord(offset)
+			cjk = append(cjk, r)
+			cjkOffsets = append(cjkOffsets, offset)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK(offset)
+			if len(word) == 0 {
+				wordStart = offset
+			}
+			word = append(word, r)
+		default:
+			flushWord(offset)
+			flushCJK(offset)
+		}
+	}
+	flushWord(len(text))
+	flushCJK(len(text))
+	return tokens
+}
+
+// normalize lowercases r and folds full-width ASCII variants.
+func normalize(r rune) rune {
+	if r >= '！' && r <= '～' {
+		r -= '！' - '!'
+	}
+	return unicode.ToLower(r)
+}
+
+// isCJK reports whether r is written without spaces between words.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
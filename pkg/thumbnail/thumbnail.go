@@ -0,0 +1,186 @@
+// Package thumbnail generates resized JPEG copies of cover images so lists
+// don't have to load full-size covers.
+package thumbnail
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // Register the GIF decoder
+	"image/jpeg"
+	_ "image/png" // Register the PNG decoder
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sizes are the thumbnail sizes, in pixels along the longest side, generated
+// for each cover.
+var Sizes = []int{128, 512}
+
+var (
+	// ErrUnsupportedFormat is returned for covers the standard library can't
+	// decode, such as WebP.
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	// ErrTooLarge is returned for covers with more than maxPixels pixels,
+	// which would take too much memory to decode.
+	ErrTooLarge = errors.New("image too large")
+)
+
+const (
+	// jpegQuality is the quality thumbnails are encoded with.
+	jpegQuality = 85
+	// maxPixels is the largest cover, in pixels, that is decoded. A
+	// decoded image takes up to 8 bytes per pixel.
+	maxPixels = 50_000_000
+)
+
+// IsValidSize reports whether size is one of Sizes.
+func IsValidSize(size int) bool {
+	for _, s := range Sizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+// Path returns where the thumbnail of the given size is stored, next to the
+// cover: cover.png becomes cover_128.jpg.
+func Path(coverPath string, size int) string {
+	base := strings.TrimSuffix(filepath.Base(coverPath), filepath.Ext(coverPath))
+	return filepath.Join(filepath.Dir(coverPath), fmt.Sprintf("%s_%d.jpg", base, size))
+}
+
+// GenerateAll generates the thumbnails of every size in Sizes.
+func GenerateAll(coverPath string) error {
+	for _, size := range Sizes {
+		if _, err := Generate(coverPath, size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ensure returns the path of the thumbnail of the given size, generating it
+// if it is missing or older than the cover.
+func Ensure(coverPath string, size int) (string, error) {
+	cover, err := os.Stat(coverPath)
+	if err != nil {
+		return "", err
+	}
+	path := Path(coverPath, size)
+	if thumb, err := os.Stat(path); err == nil && !thumb.ModTime().Before(cover.ModTime()) {
+		return path, nil
+	}
+	return Generate(coverPath, size)
+}
+
+// Generate writes the thumbnail of the given size and returns its path.
+// Covers smaller than size are re-encoded without being enlarged.
+func Generate(coverPath string, size int) (string, error) {
+	f, err := os.Open(coverPath)
+	if err != nil {
+		return "", err
+	}
+	src, err := decode(f)
+	f.Close()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", filepath.Base(coverPath), err)
+	}
+
+	// Write to a temporary file first so concurrent requests never see a
+	// partial thumbnail
+	path := Path(coverPath, size)
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".thumbnail-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := jpeg.Encode(tmp, Resize(src, size), &jpeg.Options{Quality: jpegQuality}); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("encode thumbnail: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// decode decodes an image after checking from its header that it isn't
+// too large.
+func decode(f *os.File) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, ErrUnsupportedFormat
+		}
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooLarge, cfg.Width, cfg.Height)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	return src, nil
+}
+
+// Resize scales src down so its longest side is size, keeping the aspect
+// ratio. Each output pixel averages the source pixels it covers, and
+// transparent areas are flattened onto white since JPEG has no alpha.
+func Resize(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+	dw, dh := sw, sh
+	if sw > size || sh > size {
+		if sw >= sh {
+			dw, dh = size, max(1, sh*size/sw)
+		} else {
+			dw, dh = max(1, sw*size/sh), size
+		}
+	}
+
+	// Flatten the source rows each output row covers onto white, in a
+	// buffer whose pixels can be read directly. Only one band of rows is
+	// converted at a time, so the full-size image is never copied.
+	band := image.NewRGBA(image.Rect(0, 0, sw, (sh+dh-1)/dh))
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*sh/dh, max((y+1)*sh/dh, y*sh/dh+1)
+		rows := image.Rect(0, 0, sw, y1-y0)
+		draw.Draw(band, rows, image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(band, rows, src, image.Pt(bounds.Min.X, bounds.Min.Y+y0), draw.Over)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*sw/dw, max((x+1)*sw/dw, x*sw/dw+1)
+			var sum [4]int
+			for sy := 0; sy < y1-y0; sy++ {
+				row := band.Pix[sy*band.Stride+x0*4 : sy*band.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			i := y*dst.Stride + x*4
+			for c := 0; c < 4; c++ {
+				dst.Pix[i+c] = uint8((sum[c] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,92 @@
+package thumbnail
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsure(t *testing.T) {
+	dir := t.TempDir()
+	cover := filepath.Join(dir, "cover.png")
+	src := image.NewNRGBA(image.Rect(0, 0, 300, 150))
+	for y := 0; y < 150; y++ {
+		for x := 0; x < 300; x++ {
+			src.Set(x, y, color.NRGBA{R: 200, A: 255})
+		}
+	}
+	f, err := os.Create(cover)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, src); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	path, err := Ensure(cover, 128)
+	if err != nil {
+		t.Fatalf("Ensure() error = %v", err)
+	}
+	if want := filepath.Join(dir, "cover_128.jpg"); path != want {
+		t.Errorf("Ensure() = %q, want %q", path, want)
+	}
+	f, err = os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumb, err := jpeg.Decode(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("thumbnail is not a JPEG: %v", err)
+	}
+	if got := thumb.Bounds().Size(); got != image.Pt(128, 64) {
+		t.Errorf("thumbnail size = %v, want 128x64", got)
+	}
+	if r, _, _, _ := thumb.At(64, 32).RGBA(); r>>8 < 190 || r>>8 > 210 {
+		t.Errorf("thumbnail red = %d, want ~200", r>>8)
+	}
+
+	// An up-to-date thumbnail is reused
+	info, _ := os.Stat(path)
+	if _, err := Ensure(cover, 128); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.Stat(path); !again.ModTime().Equal(info.ModTime()) {
+		t.Error("Ensure() regenerated an up-to-date thumbnail")
+	}
+
+	// Covers are never enlarged
+	if _, err := Generate(cover, 512); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := os.Open(Path(cover, 512)); err == nil {
+		cfg, _ := jpeg.DecodeConfig(f)
+		f.Close()
+		if cfg.Width != 300 || cfg.Height != 150 {
+			t.Errorf("512 thumbnail = %dx%d, want 300x150", cfg.Width, cfg.Height)
+		}
+	}
+
+	webp := filepath.Join(t.TempDir(), "cover.webp")
+	if err := os.WriteFile(webp, []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Ensure(webp, 128); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Ensure(webp) error = %v, want ErrUnsupportedFormat", err)
+	}
+
+	// Oversized covers are rejected from their header, before decoding
+	huge := filepath.Join(t.TempDir(), "cover.gif")
+	if err := os.WriteFile(huge, []byte("GIF89a\xff\xff\xff\xff\x00\x00\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Ensure(huge, 128); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Ensure(65535x65535) error = %v, want ErrTooLarge", err)
+	}
+}
@@ -57,3 +57,37 @@ func (v *XiaoyuzhouURLValidator) ValidateURL(urlStr string) (bool, string) {
 
 	return true, ""
 }
+
+// XiaoyuzhouShowURLValidator validates Xiaoyuzhou FM podcast (show) page URLs.
+type XiaoyuzhouShowURLValidator struct {
+	// Pattern matches: *.xiaoyuzhoufm.com/podcast/{podcast_id}
+	pattern *regexp.Regexp
+}
+
+// NewXiaoyuzhouShowURLValidator creates a new validator for Xiaoyuzhou FM show URLs.
+func NewXiaoyuzhouShowURLValidator() *XiaoyuzhouShowURLValidator {
+	// Pattern matches Xiaoyuzhou FM podcast pages, e.g.
+	//   - https://www.xiaoyuzhoufm.com/podcast/{podcast_id}
+	pattern := regexp.MustCompile(`^https?://([a-z0-9-]+\.)*xiaoyuzhoufm\.com/podcast/[^/?#]+/?([?#].*)?$`)
+	return &XiaoyuzhouShowURLValidator{
+		pattern: pattern,
+	}
+}
+
+// ValidateURL implements URLValidator for Xiaoyuzhou FM show URLs.
+func (v *XiaoyuzhouShowURLValidator) ValidateURL(urlStr string) (bool, string) {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return false, "URL格式无效: " + err.Error()
+	}
+
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return false, "URL必须使用HTTP或HTTPS协议"
+	}
+
+	if !v.pattern.MatchString(urlStr) {
+		return false, "URL格式不正确，应为: https://www.xiaoyuzhoufm.com/podcast/{podcast_id}"
+	}
+
+	return true, ""
+}
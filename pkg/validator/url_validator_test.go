@@ -141,3 +141,28 @@ func TestXiaoyuzhouURLValidator_ValidateURL_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestXiaoyuzhouShowURLValidator_ValidateURL(t *testing.T) {
+	validator := NewXiaoyuzhouShowURLValidator()
+
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://www.xiaoyuzhoufm.com/podcast/5e280fab418a84a0461fa8c4", true},
+		{"https://xiaoyuzhoufm.com/podcast/5e280fab418a84a0461fa8c4/", true},
+		{"https://www.xiaoyuzhoufm.com/podcast/5e280fab418a84a0461fa8c4?s=share", true},
+		{"https://www.xiaoyuzhoufm.com/podcast/", false},
+		{"https://www.xiaoyuzhoufm.com/podcast/abc/episodes", false},
+		{"https://www.xiaoyuzhoufm.com/episode/12345678", false},
+		{"https://www.example.com/podcast/12345678", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.url, func(t *testing.T) {
+			if valid, errMsg := validator.ValidateURL(tc.url); valid != tc.valid {
+				t.Errorf("ValidateURL(%s) = %v (%s), want %v", tc.url, valid, errMsg, tc.valid)
+			}
+		})
+	}
+}
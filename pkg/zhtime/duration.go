@@ -0,0 +1,83 @@
+// Package zhtime parses the Chinese duration and time expressions shown on
+// podcast pages, such as "1小时15分钟" or "3天前".
+package zhtime
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidDuration is returned for text that is not a recognized duration.
+var ErrInvalidDuration = errors.New("无法识别的时长")
+
+// durationUnits maps Chinese and English unit names to their length.
+var durationUnits = map[string]time.Duration{
+	"秒": time.Second, "秒钟": time.Second,
+	"分": time.Minute, "分钟": time.Minute,
+	"时": time.Hour, "小时": time.Hour, "个小时": time.Hour, "钟头": time.Hour, "个钟头": time.Hour,
+	"天": 24 * time.Hour, "日": 24 * time.Hour,
+	"周": 7 * 24 * time.Hour, "星期": 7 * 24 * time.Hour, "个星期": 7 * 24 * time.Hour, "礼拜": 7 * 24 * time.Hour, "个礼拜": 7 * 24 * time.Hour,
+	"d": 24 * time.Hour, "w": 7 * 24 * time.Hour,
+}
+
+// durationPart matches one number and unit, e.g. "15分钟" or "1.5小时".
+var durationPart = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*(个小时|个钟头|个星期|个礼拜|小时|钟头|分钟|秒钟|星期|礼拜|[秒分时天日周dw])`)
+
+// ParseDuration parses a duration such as "103分钟", "1小时15分钟", "45分30秒",
+// "2天", a clock value like "1:02:03", or a Go duration like "1h30m".
+// Spaces are ignored.
+func ParseDuration(s string) (time.Duration, error) {
+	text := strings.Join(strings.Fields(s), "")
+	if text == "" {
+		return 0, fmt.Errorf("%w: 空字符串", ErrInvalidDuration)
+	}
+
+	if d, ok := parseClock(text); ok {
+		return d, nil
+	}
+	if d, err := time.ParseDuration(text); err == nil {
+		return d, nil
+	}
+
+	matches := durationPart.FindAllStringSubmatchIndex(text, -1)
+	if matches == nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+	}
+
+	var total time.Duration
+	end := 0
+	for _, m := range matches {
+		if m[0] != end {
+			// Something other than a number and unit in between
+			return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+		}
+		value, _ := strconv.ParseFloat(text[m[2]:m[3]], 64)
+		total += time.Duration(value * float64(durationUnits[text[m[4]:m[5]]]))
+		end = m[1]
+	}
+	if end != len(text) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+	}
+	return total, nil
+}
+
+// parseClock parses "MM:SS" or "HH:MM:SS".
+func parseClock(s string) (time.Duration, bool) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	var seconds int
+	for _, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil || v < 0 {
+			return 0, false
+		}
+		seconds = seconds*60 + v
+	}
+	return time.Duration(seconds) * time.Second, true
+}
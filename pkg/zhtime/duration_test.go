@@ -0,0 +1,43 @@
+package zhtime
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"103分钟", 103 * time.Minute},
+		{"1小时15分钟", 75 * time.Minute},
+		{"1 小时 15 分", 75 * time.Minute},
+		{"2小时", 2 * time.Hour},
+		{"1.5小时", 90 * time.Minute},
+		{"45分30秒", 45*time.Minute + 30*time.Second},
+		{"3天", 72 * time.Hour},
+		{"2周", 14 * 24 * time.Hour},
+		{"7d", 7 * 24 * time.Hour},
+		{"1h30m", 90 * time.Minute},
+		{"1:02:03", time.Hour + 2*time.Minute + 3*time.Second},
+		{"45:10", 45*time.Minute + 10*time.Second},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if err != nil {
+			t.Errorf("ParseDuration(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "abc", "分钟", "10分钟左右", "约10分钟", "1:xx"} {
+		if _, err := ParseDuration(in); !errors.Is(err, ErrInvalidDuration) {
+			t.Errorf("ParseDuration(%q) error = %v, want ErrInvalidDuration", in, err)
+		}
+	}
+}
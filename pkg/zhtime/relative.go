@@ -0,0 +1,101 @@
+package zhtime
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidTime is returned for text that is not a recognized publish time.
+var ErrInvalidTime = errors.New("无法识别的发布时间")
+
+// Precision states how closely a resolved time matches the real one.
+type Precision string
+
+const (
+	PrecisionExact  Precision = "exact"
+	PrecisionMinute Precision = "minute"
+	PrecisionHour   Precision = "hour"
+	PrecisionDay    Precision = "day"
+	PrecisionWeek   Precision = "week"
+	PrecisionMonth  Precision = "month"
+	PrecisionYear   Precision = "year"
+)
+
+// relativeTime matches a relative time such as "3天前" or "2个月前".
+var relativeTime = regexp.MustCompile(`^(\d+)(秒钟?|分钟?|个?小时|个?钟头|天|日|个?星期|周|个?月|年)前$`)
+
+// chineseDate matches dates such as "2024年3月5日" or "3月5日".
+var chineseDate = regexp.MustCompile(`^(?:(\d{4})年)?(\d{1,2})月(\d{1,2})[日号]$`)
+
+// ResolvePublishTime turns the publish time shown on a page into an absolute
+// time. Relative times such as "2个月前" or "刚刚发布" are counted back from
+// ref, the time the page was fetched, so the result is only as precise as
+// the unit they are given in. Absolute dates are accepted as well.
+func ResolvePublishTime(s string, ref time.Time) (time.Time, Precision, error) {
+	text := strings.Join(strings.Fields(s), "")
+	switch text {
+	case "":
+		return time.Time{}, "", fmt.Errorf("%w: 空字符串", ErrInvalidTime)
+	case "刚刚", "刚刚发布", "刚刚更新":
+		return ref, PrecisionMinute, nil
+	case "今天":
+		return ref, PrecisionDay, nil
+	case "昨天":
+		return ref.AddDate(0, 0, -1), PrecisionDay, nil
+	case "前天":
+		return ref.AddDate(0, 0, -2), PrecisionDay, nil
+	}
+
+	if m := relativeTime.FindStringSubmatch(text); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return time.Time{}, "", fmt.Errorf("%w: %q", ErrInvalidTime, s)
+		}
+		switch strings.TrimPrefix(m[2], "个") {
+		case "秒", "秒钟":
+			return ref.Add(-time.Duration(n) * time.Second), PrecisionMinute, nil
+		case "分", "分钟":
+			return ref.Add(-time.Duration(n) * time.Minute), PrecisionMinute, nil
+		case "小时", "钟头":
+			return ref.Add(-time.Duration(n) * time.Hour), PrecisionHour, nil
+		case "天", "日":
+			return ref.AddDate(0, 0, -n), PrecisionDay, nil
+		case "星期", "周":
+			return ref.AddDate(0, 0, -7*n), PrecisionWeek, nil
+		case "月":
+			return ref.AddDate(0, -n, 0), PrecisionMonth, nil
+		case "年":
+			return ref.AddDate(-n, 0, 0), PrecisionYear, nil
+		}
+	}
+
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		return t, PrecisionExact, nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006/01/02", "2006.01.02"} {
+		if t, err := time.ParseInLocation(layout, text, ref.Location()); err == nil {
+			return t, PrecisionDay, nil
+		}
+	}
+	if m := chineseDate.FindStringSubmatch(text); m != nil {
+		year := ref.Year()
+		if m[1] != "" {
+			year, _ = strconv.Atoi(m[1])
+		}
+		month, _ := strconv.Atoi(m[2])
+		day, _ := strconv.Atoi(m[3])
+		t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, ref.Location())
+		if t.Month() == time.Month(month) && t.Day() == day {
+			if m[1] == "" && t.After(ref) {
+				// Pages leave out the year for dates in the past twelve months
+				t = t.AddDate(-1, 0, 0)
+			}
+			return t, PrecisionDay, nil
+		}
+	}
+	return time.Time{}, "", fmt.Errorf("%w: %q", ErrInvalidTime, s)
+}
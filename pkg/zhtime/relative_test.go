@@ -0,0 +1,45 @@
+package zhtime
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestResolvePublishTime(t *testing.T) {
+	ref := time.Date(2024, 3, 31, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in        string
+		want      time.Time
+		precision Precision
+	}{
+		{"刚刚发布", ref, PrecisionMinute},
+		{"25分钟前", ref.Add(-25 * time.Minute), PrecisionMinute},
+		{"3小时前", ref.Add(-3 * time.Hour), PrecisionHour},
+		{"昨天", time.Date(2024, 3, 30, 20, 0, 0, 0, time.UTC), PrecisionDay},
+		{"3天前", time.Date(2024, 3, 28, 20, 0, 0, 0, time.UTC), PrecisionDay},
+		{"2周前", time.Date(2024, 3, 17, 20, 0, 0, 0, time.UTC), PrecisionWeek},
+		{"2个月前", time.Date(2024, 1, 31, 20, 0, 0, 0, time.UTC), PrecisionMonth},
+		{"1年前", time.Date(2023, 3, 31, 20, 0, 0, 0, time.UTC), PrecisionYear},
+		{"2024-01-05", time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), PrecisionDay},
+		{"2024-01-05T08:00:00Z", time.Date(2024, 1, 5, 8, 0, 0, 0, time.UTC), PrecisionExact},
+		{"2023年12月1日", time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), PrecisionDay},
+		{"11月20日", time.Date(2023, 11, 20, 0, 0, 0, 0, time.UTC), PrecisionDay},
+	}
+	for _, tt := range tests {
+		got, precision, err := ResolvePublishTime(tt.in, ref)
+		if err != nil {
+			t.Errorf("ResolvePublishTime(%q) error = %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) || precision != tt.precision {
+			t.Errorf("ResolvePublishTime(%q) = %v, %s, want %v, %s", tt.in, got, precision, tt.want, tt.precision)
+		}
+	}
+
+	for _, in := range []string{"", "很久以前", "3天后", "2月30日"} {
+		if _, _, err := ResolvePublishTime(in, ref); !errors.Is(err, ErrInvalidTime) {
+			t.Errorf("ResolvePublishTime(%q) error = %v, want ErrInvalidTime", in, err)
+		}
+	}
+}
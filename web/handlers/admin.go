@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/meixg/podcast-reader/pkg/models"
+	"github.com/meixg/podcast-reader/web/services"
+)
+
+// AdminHandler handles administrative HTTP requests
+type AdminHandler struct {
+	taskService *services.TaskService
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(taskService *services.TaskService) *AdminHandler {
+	return &AdminHandler{
+		taskService: taskService,
+	}
+}
+
+// UpdateQueueRequest represents the request body for changing queue settings
+type UpdateQueueRequest struct {
+	Concurrency *int                 `json:"concurrency,omitempty"`
+	Order       *services.QueueOrder `json:"order,omitempty"`
+}
+
+// HandleQueue handles GET and PUT/PATCH/POST /api/admin/queue
+func (h *AdminHandler) HandleQueue(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.sendJSON(w, h.taskService.Queue().Stats(), http.StatusOK)
+	case http.MethodPut, http.MethodPatch, http.MethodPost:
+		h.updateQueue(w, r)
+	default:
+		h.sendError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+	}
+}
+
+// updateQueue handles PUT /api/admin/queue. Raising the concurrency starts
+// waiting downloads right away; lowering it lets running ones finish.
+func (h *AdminHandler) updateQueue(w http.ResponseWriter, r *http.Request) {
+	var req UpdateQueueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, "Invalid request body", "INVALID_REQUEST", http.StatusBadRequest)
+		return
+	}
+
+	queue := h.taskService.Queue()
+	if req.Order != nil {
+		if err := queue.SetOrder(*req.Order); err != nil {
+			h.sendError(w, err.Error(), "INVALID_PARAMETER", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Concurrency != nil {
+		if err := queue.SetConcurrency(*req.Concurrency); err != nil {
+			h.sendError(w, err.Error(), "INVALID_PARAMETER", http.StatusBadRequest)
+			return
+		}
+	}
+
+	h.sendJSON(w, queue.Stats(), http.StatusOK)
+}
+
+// Helper methods
+func (h *AdminHandler) sendJSON(w http.ResponseWriter, data interface{}, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *AdminHandler) sendError(w http.ResponseWriter, message, code string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.APIError{
+		Error: message,
+		Code:  code,
+	})
+}
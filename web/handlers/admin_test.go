@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/meixg/podcast-reader/web/services"
+)
+
+func TestAdminHandler_HandleQueue(t *testing.T) {
+	taskService := services.NewTaskService()
+	h := NewAdminHandler(taskService)
+	request := func(method, body string) (*httptest.ResponseRecorder, services.QueueStats) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.HandleQueue(w, httptest.NewRequest(method, "/api/admin/queue", strings.NewReader(body)))
+		var stats services.QueueStats
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+				t.Fatalf("%s: invalid response: %v", method, err)
+			}
+		}
+		return w, stats
+	}
+
+	// The limit can be changed on the running queue with any update method
+	for i, method := range []string{http.MethodPut, http.MethodPatch, http.MethodPost} {
+		w, stats := request(method, fmt.Sprintf(`{"concurrency":%d}`, 5+i))
+		if w.Code != http.StatusOK || stats.Concurrency != 5+i {
+			t.Errorf("%s concurrency %d = %d, %+v", method, 5+i, w.Code, stats)
+		}
+	}
+	if _, stats := request(http.MethodGet, ""); stats.Concurrency != 7 || taskService.Queue().Stats().Concurrency != 7 {
+		t.Errorf("GET = %+v, want concurrency 7", stats)
+	}
+
+	if _, stats := request(http.MethodPut, `{"order":"priority"}`); stats.Order != services.QueueOrderPriority || stats.Concurrency != 7 {
+		t.Errorf("PUT order = %+v, want priority order and the same concurrency", stats)
+	}
+	for _, body := range []string{`{"concurrency":0}`, `{"order":"random"}`, `not json`} {
+		if w, _ := request(http.MethodPut, body); w.Code != http.StatusBadRequest {
+			t.Errorf("PUT %s = %d, want 400", body, w.Code)
+		}
+	}
+	if w, _ := request(http.MethodDelete, ""); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE = %d, want 405", w.Code)
+	}
+}
@@ -2,11 +2,18 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/meixg/podcast-reader/pkg/mediainfo"
 	"github.com/meixg/podcast-reader/pkg/models"
+	"github.com/meixg/podcast-reader/pkg/thumbnail"
 	"github.com/meixg/podcast-reader/web/services"
 )
 
@@ -23,6 +30,8 @@ func NewEpisodeHandler(service *services.EpisodeService) *EpisodeHandler {
 }
 
 // GetEpisodes handles GET /api/episodes
+// Supports ?sort=downloadDate|publishDate|relevance, ?order=asc|desc, ?since=/?until= publish dates
+// and ?q= full-text search, which sorts by relevance unless another sort is given
 func (h *EpisodeHandler) GetEpisodes(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		h.sendError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
@@ -39,8 +48,43 @@ func (h *EpisodeHandler) GetEpisodes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Sort and filter by publish date
+	query := r.URL.Query()
+	opts := services.EpisodeListOptions{
+		Sort:  services.SortByDownloadDate,
+		Query: strings.TrimSpace(query.Get("q")),
+	}
+	if opts.Query != "" {
+		opts.Sort = services.SortByRelevance
+	}
+	switch sortBy := services.EpisodeSort(query.Get("sort")); sortBy {
+	case "":
+	case services.SortByDownloadDate, services.SortByPublishDate, services.SortByRelevance:
+		opts.Sort = sortBy
+	default:
+		h.sendError(w, "Invalid sort. Must be downloadDate, publishDate or relevance", "INVALID_PARAMETER", http.StatusBadRequest)
+		return
+	}
+	switch query.Get("order") {
+	case "", "desc":
+	case "asc":
+		opts.Ascending = true
+	default:
+		h.sendError(w, "Invalid order. Must be asc or desc", "INVALID_PARAMETER", http.StatusBadRequest)
+		return
+	}
+	var err error
+	if opts.Since, err = parseDate(query.Get("since"), false); err != nil {
+		h.sendError(w, "Invalid since date, expected YYYY-MM-DD or RFC 3339", "INVALID_PARAMETER", http.StatusBadRequest)
+		return
+	}
+	if opts.Until, err = parseDate(query.Get("until"), true); err != nil {
+		h.sendError(w, "Invalid until date, expected YYYY-MM-DD or RFC 3339", "INVALID_PARAMETER", http.StatusBadRequest)
+		return
+	}
+
 	// Get episodes
-	result, err := h.service.GetEpisodes(page, pageSize)
+	result, err := h.service.GetEpisodes(page, pageSize, opts)
 	if err != nil {
 		h.sendError(w, "Failed to get episodes", "SERVER_ERROR", http.StatusInternalServerError)
 		return
@@ -49,32 +93,139 @@ func (h *EpisodeHandler) GetEpisodes(w http.ResponseWriter, r *http.Request) {
 	h.sendJSON(w, result, http.StatusOK)
 }
 
-// GetShowNotes handles GET /api/episodes/:id/shownotes
-func (h *EpisodeHandler) GetShowNotes(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+// HandleEpisode handles GET /api/episodes/{id}/shownotes, /chapters, /audio and /cover
+func (h *EpisodeHandler) HandleEpisode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
 		h.sendError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
 		return
 	}
 
 	// Extract episode ID from path
-	path := strings.TrimPrefix(r.URL.Path, "/api/episodes/")
-	episodeID := strings.TrimSuffix(path, "/shownotes")
-
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/episodes/"), "/")
+	episodeID, resource, _ := strings.Cut(path, "/")
 	if episodeID == "" {
 		h.sendError(w, "Episode ID required", "INVALID_PARAMETER", http.StatusBadRequest)
 		return
 	}
 
-	// Get show notes
+	switch resource {
+	case "shownotes":
+		h.getShowNotes(w, episodeID)
+	case "chapters":
+		h.getChapters(w, episodeID)
+	case "audio":
+		h.getAudio(w, r, episodeID)
+	case "cover":
+		h.getCover(w, r, episodeID)
+	default:
+		h.sendError(w, "Not found", "NOT_FOUND", http.StatusNotFound)
+	}
+}
+
+// getShowNotes handles GET /api/episodes/{id}/shownotes
+func (h *EpisodeHandler) getShowNotes(w http.ResponseWriter, episodeID string) {
 	showNotes, err := h.service.GetShowNotes(episodeID)
 	if err != nil {
-		h.sendError(w, "Episode not found", "NOT_FOUND", http.StatusNotFound)
+		h.sendServiceError(w, err)
 		return
 	}
 
 	h.sendJSON(w, map[string]string{"showNotes": showNotes}, http.StatusOK)
 }
 
+// getChapters handles GET /api/episodes/{id}/chapters
+func (h *EpisodeHandler) getChapters(w http.ResponseWriter, episodeID string) {
+	chapters, err := h.service.GetChapters(episodeID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.sendJSON(w, map[string][]models.Chapter{"chapters": chapters}, http.StatusOK)
+}
+
+// getAudio handles GET /api/episodes/{id}/audio, including Range and conditional requests
+func (h *EpisodeHandler) getAudio(w http.ResponseWriter, r *http.Request, episodeID string) {
+	path, err := h.service.GetAudioPath(episodeID)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	h.serveFile(w, r, path, mediainfo.ContentType(path))
+}
+
+// getCover handles GET /api/episodes/{id}/cover
+// Supports ?size= with one of the thumbnail sizes
+func (h *EpisodeHandler) getCover(w http.ResponseWriter, r *http.Request, episodeID string) {
+	size := 0
+	if value := r.URL.Query().Get("size"); value != "" {
+		var err error
+		if size, err = strconv.Atoi(value); err != nil || !thumbnail.IsValidSize(size) {
+			h.sendError(w, fmt.Sprintf("Invalid size. Must be one of %v", thumbnail.Sizes), "INVALID_PARAMETER", http.StatusBadRequest)
+			return
+		}
+	}
+
+	path, err := h.service.GetCoverPath(episodeID, size)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	// Covers are not always in the format their extension says, so sniff them
+	h.serveFile(w, r, path, "")
+}
+
+// serveFile serves a file with Range, ETag and Last-Modified support.
+// An empty contentType is detected from the file contents.
+func (h *EpisodeHandler) serveFile(w http.ResponseWriter, r *http.Request, path, contentType string) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			h.sendError(w, "File not found", "NOT_FOUND", http.StatusNotFound)
+			return
+		}
+		h.sendError(w, "Failed to open file", "SERVER_ERROR", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		h.sendError(w, "Failed to open file", "SERVER_ERROR", http.StatusInternalServerError)
+		return
+	}
+
+	if contentType == "" {
+		var head [512]byte
+		n, _ := io.ReadFull(f, head[:])
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			h.sendError(w, "Failed to read file", "SERVER_ERROR", http.StatusInternalServerError)
+			return
+		}
+		contentType = http.DetectContentType(head[:n])
+	}
+
+	// The file is rewritten in place when re-tagged, so size and mtime identify its contents
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+	http.ServeContent(w, r, filepath.Base(path), info.ModTime(), f)
+}
+
+// sendServiceError maps episode service errors to HTTP responses
+func (h *EpisodeHandler) sendServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrEpisodeNotFound):
+		h.sendError(w, "Episode not found", "NOT_FOUND", http.StatusNotFound)
+		return
+	case errors.Is(err, services.ErrCoverNotFound):
+		h.sendError(w, "Cover not found", "NOT_FOUND", http.StatusNotFound)
+		return
+	}
+	h.sendError(w, "Failed to get episode", "SERVER_ERROR", http.StatusInternalServerError)
+}
+
 // Helper methods
 func (h *EpisodeHandler) parseIntParam(r *http.Request, key string, defaultValue int) int {
 	value := r.URL.Query().Get(key)
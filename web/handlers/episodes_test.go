@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/meixg/podcast-reader/pkg/scanner"
+	"github.com/meixg/podcast-reader/web/services"
+)
+
+func TestEpisodeHandler_GetAudio(t *testing.T) {
+	dir := t.TempDir()
+	episodeDir := filepath.Join(dir, "show")
+	if err := os.MkdirAll(episodeDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(episodeDir, "podcast.m4a"), []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	library := services.NewLibraryService(scanner.NewScanner(dir), filepath.Join(dir, ".library.json"))
+	service := services.NewEpisodeService(library)
+	result, err := service.GetEpisodes(1, 20, services.EpisodeListOptions{})
+	if err != nil || len(result.Episodes) != 1 {
+		t.Fatalf("GetEpisodes() = %+v, %v", result, err)
+	}
+	h := NewEpisodeHandler(service)
+	url := "/api/episodes/" + result.Episodes[0].ID + "/audio"
+
+	get := func(headers map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		h.HandleEpisode(w, req)
+		return w
+	}
+
+	full := get(nil)
+	if full.Code != http.StatusOK || full.Body.String() != "0123456789" {
+		t.Fatalf("GET = %d %q", full.Code, full.Body.String())
+	}
+	if got := full.Header().Get("Content-Type"); got != "audio/mp4" {
+		t.Errorf("Content-Type = %q, want audio/mp4", got)
+	}
+	if got := full.Header().Get("Accept-Ranges"); got != "bytes" {
+		t.Errorf("Accept-Ranges = %q, want bytes", got)
+	}
+	etag := full.Header().Get("ETag")
+	lastModified := full.Header().Get("Last-Modified")
+	if etag == "" || lastModified == "" {
+		t.Fatalf("ETag = %q, Last-Modified = %q", etag, lastModified)
+	}
+
+	tests := []struct {
+		name         string
+		headers      map[string]string
+		code         int
+		body         string
+		contentRange string
+	}{
+		{"range", map[string]string{"Range": "bytes=2-5"}, http.StatusPartialContent, "2345", "bytes 2-5/10"},
+		{"suffix range", map[string]string{"Range": "bytes=-3"}, http.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"range past the end", map[string]string{"Range": "bytes=20-"}, http.StatusRequestedRangeNotSatisfiable, "", "bytes */10"},
+		{"if-range matching etag", map[string]string{"Range": "bytes=0-1", "If-Range": etag}, http.StatusPartialContent, "01", "bytes 0-1/10"},
+		{"if-range stale etag", map[string]string{"Range": "bytes=0-1", "If-Range": `"stale"`}, http.StatusOK, "0123456789", ""},
+		{"if-none-match", map[string]string{"If-None-Match": etag}, http.StatusNotModified, "", ""},
+		{"if-none-match other", map[string]string{"If-None-Match": `"other"`}, http.StatusOK, "0123456789", ""},
+		{"if-modified-since", map[string]string{"If-Modified-Since": lastModified}, http.StatusNotModified, "", ""},
+	}
+	for _, tt := range tests {
+		w := get(tt.headers)
+		if w.Code != tt.code {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.code)
+			continue
+		}
+		if tt.code != http.StatusRequestedRangeNotSatisfiable && w.Body.String() != tt.body {
+			t.Errorf("%s: body = %q, want %q", tt.name, w.Body.String(), tt.body)
+		}
+		if got := w.Header().Get("Content-Range"); got != tt.contentRange {
+			t.Errorf("%s: Content-Range = %q, want %q", tt.name, got, tt.contentRange)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/episodes/missing/audio", nil)
+	w := httptest.NewRecorder()
+	h.HandleEpisode(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown episode: status = %d, want 404", w.Code)
+	}
+}
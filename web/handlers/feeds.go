@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/meixg/podcast-reader/pkg/models"
+	"github.com/meixg/podcast-reader/pkg/rss"
+	"github.com/meixg/podcast-reader/web/services"
+)
+
+// FeedHandler serves the downloaded episodes as podcast RSS feeds
+type FeedHandler struct {
+	service   *services.FeedService
+	publicURL string
+}
+
+// NewFeedHandler creates a new feed handler. publicURL is the URL podcast
+// apps reach the server at; if empty it is taken from each request.
+func NewFeedHandler(service *services.FeedService, publicURL string) *FeedHandler {
+	return &FeedHandler{
+		service:   service,
+		publicURL: strings.TrimRight(publicURL, "/"),
+	}
+}
+
+// HandleLibraryFeed handles GET /feed.xml
+func (h *FeedHandler) HandleLibraryFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		h.sendError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+		return
+	}
+
+	channel, err := h.service.LibraryFeed(h.baseURL(r))
+	if err != nil {
+		h.sendError(w, "Failed to build feed", "SERVER_ERROR", http.StatusInternalServerError)
+		return
+	}
+	h.sendFeed(w, channel)
+}
+
+// HandlePodcastFeed handles GET /feeds/{podcast}.xml
+func (h *FeedHandler) HandlePodcastFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		h.sendError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+		return
+	}
+
+	podcast, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/feeds/"), ".xml")
+	if !ok || podcast == "" {
+		h.sendError(w, "Not found", "NOT_FOUND", http.StatusNotFound)
+		return
+	}
+
+	channel, err := h.service.PodcastFeed(h.baseURL(r), podcast)
+	if errors.Is(err, services.ErrPodcastNotFound) {
+		h.sendError(w, "Podcast not found", "NOT_FOUND", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.sendError(w, "Failed to build feed", "SERVER_ERROR", http.StatusInternalServerError)
+		return
+	}
+	h.sendFeed(w, channel)
+}
+
+// baseURL returns the URL links in feeds are built from
+func (h *FeedHandler) baseURL(r *http.Request) string {
+	if h.publicURL != "" {
+		return h.publicURL
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// sendFeed writes a feed, buffered so errors can still become a JSON response
+func (h *FeedHandler) sendFeed(w http.ResponseWriter, channel *rss.Channel) {
+	var buf bytes.Buffer
+	if err := channel.Write(&buf); err != nil {
+		h.sendError(w, "Failed to build feed", "SERVER_ERROR", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", rss.ContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func (h *FeedHandler) sendError(w http.ResponseWriter, message, code string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.APIError{
+		Error: message,
+		Code:  code,
+	})
+}
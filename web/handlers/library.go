@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/meixg/podcast-reader/pkg/models"
+	"github.com/meixg/podcast-reader/web/services"
+)
+
+// LibraryHandler handles requests about the episode library
+type LibraryHandler struct {
+	service *services.LibraryService
+}
+
+// NewLibraryHandler creates a new library handler
+func NewLibraryHandler(service *services.LibraryService) *LibraryHandler {
+	return &LibraryHandler{
+		service: service,
+	}
+}
+
+// HandleRescan handles POST /api/library/rescan, which reconciles the
+// library with the downloads directory right away
+func (h *LibraryHandler) HandleRescan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, err := h.service.Rescan()
+	if err != nil {
+		h.sendError(w, "Failed to rescan library", "SERVER_ERROR", http.StatusInternalServerError)
+		return
+	}
+	h.sendJSON(w, result, http.StatusOK)
+}
+
+func (h *LibraryHandler) sendJSON(w http.ResponseWriter, data interface{}, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *LibraryHandler) sendError(w http.ResponseWriter, message, code string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.APIError{
+		Error: message,
+		Code:  code,
+	})
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/meixg/podcast-reader/pkg/models"
+	"github.com/meixg/podcast-reader/pkg/opml"
+	"github.com/meixg/podcast-reader/web/services"
+)
+
+// maxOPMLUpload limits the size of imported OPML files
+const maxOPMLUpload = 8 << 20
+
+// OPMLHandler handles OPML import and export
+type OPMLHandler struct {
+	service *services.OPMLService
+}
+
+// NewOPMLHandler creates a new OPML handler
+func NewOPMLHandler(service *services.OPMLService) *OPMLHandler {
+	return &OPMLHandler{
+		service: service,
+	}
+}
+
+// HandleOPML handles GET /api/opml (export) and POST /api/opml (import)
+func (h *OPMLHandler) HandleOPML(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.exportOPML(w)
+	case http.MethodPost:
+		h.importOPML(w, r)
+	default:
+		h.sendError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+	}
+}
+
+// exportOPML sends the shows in the library as a downloadable OPML file
+func (h *OPMLHandler) exportOPML(w http.ResponseWriter) {
+	doc, err := h.service.Export()
+	if err != nil {
+		h.sendError(w, "Failed to export OPML", "SERVER_ERROR", http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		h.sendError(w, "Failed to export OPML", "SERVER_ERROR", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", opml.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="podcasts.opml"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// importOPML imports the OPML file in the request body, sent as is or as the
+// "file" field of a multipart form.
+// Supports ?mode=subscribe|download and ?latest=N
+func (h *OPMLHandler) importOPML(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := services.OPMLImportOptions{Mode: models.OPMLImportSubscribe}
+	switch mode := models.OPMLImportMode(query.Get("mode")); mode {
+	case "":
+	case models.OPMLImportSubscribe, models.OPMLImportDownload:
+		opts.Mode = mode
+	default:
+		h.sendError(w, "Invalid mode. Must be subscribe or download", "INVALID_PARAMETER", http.StatusBadRequest)
+		return
+	}
+	if value := query.Get("latest"); value != "" {
+		latest, err := strconv.Atoi(value)
+		if err != nil || latest < 0 {
+			h.sendError(w, "Invalid latest. Must be a non-negative number", "INVALID_PARAMETER", http.StatusBadRequest)
+			return
+		}
+		opts.Latest = latest
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxOPMLUpload)
+	var doc *opml.Document
+	var err error
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		var file multipart.File
+		if file, _, err = r.FormFile("file"); err == nil {
+			doc, err = opml.Parse(file)
+			file.Close()
+		}
+	} else {
+		doc, err = opml.Parse(r.Body)
+	}
+	if err != nil {
+		h.sendError(w, "Invalid OPML file", "INVALID_PARAMETER", http.StatusBadRequest)
+		return
+	}
+
+	h.sendJSON(w, h.service.Import(doc, opts), http.StatusOK)
+}
+
+func (h *OPMLHandler) sendJSON(w http.ResponseWriter, data interface{}, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *OPMLHandler) sendError(w http.ResponseWriter, message, code string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.APIError{
+		Error: message,
+		Code:  code,
+	})
+}
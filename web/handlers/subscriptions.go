@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/meixg/podcast-reader/pkg/downloader"
+	"github.com/meixg/podcast-reader/pkg/models"
+	"github.com/meixg/podcast-reader/pkg/rules"
+	"github.com/meixg/podcast-reader/web/services"
+)
+
+// SubscriptionHandler handles subscription-related HTTP requests
+type SubscriptionHandler struct {
+	service *services.SubscriptionService
+}
+
+// NewSubscriptionHandler creates a new subscription handler
+func NewSubscriptionHandler(service *services.SubscriptionService) *SubscriptionHandler {
+	return &SubscriptionHandler{
+		service: service,
+	}
+}
+
+// HandleSubscriptions handles GET and POST /api/subscriptions
+func (h *SubscriptionHandler) HandleSubscriptions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.sendJSON(w, h.service.List(), http.StatusOK)
+	case http.MethodPost:
+		h.createSubscription(w, r)
+	default:
+		h.sendError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleSubscription handles GET, PUT and DELETE /api/subscriptions/{id}
+// and POST /api/subscriptions/{id}/check|dry-run
+func (h *SubscriptionHandler) HandleSubscription(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/subscriptions/"), "/")
+	id, action, _ := strings.Cut(path, "/")
+	if id == "" {
+		h.sendError(w, "Subscription ID required", "INVALID_PARAMETER", http.StatusBadRequest)
+		return
+	}
+
+	if action != "" {
+		if r.Method != http.MethodPost {
+			h.sendError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+			return
+		}
+		switch action {
+		case "check":
+			// Failures are recorded in lastError; the subscription is returned either way
+			subscription, err := h.service.Check(r.Context(), id)
+			if subscription == nil {
+				h.sendServiceError(w, err)
+				return
+			}
+			h.sendJSON(w, subscription, http.StatusOK)
+		case "dry-run":
+			h.dryRunSubscription(w, r, id)
+		default:
+			h.sendError(w, "Unknown subscription action", "NOT_FOUND", http.StatusNotFound)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		subscription, err := h.service.Get(id)
+		if err != nil {
+			h.sendServiceError(w, err)
+			return
+		}
+		h.sendJSON(w, subscription, http.StatusOK)
+	case http.MethodPut, http.MethodPatch:
+		var req models.UpdateSubscriptionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.sendError(w, "Invalid request body", "INVALID_REQUEST", http.StatusBadRequest)
+			return
+		}
+		subscription, err := h.service.Update(id, req)
+		if err != nil {
+			h.sendServiceError(w, err)
+			return
+		}
+		h.sendJSON(w, subscription, http.StatusOK)
+	case http.MethodDelete:
+		if err := h.service.Delete(id); err != nil {
+			h.sendServiceError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		h.sendError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+	}
+}
+
+// createSubscription handles POST /api/subscriptions
+func (h *SubscriptionHandler) createSubscription(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, "Invalid request body", "INVALID_REQUEST", http.StatusBadRequest)
+		return
+	}
+	if req.URL == "" {
+		h.sendError(w, "URL is required", "INVALID_URL", http.StatusBadRequest)
+		return
+	}
+
+	subscription, err := h.service.Create(req)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+	h.sendJSON(w, subscription, http.StatusCreated)
+}
+
+// HandleDryRun handles POST /api/rules/dry-run, previewing which episodes of
+// a show a rule would download
+func (h *SubscriptionHandler) HandleDryRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.DryRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, "Invalid request body", "INVALID_REQUEST", http.StatusBadRequest)
+		return
+	}
+	if req.URL == "" {
+		h.sendError(w, "URL is required", "INVALID_URL", http.StatusBadRequest)
+		return
+	}
+
+	decisions, err := h.service.DryRun(r.Context(), req.URL, req.Rules)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+	h.sendJSON(w, decisions, http.StatusOK)
+}
+
+// dryRunSubscription handles POST /api/subscriptions/{id}/dry-run. Rules in
+// the request body are previewed instead of the subscription's own rules.
+func (h *SubscriptionHandler) dryRunSubscription(w http.ResponseWriter, r *http.Request, id string) {
+	subscription, err := h.service.Get(id)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+
+	var req models.DryRunRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			h.sendError(w, "Invalid request body", "INVALID_REQUEST", http.StatusBadRequest)
+			return
+		}
+	}
+	rule := subscription.Rules
+	if req.Rules != nil {
+		rule = req.Rules
+	}
+
+	decisions, err := h.service.DryRun(r.Context(), subscription.URL, rule)
+	if err != nil {
+		h.sendServiceError(w, err)
+		return
+	}
+	h.sendJSON(w, decisions, http.StatusOK)
+}
+
+// sendServiceError maps subscription service errors to HTTP responses
+func (h *SubscriptionHandler) sendServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrSubscriptionNotFound):
+		h.sendError(w, "Subscription not found", "NOT_FOUND", http.StatusNotFound)
+	case errors.Is(err, services.ErrSubscriptionExists):
+		h.sendError(w, "Already subscribed to this URL", "DUPLICATE_SUBSCRIPTION", http.StatusConflict)
+	case errors.Is(err, services.ErrNotAShow):
+		h.sendError(w, "URL must be a show page or feed", "INVALID_URL", http.StatusBadRequest)
+	case errors.Is(err, services.ErrInvalidPollInterval), errors.Is(err, rules.ErrInvalidRule):
+		h.sendError(w, err.Error(), "INVALID_PARAMETER", http.StatusBadRequest)
+	case errors.Is(err, downloader.ErrListingNotSupported):
+		h.sendError(w, "This source cannot list show episodes", "INVALID_URL", http.StatusBadRequest)
+	case errors.Is(err, downloader.ErrPageNotFound), errors.Is(err, downloader.ErrInvalidFeed):
+		h.sendError(w, "Failed to list show episodes", "UPSTREAM_ERROR", http.StatusBadGateway)
+	default:
+		h.sendError(w, "Failed to update subscriptions", "SERVER_ERROR", http.StatusInternalServerError)
+	}
+}
+
+// Helper methods
+func (h *SubscriptionHandler) sendJSON(w http.ResponseWriter, data interface{}, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (h *SubscriptionHandler) sendError(w http.ResponseWriter, message, code string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.APIError{
+		Error: message,
+		Code:  code,
+	})
+}
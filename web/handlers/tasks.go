@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/meixg/podcast-reader/pkg/downloader"
 	"github.com/meixg/podcast-reader/pkg/models"
 	"github.com/meixg/podcast-reader/web/services"
 )
@@ -33,6 +36,62 @@ func (h *TaskHandler) HandleTasks(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// HandleTask handles GET /api/tasks/{id} and POST /api/tasks/{id}/{action}
+func (h *TaskHandler) HandleTask(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/tasks/"), "/")
+	taskID, action, _ := strings.Cut(path, "/")
+	if taskID == "" {
+		h.sendError(w, "Task ID required", "INVALID_PARAMETER", http.StatusBadRequest)
+		return
+	}
+
+	if action == "" {
+		if r.Method != http.MethodGet {
+			h.sendError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+			return
+		}
+		task, err := h.service.GetTask(taskID)
+		if err != nil {
+			h.sendError(w, "Task not found", "NOT_FOUND", http.StatusNotFound)
+			return
+		}
+		h.sendJSON(w, task, http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		h.sendError(w, "Method not allowed", "METHOD_NOT_ALLOWED", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var task *models.DownloadTask
+	var err error
+	switch action {
+	case "cancel":
+		task, err = h.service.CancelTask(taskID)
+	case "pause":
+		task, err = h.service.PauseTask(taskID)
+	case "resume":
+		task, err = h.service.ResumeTask(taskID)
+	case "retry":
+		task, err = h.service.RetryTask(taskID)
+	default:
+		h.sendError(w, "Unknown task action", "NOT_FOUND", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case errors.Is(err, services.ErrTaskNotFound):
+		h.sendError(w, "Task not found", "NOT_FOUND", http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidTaskState):
+		h.sendError(w, "Cannot "+action+" task in its current state", "INVALID_STATE", http.StatusConflict)
+	case err != nil:
+		h.sendError(w, "Failed to update task", "SERVER_ERROR", http.StatusInternalServerError)
+	default:
+		h.sendJSON(w, task, http.StatusOK)
+	}
+}
+
 // getTasks handles GET /api/tasks
 func (h *TaskHandler) getTasks(w http.ResponseWriter, r *http.Request) {
 	tasks := h.service.GetTasks()
@@ -53,15 +112,18 @@ func (h *TaskHandler) createTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !strings.Contains(req.URL, "xiaoyuzhoufm.com") {
-		h.sendError(w, "Invalid URL format. Must be a Xiaoyuzhou FM URL", "INVALID_URL", http.StatusBadRequest)
+	// Show URLs fan out into one task per episode
+	if h.service.IsShowURL(req.URL) {
+		h.createBatch(w, r, &req)
 		return
 	}
 
-	// Create task
-	task, err := h.service.CreateTask(req.URL)
+	// Create task; the URL is validated by looking up its source
+	task, err := h.service.CreateTaskWithPriority(req.URL, req.Priority)
 	if err != nil {
-		if strings.Contains(err.Error(), "already exists") {
+		if errors.Is(err, downloader.ErrUnsupportedURL) {
+			h.sendError(w, "Unsupported URL: no source can download it", "INVALID_URL", http.StatusBadRequest)
+		} else if strings.Contains(err.Error(), "already exists") {
 			h.sendError(w, "A download task for this URL already exists", "DUPLICATE_TASK", http.StatusBadRequest)
 		} else {
 			h.sendError(w, "Failed to create task", "SERVER_ERROR", http.StatusInternalServerError)
@@ -72,6 +134,66 @@ func (h *TaskHandler) createTask(w http.ResponseWriter, r *http.Request) {
 	h.sendJSON(w, task, http.StatusCreated)
 }
 
+// createBatch creates a batch task for all selected episodes of a show
+func (h *TaskHandler) createBatch(w http.ResponseWriter, r *http.Request, req *models.CreateTaskRequest) {
+	if req.Latest < 0 {
+		h.sendError(w, "latest must not be negative", "INVALID_REQUEST", http.StatusBadRequest)
+		return
+	}
+	since, err := parseDate(req.Since, false)
+	if err != nil {
+		h.sendError(w, "Invalid since date, expected YYYY-MM-DD or RFC 3339", "INVALID_REQUEST", http.StatusBadRequest)
+		return
+	}
+	until, err := parseDate(req.Until, true)
+	if err != nil {
+		h.sendError(w, "Invalid until date, expected YYYY-MM-DD or RFC 3339", "INVALID_REQUEST", http.StatusBadRequest)
+		return
+	}
+
+	opts := services.BatchOptions{
+		Filter: downloader.EpisodeFilter{
+			Latest: req.Latest,
+			Since:  since,
+			Until:  until,
+		},
+		SkipExisting: req.SkipExisting == nil || *req.SkipExisting,
+		Priority:     req.Priority,
+	}
+	task, err := h.service.CreateBatch(r.Context(), req.URL, opts)
+	if err != nil {
+		if errors.Is(err, downloader.ErrListingNotSupported) {
+			h.sendError(w, "This source cannot list show episodes", "INVALID_URL", http.StatusBadRequest)
+		} else if strings.Contains(err.Error(), "already exists") {
+			h.sendError(w, "A download task for this URL already exists", "DUPLICATE_TASK", http.StatusBadRequest)
+		} else {
+			h.sendError(w, "Failed to list show episodes", "UPSTREAM_ERROR", http.StatusBadGateway)
+		}
+		return
+	}
+
+	h.sendJSON(w, task, http.StatusCreated)
+}
+
+// parseDate parses a YYYY-MM-DD or RFC 3339 date. Plain dates are taken as
+// the start of the day, or the end of it for inclusive upper bounds.
+func parseDate(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
 // Helper methods
 func (h *TaskHandler) sendJSON(w http.ResponseWriter, data interface{}, status int) {
 	w.Header().Set("Content-Type", "application/json")
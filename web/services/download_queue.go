@@ -0,0 +1,185 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// QueueOrder controls the order in which queued tasks are started
+type QueueOrder string
+
+const (
+	// QueueOrderFIFO starts tasks in submission order
+	QueueOrderFIFO QueueOrder = "fifo"
+	// QueueOrderPriority starts higher priority tasks first, FIFO among equals
+	QueueOrderPriority QueueOrder = "priority"
+)
+
+// DefaultConcurrency is the number of downloads that run at the same time by default
+const DefaultConcurrency = 3
+
+// QueueStats describes the current state of the download queue
+type QueueStats struct {
+	Concurrency int        `json:"concurrency"`
+	Order       QueueOrder `json:"order"`
+	Running     int        `json:"running"`
+	Queued      int        `json:"queued"`
+}
+
+// queuedJob is a task waiting for a free worker slot
+type queuedJob struct {
+	taskID   string
+	url      string
+	priority int
+	seq      uint64
+}
+
+// DownloadQueue runs download jobs with bounded concurrency
+type DownloadQueue struct {
+	mu          sync.Mutex
+	pending     []*queuedJob
+	running     map[*queuedJob]bool
+	concurrency int
+	order       QueueOrder
+	seq         uint64
+	run         func(taskID, url string)
+}
+
+// NewDownloadQueue creates a queue that calls run for each job, at most concurrency at a time
+func NewDownloadQueue(concurrency int, order QueueOrder, run func(taskID, url string)) *DownloadQueue {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if order != QueueOrderFIFO {
+		order = QueueOrderPriority
+	}
+	return &DownloadQueue{
+		running:     make(map[*queuedJob]bool),
+		concurrency: concurrency,
+		order:       order,
+		run:         run,
+	}
+}
+
+// Enqueue adds a job and starts it immediately if a worker slot is free
+func (q *DownloadQueue) Enqueue(taskID, url string, priority int) {
+	q.mu.Lock()
+	q.seq++
+	q.pending = append(q.pending, &queuedJob{
+		taskID:   taskID,
+		url:      url,
+		priority: priority,
+		seq:      q.seq,
+	})
+	q.mu.Unlock()
+
+	q.dispatch()
+}
+
+// Remove drops a job that has not started yet. Returns false if it was not queued.
+func (q *DownloadQueue) Remove(taskID string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, job := range q.pending {
+		if job.taskID == taskID {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Positions returns the 1-based queue position of every waiting task
+func (q *DownloadQueue) Positions() map[string]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.sortPending()
+	positions := make(map[string]int, len(q.pending))
+	for i, job := range q.pending {
+		positions[job.taskID] = i + 1
+	}
+	return positions
+}
+
+// SetConcurrency changes the number of parallel downloads at runtime.
+// Running jobs are never interrupted; lowering the limit takes effect as they finish.
+func (q *DownloadQueue) SetConcurrency(concurrency int) error {
+	if concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1")
+	}
+
+	q.mu.Lock()
+	q.concurrency = concurrency
+	q.mu.Unlock()
+
+	q.dispatch()
+	return nil
+}
+
+// SetOrder changes how waiting jobs are ordered
+func (q *DownloadQueue) SetOrder(order QueueOrder) error {
+	if order != QueueOrderFIFO && order != QueueOrderPriority {
+		return fmt.Errorf("unknown queue order: %s", order)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.order = order
+	return nil
+}
+
+// Stats returns a snapshot of the queue state
+func (q *DownloadQueue) Stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return QueueStats{
+		Concurrency: q.concurrency,
+		Order:       q.order,
+		Running:     len(q.running),
+		Queued:      len(q.pending),
+	}
+}
+
+// dispatch starts waiting jobs while worker slots are free
+func (q *DownloadQueue) dispatch() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.sortPending()
+	for len(q.running) < q.concurrency && len(q.pending) > 0 {
+		job := q.pending[0]
+		q.pending = q.pending[1:]
+		q.running[job] = true
+
+		go func(job *queuedJob) {
+			defer q.finish(job)
+			q.run(job.taskID, job.url)
+		}(job)
+	}
+}
+
+// finish releases the worker slot of a completed job. Jobs are tracked by
+// identity, since a task resumed while its previous run is still unwinding
+// is queued again under the same ID.
+func (q *DownloadQueue) finish(job *queuedJob) {
+	q.mu.Lock()
+	delete(q.running, job)
+	q.mu.Unlock()
+
+	q.dispatch()
+}
+
+// sortPending orders waiting jobs according to the queue order. Callers must hold q.mu.
+func (q *DownloadQueue) sortPending() {
+	sort.SliceStable(q.pending, func(i, j int) bool {
+		a, b := q.pending[i], q.pending[j]
+		if q.order == QueueOrderPriority && a.priority != b.priority {
+			return a.priority > b.priority
+		}
+		return a.seq < b.seq
+	})
+}
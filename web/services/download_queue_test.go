@@ -0,0 +1,122 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDownloadQueue_BoundsConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	release := make(chan struct{})
+	started := make(chan struct{}, 5)
+	var wg sync.WaitGroup
+
+	q := NewDownloadQueue(2, QueueOrderFIFO, func(taskID, url string) {
+		defer wg.Done()
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		started <- struct{}{}
+
+		<-release
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+
+	wg.Add(5)
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		q.Enqueue(id, "", 0)
+	}
+
+	if stats := q.Stats(); stats.Running != 2 || stats.Queued != 3 {
+		t.Errorf("stats = %+v, want 2 running and 3 queued", stats)
+	}
+	if pos := q.Positions(); pos["c"] != 1 || pos["e"] != 3 {
+		t.Errorf("positions = %v, want c=1 e=3", pos)
+	}
+
+	<-started
+	<-started
+	close(release)
+	wg.Wait()
+
+	if maxRunning != 2 {
+		t.Errorf("max concurrent = %d, want 2", maxRunning)
+	}
+}
+
+func TestDownloadQueue_PriorityOrder(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	block := make(chan struct{})
+	var wg sync.WaitGroup
+
+	q := NewDownloadQueue(1, QueueOrderPriority, func(taskID, url string) {
+		defer wg.Done()
+		if taskID == "first" {
+			<-block
+		}
+		mu.Lock()
+		order = append(order, taskID)
+		mu.Unlock()
+	})
+
+	wg.Add(4)
+	q.Enqueue("first", "", 0)
+	q.Enqueue("low", "", 0)
+	q.Enqueue("high", "", 10)
+	q.Enqueue("mid", "", 5)
+
+	if !q.Remove("mid") {
+		t.Error("Remove(mid) = false, want true")
+	}
+	wg.Done()
+
+	close(block)
+	wg.Wait()
+
+	want := []string{"first", "high", "low"}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestDownloadQueue_SetConcurrencyStartsWaitingJobs(t *testing.T) {
+	started := make(chan string, 3)
+	block := make(chan struct{})
+	q := NewDownloadQueue(1, QueueOrderFIFO, func(taskID, url string) {
+		started <- taskID
+		<-block
+	})
+	defer close(block)
+
+	q.Enqueue("a", "", 0)
+	q.Enqueue("b", "", 0)
+	<-started
+
+	if err := q.SetConcurrency(0); err == nil {
+		t.Error("SetConcurrency(0) should fail")
+	}
+	q.SetConcurrency(2)
+
+	select {
+	case id := <-started:
+		if id != "b" {
+			t.Errorf("started %s, want b", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("raising concurrency did not start the waiting job")
+	}
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -12,19 +13,26 @@ import (
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/meixg/podcast-reader/pkg/downloader"
-	"github.com/meixg/podcast-reader/pkg/models"
+	"github.com/meixg/podcast-reader/pkg/scanner"
+	"github.com/meixg/podcast-reader/pkg/thumbnail"
 )
 
 // DownloadService handles the complete podcast download workflow
 type DownloadService struct {
-	downloadsDir      string
-	httpClient        *http.Client
-	urlExtractor      downloader.URLExtractor
-	fileDownloader    downloader.FileDownloader
-	imageDownloader   downloader.ImageDownloader
-	metadataExtractor *downloader.MetadataExtractor
-	metadataWriter    *downloader.MetadataWriter
-	taskService       *TaskService
+	downloadsDir    string
+	httpClient      *http.Client
+	sources         *downloader.Registry
+	fileDownloader  downloader.FileDownloader
+	imageDownloader downloader.ImageDownloader
+	metadataWriter  *downloader.MetadataWriter
+	taskService     *TaskService
+	indexer         EpisodeIndexer
+}
+
+// EpisodeIndexer adds finished downloads to the episode library;
+// implemented by LibraryService
+type EpisodeIndexer interface {
+	IndexEpisode(audioPath string) (string, error)
 }
 
 // NewDownloadService creates a new download service
@@ -43,79 +51,91 @@ func NewDownloadService(downloadsDir string, taskService *TaskService) *Download
 		Timeout: 2 * time.Minute,
 	}
 
-	// Create HTTP client wrapper for goquery (used for metadata extraction)
-	httpClientWrapper := &httpClientDoer{client: metadataClient}
-
 	return &DownloadService{
-		downloadsDir:      downloadsDir,
-		httpClient:        metadataClient,
-		urlExtractor:      downloader.NewHTMLExtractor(httpClientWrapper),
-		fileDownloader:    downloader.NewHTTPDownloader(downloadClient, false),
-		imageDownloader:   downloader.NewHTTPImageDownloader(imageClient, 10*1024*1024), // 10MB max
-		metadataExtractor: downloader.NewMetadataExtractor(httpClientWrapper),
-		metadataWriter:    downloader.NewMetadataWriter(),
-		taskService:       taskService,
+		downloadsDir:    downloadsDir,
+		httpClient:      metadataClient,
+		sources:         downloader.NewDefaultRegistry(metadataClient),
+		fileDownloader:  downloader.NewHTTPDownloader(downloadClient, false),
+		imageDownloader: downloader.NewHTTPImageDownloader(imageClient, 10*1024*1024), // 10MB max
+		metadataWriter:  downloader.NewMetadataWriter(),
+		taskService:     taskService,
 	}
 }
 
-// httpClientDoer wraps http.Client to implement the Doer interface
-type httpClientDoer struct {
-	client *http.Client
-}
-
-// Get implements the Doer interface for goquery
-func (h *httpClientDoer) Get(url string) (*goquery.Document, error) {
-	resp, err := h.client.Get(url)
-	if err != nil {
-		return nil, err
+// SetConnections sets how many parallel range requests are used per audio download
+func (s *DownloadService) SetConnections(connections int) {
+	downloadClient := &http.Client{
+		Timeout: 30 * time.Minute,
 	}
-	defer resp.Body.Close()
+	s.fileDownloader = downloader.NewFileDownloader(downloadClient, connections, false)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, url)
-	}
+// SetEpisodeIndexer sets the library finished downloads are added to
+func (s *DownloadService) SetEpisodeIndexer(indexer EpisodeIndexer) {
+	s.indexer = indexer
+}
 
-	return goquery.NewDocumentFromReader(resp.Body)
+// Sources returns the registry used to resolve episode URLs
+func (s *DownloadService) Sources() *downloader.Registry {
+	return s.sources
 }
 
-// ExecuteDownload executes the complete download workflow for a task
+// ExecuteDownload executes the complete download workflow for a task.
+// Failures that look temporary are retried according to the task service's retry policy.
+// The workflow stops after the current step once ctx is cancelled.
 func (s *DownloadService) ExecuteDownload(ctx context.Context, taskID, url string) {
 	// Update task status to downloading
-	s.taskService.UpdateProgress(taskID, 0)
+	s.taskService.UpdateProgress(ctx, taskID, 0)
 
 	// Step 1: Extract metadata (30% progress)
 	metadata, err := s.extractMetadata(ctx, url)
 	if err != nil {
-		s.taskService.MarkFailed(taskID, fmt.Sprintf("提取元数据失败: %v", err))
+		if s.interrupted(ctx, "") {
+			return
+		}
+		s.taskService.RetryOrFail(ctx, taskID, fmt.Sprintf("提取元数据失败: %v", err), err)
+		return
+	}
+	if s.interrupted(ctx, "") {
 		return
 	}
-	s.taskService.UpdateProgress(taskID, 30)
+	s.taskService.UpdateProgress(ctx, taskID, 30)
 
 	// Step 2: Create podcast directory (40% progress)
 	podcastDir, err := s.createPodcastDir(metadata.Title)
 	if err != nil {
-		s.taskService.MarkFailed(taskID, fmt.Sprintf("创建目录失败: %v", err))
+		s.taskService.RetryOrFail(ctx, taskID, fmt.Sprintf("创建目录失败: %v", err), err)
+		return
+	}
+	if s.interrupted(ctx, "") {
 		return
 	}
-	s.taskService.UpdateProgress(taskID, 40)
+	s.taskService.UpdateProgress(ctx, taskID, 40)
 
 	// Step 3: Download audio file (40-90% progress)
-	audioPath := filepath.Join(podcastDir, "podcast.m4a")
+	audioPath := filepath.Join(podcastDir, "podcast"+metadata.AudioExtension())
+	s.taskService.SetFilePath(taskID, audioPath)
 	err = s.downloadAudio(ctx, metadata.AudioURL, audioPath, taskID)
 	if err != nil {
-		s.taskService.MarkFailed(taskID, fmt.Sprintf("下载音频失败: %v", err))
+		if s.interrupted(ctx, audioPath) {
+			return
+		}
+		s.taskService.RetryOrFail(ctx, taskID, fmt.Sprintf("下载音频失败: %v", err), err)
 		return
 	}
-	s.taskService.UpdateProgress(taskID, 90)
+	s.taskService.UpdateProgress(ctx, taskID, 90)
 
-	// Step 4: Download cover image (95% progress)
-	if metadata.CoverURL != "" {
-		coverPath := filepath.Join(podcastDir, "cover.jpg")
-		if err := s.downloadCover(ctx, metadata.CoverURL, coverPath); err != nil {
+	// Step 4: Download cover image at the largest size offered (95% progress)
+	var coverPath string
+	if coverURL := metadata.CoverDownloadURL(); coverURL != "" {
+		if coverPath, err = s.downloadCover(ctx, coverURL, podcastDir); err != nil {
 			log.Printf("Warning: Failed to download cover: %v", err)
 		}
 	}
-	s.taskService.UpdateProgress(taskID, 95)
+	if s.interrupted(ctx, "") {
+		return
+	}
+	s.taskService.UpdateProgress(ctx, taskID, 95)
 
 	// Step 5: Save show notes (95% progress)
 	if metadata.ShowNotes != "" {
@@ -124,24 +144,59 @@ func (s *DownloadService) ExecuteDownload(ctx context.Context, taskID, url strin
 			log.Printf("Warning: Failed to save show notes: %v", err)
 		}
 	}
-	s.taskService.UpdateProgress(taskID, 95)
+	if s.interrupted(ctx, "") {
+		return
+	}
+	s.taskService.UpdateProgress(ctx, taskID, 95)
+
+	// Step 6: Tag the audio file with title, podcast and cover
+	// Continue even if tagging fails; the file is still playable
+	if err := downloader.TagAudio(audioPath, coverPath, metadata); err != nil {
+		log.Printf("Warning: Failed to tag audio: %v", err)
+	}
+	if s.interrupted(ctx, "") {
+		return
+	}
 
-	// Step 6: Extract and save metadata (98% progress)
-	// Continue even if metadata extraction fails
-	s.taskService.UpdateTaskStatus(taskID, "extracting_metadata")
-	if err := s.extractAndSaveMetadata(ctx, url, podcastDir); err != nil {
-		log.Printf("Warning: Failed to extract metadata: %v", err)
-		// Continue - metadata extraction failure doesn't block download
+	// Step 7: Save metadata resolved with the episode (98% progress)
+	// Continue even if writing the metadata fails
+	s.taskService.UpdateTaskStatus(ctx, taskID, "extracting_metadata")
+	if err := s.saveMetadata(url, metadata, podcastDir); err != nil {
+		log.Printf("Warning: Failed to save metadata: %v", err)
+		// Continue - metadata failure doesn't block download
 	}
-	s.taskService.UpdateProgress(taskID, 98)
+	s.taskService.UpdateProgress(ctx, taskID, 98)
 
-	// Step 7: Mark as completed (100% progress)
-	s.taskService.MarkCompleted(taskID, "")
-	s.taskService.UpdateProgress(taskID, 100)
+	// Step 8: Add the episode to the library and mark as completed (100% progress)
+	if s.interrupted(ctx, "") {
+		return
+	}
+	var episodeID string
+	if s.indexer != nil {
+		if episodeID, err = s.indexer.IndexEpisode(audioPath); err != nil {
+			log.Printf("Warning: Failed to add episode to library: %v", err)
+		}
+	}
+	s.taskService.MarkCompleted(ctx, taskID, episodeID)
 
 	log.Printf("Download completed: %s -> %s", metadata.Title, podcastDir)
 }
 
+// interrupted reports whether the task was paused or cancelled through the API.
+// A cancelled task's partial audio file is removed; a paused one keeps it for resuming.
+func (s *DownloadService) interrupted(ctx context.Context, audioPath string) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+
+	if errors.Is(context.Cause(ctx), ErrTaskCancelled) && audioPath != "" {
+		if err := downloader.RemovePartial(audioPath); err != nil {
+			log.Printf("Warning: Failed to remove partial download %s: %v", audioPath, err)
+		}
+	}
+	return true
+}
+
 // IsAlreadyDownloaded checks if an episode has already been downloaded
 func (s *DownloadService) IsAlreadyDownloaded(url string) (bool, error) {
 	// Extract metadata to get the title
@@ -157,8 +212,8 @@ func (s *DownloadService) IsAlreadyDownloaded(url string) (bool, error) {
 	safeTitle := sanitizeFilename(metadata.Title)
 	podcastDir := filepath.Join(s.downloadsDir, safeTitle)
 
-	// Check if directory exists and contains podcast.m4a
-	audioPath := filepath.Join(podcastDir, "podcast.m4a")
+	// Check if directory exists and contains the audio file
+	audioPath := filepath.Join(podcastDir, "podcast"+metadata.AudioExtension())
 	if _, err := os.Stat(audioPath); err == nil {
 		return true, nil
 	}
@@ -166,9 +221,64 @@ func (s *DownloadService) IsAlreadyDownloaded(url string) (bool, error) {
 	return false, nil
 }
 
-// extractMetadata extracts episode metadata from the URL
+// ListShow lists the episodes of a show with the source that handles the URL.
+// Shows whose source lists only the newest episodes return those.
+func (s *DownloadService) ListShow(ctx context.Context, showURL string) ([]*downloader.EpisodeMetadata, downloader.Source, error) {
+	episodes, source, _, err := s.listShow(ctx, showURL)
+	return episodes, source, err
+}
+
+// listShow is ListShow that also returns how many older episodes of the show
+// its source couldn't list
+func (s *DownloadService) listShow(ctx context.Context, showURL string) ([]*downloader.EpisodeMetadata, downloader.Source, int, error) {
+	episodes, source, err := s.sources.List(ctx, showURL)
+	var truncated *downloader.TruncatedListError
+	if errors.As(err, &truncated) {
+		log.Printf("Warning: %s: %v", showURL, err)
+		return episodes, source, truncated.Total - truncated.Listed, nil
+	}
+	if err != nil {
+		return nil, source, 0, fmt.Errorf("failed to list episodes: %w", err)
+	}
+	return episodes, source, 0, nil
+}
+
+// downloadedFilter returns a function reporting whether a listed episode is
+// already in the downloads directory, either by the source URL recorded in
+// .metadata.json or by an existing audio file in the episode's directory
+func (s *DownloadService) downloadedFilter() func(*downloader.EpisodeMetadata) bool {
+	sourceURLs := make(map[string]bool)
+	metadataScanner := scanner.NewMetadataScanner()
+	entries, err := os.ReadDir(s.downloadsDir)
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: Failed to read downloads directory: %v", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		metadata, err := metadataScanner.ReadMetadata(filepath.Join(s.downloadsDir, entry.Name()))
+		if err == nil && metadata != nil && metadata.SourceURL != "" {
+			sourceURLs[metadata.SourceURL] = true
+		}
+	}
+
+	return func(episode *downloader.EpisodeMetadata) bool {
+		if sourceURLs[episode.PageURL] {
+			return true
+		}
+		if episode.Title == "" {
+			return false
+		}
+		audioPath := filepath.Join(s.downloadsDir, sanitizeFilename(episode.Title), "podcast"+episode.AudioExtension())
+		_, err := os.Stat(audioPath)
+		return err == nil
+	}
+}
+
+// extractMetadata resolves episode metadata with the source that handles the URL
 func (s *DownloadService) extractMetadata(ctx context.Context, url string) (*downloader.EpisodeMetadata, error) {
-	metadata, err := s.urlExtractor.ExtractURL(ctx, url)
+	metadata, _, err := s.sources.Resolve(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract metadata: %w", err)
 	}
@@ -190,15 +300,21 @@ func (s *DownloadService) createPodcastDir(title string) (string, error) {
 
 // downloadAudio downloads the audio file with progress tracking
 func (s *DownloadService) downloadAudio(ctx context.Context, audioURL, destPath string, taskID string) error {
-	// Create a progress writer that updates the task
-	progressWriter := &taskProgressWriter{
-		taskService: s.taskService,
-		taskID:      taskID,
-		minProgress: 40,
-		maxProgress: 90,
+	// Map byte progress onto the 40-90% band of the task
+	const minProgress, maxProgress = 40, 90
+	progressFunc := func(p downloader.Progress) {
+		progress := minProgress
+		if percent := p.Percent(); percent >= 0 {
+			progress = minProgress + int(percent*float64(maxProgress-minProgress))
+		}
+		s.taskService.UpdateTransfer(ctx, taskID, progress, p.Downloaded, p.Total, p.Rate, p.Remaining())
+	}
+
+	if downloader.HasPartial(destPath) {
+		log.Printf("Resuming partial download: %s", destPath)
 	}
 
-	bytesWritten, err := s.fileDownloader.Download(ctx, audioURL, destPath, progressWriter)
+	bytesWritten, err := s.fileDownloader.Download(ctx, audioURL, destPath, progressFunc)
 	if err != nil {
 		return err
 	}
@@ -213,15 +329,19 @@ func (s *DownloadService) downloadAudio(ctx context.Context, audioURL, destPath
 	return nil
 }
 
-// downloadCover downloads the cover image
-func (s *DownloadService) downloadCover(ctx context.Context, coverURL, destPath string) error {
-	_, err := s.imageDownloader.Download(ctx, coverURL, destPath, nil)
+// downloadCover downloads the cover image and its thumbnails
+func (s *DownloadService) downloadCover(ctx context.Context, coverURL, podcastDir string) (string, error) {
+	coverPath, err := s.imageDownloader.DownloadCover(ctx, coverURL, podcastDir, nil)
 	if err != nil {
-		return err
+		return "", err
 	}
+	log.Printf("Downloaded cover: %s", coverPath)
 
-	log.Printf("Downloaded cover: %s", destPath)
-	return nil
+	// Thumbnails are regenerated on request if this fails
+	if err := thumbnail.GenerateAll(coverPath); err != nil && !errors.Is(err, thumbnail.ErrUnsupportedFormat) {
+		log.Printf("Warning: Failed to generate thumbnails: %v", err)
+	}
+	return coverPath, nil
 }
 
 // saveShowNotes saves show notes to a text file
@@ -240,28 +360,6 @@ func (s *DownloadService) saveShowNotes(htmlContent, destPath string) error {
 	return nil
 }
 
-// taskProgressWriter writes progress updates to the task
-type taskProgressWriter struct {
-	taskService *TaskService
-	taskID      string
-	minProgress int
-	maxProgress int
-	totalBytes  int64
-	written     int64
-}
-
-func (w *taskProgressWriter) Write(p []byte) (int, error) {
-	n := len(p)
-	w.written += int64(n)
-
-	// Calculate progress percentage based on expected size
-	// Since we don't know the total size upfront, we'll just increment
-	progress := w.minProgress + (w.maxProgress-w.minProgress)/2 // Placeholder
-	w.taskService.UpdateProgress(w.taskID, progress)
-
-	return n, nil
-}
-
 // sanitizeFilename removes invalid characters from filenames
 func sanitizeFilename(name string) string {
 	// Remove invalid characters
@@ -304,15 +402,9 @@ func convertHTMLToText(html string) string {
 	return strings.Join(cleanedLines, "\n\n")
 }
 
-// extractAndSaveMetadata extracts metadata from the page and saves it to .metadata.json
-func (s *DownloadService) extractAndSaveMetadata(ctx context.Context, pageURL, podcastDir string) error {
-	// Extract metadata from page
-	metadata, err := s.metadataExtractor.ExtractMetadata(ctx, pageURL)
-	if err != nil {
-		// Log warning but don't fail - write empty metadata file
-		log.Printf("Warning: Metadata extraction failed for %s: %v", pageURL, err)
-		metadata = models.NewPodcastMetadata()
-	}
+// saveMetadata saves the episode's metadata to .metadata.json
+func (s *DownloadService) saveMetadata(pageURL string, episode *downloader.EpisodeMetadata, podcastDir string) error {
+	metadata := episode.ToPodcastMetadata()
 
 	// Save the original page URL
 	metadata.SourceURL = pageURL
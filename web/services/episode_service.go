@@ -1,40 +1,75 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"sort"
+	"time"
 
+	"github.com/meixg/podcast-reader/pkg/downloader"
 	"github.com/meixg/podcast-reader/pkg/models"
-	"github.com/meixg/podcast-reader/pkg/scanner"
+	"github.com/meixg/podcast-reader/pkg/search"
+	"github.com/meixg/podcast-reader/pkg/thumbnail"
+)
+
+var (
+	// ErrEpisodeNotFound is returned for episode IDs that are not in the library
+	ErrEpisodeNotFound = errors.New("episode not found")
+	// ErrCoverNotFound is returned for episodes downloaded without a cover image
+	ErrCoverNotFound = errors.New("cover not found")
 )
 
 // EpisodeService manages episode operations
 type EpisodeService struct {
-	scanner         *scanner.Scanner
-	metadataScanner *scanner.MetadataScanner
+	library *LibraryService
 }
 
-// NewEpisodeService creates a new episode service
-func NewEpisodeService(s *scanner.Scanner) *EpisodeService {
+// NewEpisodeService creates a new episode service serving the episodes of library
+func NewEpisodeService(library *LibraryService) *EpisodeService {
 	return &EpisodeService{
-		scanner:         s,
-		metadataScanner: scanner.NewMetadataScanner(),
+		library: library,
 	}
 }
 
+// EpisodeSort selects the date episodes are ordered by
+type EpisodeSort string
+
+const (
+	SortByDownloadDate EpisodeSort = "downloadDate"
+	SortByPublishDate  EpisodeSort = "publishDate"
+	SortByRelevance    EpisodeSort = "relevance" // Best search matches first
+)
+
+// EpisodeListOptions sorts and filters the episode list
+type EpisodeListOptions struct {
+	Sort      EpisodeSort // Defaults to SortByDownloadDate
+	Ascending bool        // Oldest first instead of newest first
+	// Since and Until limit episodes to a publish date range; zero means
+	// unbounded. Episodes without a publish date are left out when either is set.
+	Since time.Time
+	Until time.Time
+	// Query limits episodes to those matching a full-text search of the
+	// title, podcast name and show notes
+	Query string
+}
+
 // GetEpisodes returns paginated episodes
-func (s *EpisodeService) GetEpisodes(page, pageSize int) (*models.PaginatedEpisodes, error) {
-	// Scan all episodes
-	episodes, err := s.scanner.ScanEpisodes()
+func (s *EpisodeService) GetEpisodes(page, pageSize int, opts EpisodeListOptions) (*models.PaginatedEpisodes, error) {
+	episodes, err := s.library.Episodes()
 	if err != nil {
-		return nil, fmt.Errorf("failed to scan episodes: %w", err)
+		return nil, fmt.Errorf("failed to list episodes: %w", err)
 	}
 
-	// Sort by download date (newest first)
-	sort.Slice(episodes, func(i, j int) bool {
-		return episodes[i].DownloadDate.After(episodes[j].DownloadDate)
-	})
+	episodes = filterByPublishDate(episodes, opts.Since, opts.Until)
+	if opts.Query != "" {
+		results, err := s.library.Search(opts.Query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search episodes: %w", err)
+		}
+		episodes = filterByQuery(episodes, results)
+	}
+	sortEpisodes(episodes, opts)
 
 	total := len(episodes)
 	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
@@ -66,18 +101,141 @@ func (s *EpisodeService) GetEpisodes(page, pageSize int) (*models.PaginatedEpiso
 	}, nil
 }
 
+// filterByPublishDate keeps the episodes published within [since, until]
+func filterByPublishDate(episodes []models.DownloadedEpisode, since, until time.Time) []models.DownloadedEpisode {
+	if since.IsZero() && until.IsZero() {
+		return episodes
+	}
+	filtered := episodes[:0]
+	for _, episode := range episodes {
+		published := episode.PublishedAt
+		if published == nil ||
+			!since.IsZero() && published.Before(since) ||
+			!until.IsZero() && published.After(until) {
+			continue
+		}
+		filtered = append(filtered, episode)
+	}
+	return filtered
+}
+
+// sortEpisodes orders episodes newest first, or oldest first if ascending.
+// When sorting by publish date, episodes without one come last and ties are
+// broken by download date. Sorting by relevance puts the best search matches
+// first, newest first among equal ones.
+func sortEpisodes(episodes []models.DownloadedEpisode, opts EpisodeListOptions) {
+	newer := func(a, b time.Time) bool {
+		if opts.Ascending {
+			return a.Before(b)
+		}
+		return a.After(b)
+	}
+	sort.SliceStable(episodes, func(i, j int) bool {
+		a, b := episodes[i], episodes[j]
+		if opts.Sort == SortByRelevance && a.Match != nil && b.Match != nil && a.Match.Score != b.Match.Score {
+			return a.Match.Score > b.Match.Score
+		}
+		if opts.Sort == SortByPublishDate {
+			switch {
+			case a.PublishedAt == nil && b.PublishedAt == nil:
+			case a.PublishedAt == nil:
+				return false
+			case b.PublishedAt == nil:
+				return true
+			case !a.PublishedAt.Equal(*b.PublishedAt):
+				return newer(*a.PublishedAt, *b.PublishedAt)
+			}
+		}
+		return newer(a.DownloadDate, b.DownloadDate)
+	})
+}
+
 // GetShowNotes returns show notes for a specific episode
 func (s *EpisodeService) GetShowNotes(episodeID string) (string, error) {
-	episodes, err := s.scanner.ScanEpisodes()
+	episode, err := s.getEpisode(episodeID)
 	if err != nil {
-		return "", fmt.Errorf("failed to scan episodes: %w", err)
+		return "", err
 	}
+	return episode.ShowNotes, nil
+}
 
+// GetChapters returns the chapters of a specific episode. Episodes downloaded
+// before chapters were saved in .metadata.json are parsed from shownotes.txt.
+func (s *EpisodeService) GetChapters(episodeID string) ([]models.Chapter, error) {
+	episode, err := s.getEpisode(episodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if episode.Metadata != nil && len(episode.Metadata.Chapters) > 0 {
+		return episode.Metadata.Chapters, nil
+	}
+	if chapters := downloader.ParseChapters(episode.ShowNotes); chapters != nil {
+		return chapters, nil
+	}
+	return []models.Chapter{}, nil
+}
+
+// GetAudioPath returns the path of the audio file of a specific episode
+func (s *EpisodeService) GetAudioPath(episodeID string) (string, error) {
+	episode, err := s.getEpisode(episodeID)
+	if err != nil {
+		return "", err
+	}
+	return episode.FilePath, nil
+}
+
+// GetCoverPath returns the path of the cover image of a specific episode.
+// A size from thumbnail.Sizes selects a thumbnail, generated if missing;
+// 0 or a cover that can't be resized returns the original.
+func (s *EpisodeService) GetCoverPath(episodeID string, size int) (string, error) {
+	episode, err := s.getEpisode(episodeID)
+	if err != nil {
+		return "", err
+	}
+	if episode.CoverImagePath == "" {
+		return "", ErrCoverNotFound
+	}
+	if size == 0 {
+		return episode.CoverImagePath, nil
+	}
+
+	path, err := thumbnail.Ensure(episode.CoverImagePath, size)
+	if errors.Is(err, thumbnail.ErrUnsupportedFormat) || errors.Is(err, thumbnail.ErrTooLarge) {
+		return episode.CoverImagePath, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to generate thumbnail: %w", err)
+	}
+	return path, nil
+}
+
+// filterByQuery keeps the episodes among search results and records how they matched
+func filterByQuery(episodes []models.DownloadedEpisode, results []search.Result) []models.DownloadedEpisode {
+	matches := make(map[string]*models.SearchMatch, len(results))
+	for _, result := range results {
+		matches[result.ID] = &models.SearchMatch{
+			Score:   result.Score,
+			Title:   result.Title.HTML(),
+			Snippet: result.Snippet.HTML(),
+		}
+	}
+
+	filtered := episodes[:0]
 	for _, episode := range episodes {
-		if episode.ID == episodeID {
-			return episode.ShowNotes, nil
+		if match, ok := matches[episode.ID]; ok {
+			episode.Match = match
+			filtered = append(filtered, episode)
 		}
 	}
+	return filtered
+}
 
-	return "", fmt.Errorf("episode not found")
+// getEpisode finds a downloaded episode by ID
+func (s *EpisodeService) getEpisode(episodeID string) (*models.DownloadedEpisode, error) {
+	episode, err := s.library.Episode(episodeID)
+	if err != nil {
+		return nil, err
+	}
+	return &episode, nil
 }
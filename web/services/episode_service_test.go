@@ -0,0 +1,240 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meixg/podcast-reader/pkg/scanner"
+)
+
+// writeEpisode writes an episode directory with a dummy audio file downloaded
+// at the given time, and its .metadata.json if metadata isn't empty
+func writeEpisode(t *testing.T, dir, name, metadata string, downloaded time.Time) string {
+	t.Helper()
+	episodeDir := filepath.Join(dir, name)
+	if err := os.MkdirAll(episodeDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	audio := filepath.Join(episodeDir, name+".m4a")
+	if err := os.WriteFile(audio, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(audio, downloaded, downloaded); err != nil {
+		t.Fatal(err)
+	}
+	if metadata != "" {
+		if err := os.WriteFile(filepath.Join(episodeDir, ".metadata.json"), []byte(metadata), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return audio
+}
+
+// newTestEpisodeService creates an episode service over the episodes in dir
+func newTestEpisodeService(dir string) *EpisodeService {
+	return NewEpisodeService(NewLibraryService(scanner.NewScanner(dir), filepath.Join(dir, ".library.json")))
+}
+
+func TestEpisodeService_GetEpisodes(t *testing.T) {
+	dir := t.TempDir()
+	day := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 12, 0, 0, 0, time.UTC) }
+
+	// Relative publish times in older files are resolved against extracted_at
+	writeEpisode(t, dir, "a", `{"episode_title":"A","duration":"1小时15分钟","publish_time":"2个月前","extracted_at":"2024-05-01T12:00:00Z"}`, day(5, 1))
+	writeEpisode(t, dir, "b", `{"episode_title":"B","publish_time":"2024-04-10","published_at":"2024-04-10T08:00:00Z","publish_precision":"exact","extracted_at":"2024-04-20T12:00:00Z"}`, day(4, 20))
+	writeEpisode(t, dir, "c", `{"episode_title":"C","publish_time":"3天前","extracted_at":"2024-04-01T12:00:00Z"}`, day(6, 1))
+	writeEpisode(t, dir, "d", "", day(6, 2))
+
+	s := newTestEpisodeService(dir)
+	titles := func(opts EpisodeListOptions) string {
+		t.Helper()
+		result, err := s.GetEpisodes(1, 20, opts)
+		if err != nil {
+			t.Fatalf("GetEpisodes() error = %v", err)
+		}
+		var titles []string
+		for _, episode := range result.Episodes {
+			titles = append(titles, episode.Title)
+		}
+		return strings.Join(titles, ",")
+	}
+
+	if got := titles(EpisodeListOptions{}); got != "d,C,A,B" {
+		t.Errorf("by download date = %v", got)
+	}
+	// The test audio files can't be read, so the page duration is used
+	result, _ := s.GetEpisodes(1, 20, EpisodeListOptions{})
+	if a := result.Episodes[2]; a.DurationSeconds != 4500 || a.Duration != "1:15:00" {
+		t.Errorf("duration = %d, %q, want 4500, 1:15:00", a.DurationSeconds, a.Duration)
+	}
+	if got := titles(EpisodeListOptions{Sort: SortByPublishDate}); got != "B,C,A,d" {
+		t.Errorf("by publish date = %v", got)
+	}
+	if got := titles(EpisodeListOptions{Sort: SortByPublishDate, Ascending: true}); got != "A,C,B,d" {
+		t.Errorf("by publish date ascending = %v", got)
+	}
+	if got := titles(EpisodeListOptions{Since: day(3, 15), Until: day(4, 30)}); got != "C,B" {
+		t.Errorf("published in range = %v", got)
+	}
+}
+
+func TestEpisodeService_GetChapters(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	// Chapters come from .metadata.json, else from shownotes.txt
+	writeEpisode(t, dir, "e", `{"episode_title":"E","chapters":[{"start":0,"title":"开场"},{"start":90,"title":"正题"}]}`, now)
+	writeEpisode(t, dir, "d", `{"episode_title":"d"}`, now)
+	notes := "时间轴\n00:00 开场\n12:30 话题一\n45:00 结语"
+	if err := os.WriteFile(filepath.Join(dir, "d", "shownotes.txt"), []byte(notes), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeEpisode(t, dir, "f", `{"episode_title":"F"}`, now)
+
+	s := newTestEpisodeService(dir)
+	result, err := s.GetEpisodes(1, 20, EpisodeListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, episode := range result.Episodes {
+		chapters, err := s.GetChapters(episode.ID)
+		if err != nil {
+			t.Fatalf("GetChapters(%s) error = %v", episode.Title, err)
+		}
+		want := map[string]int{"E": 2, "d": 3}[episode.Title]
+		if len(chapters) != want {
+			t.Errorf("GetChapters(%s) = %+v, want %d chapters", episode.Title, chapters, want)
+		}
+	}
+	if _, err := s.GetChapters("missing"); !errors.Is(err, ErrEpisodeNotFound) {
+		t.Errorf("GetChapters(missing) error = %v, want ErrEpisodeNotFound", err)
+	}
+}
+
+func TestEpisodeService_GetAudioPath(t *testing.T) {
+	dir := t.TempDir()
+	audio := writeEpisode(t, dir, "a", `{"episode_title":"A"}`, time.Now())
+
+	s := newTestEpisodeService(dir)
+	result, err := s.GetEpisodes(1, 20, EpisodeListOptions{})
+	if err != nil || len(result.Episodes) != 1 {
+		t.Fatalf("GetEpisodes() = %+v, %v", result, err)
+	}
+	id := result.Episodes[0].ID
+	if path, err := s.GetAudioPath(id); err != nil || path != audio {
+		t.Errorf("GetAudioPath() = %q, %v, want %q", path, err, audio)
+	}
+
+	if err := os.Remove(audio); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetAudioPath(id); !errors.Is(err, ErrEpisodeNotFound) {
+		t.Errorf("GetAudioPath() of a deleted file error = %v, want ErrEpisodeNotFound", err)
+	}
+}
+
+func TestEpisodeService_GetCoverPath(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	writeEpisode(t, dir, "a", `{"episode_title":"A"}`, now)
+	writeEpisode(t, dir, "b", `{"episode_title":"B"}`, now)
+	// Covers that can't be decoded are served as they are, at any size
+	cover := filepath.Join(dir, "a", "cover.png")
+	if err := os.WriteFile(cover, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestEpisodeService(dir)
+	result, err := s.GetEpisodes(1, 20, EpisodeListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, episode := range result.Episodes {
+		switch episode.Title {
+		case "A":
+			for _, size := range []int{0, 128} {
+				if path, err := s.GetCoverPath(episode.ID, size); err != nil || path != cover {
+					t.Errorf("GetCoverPath(A, %d) = %q, %v, want %q", size, path, err, cover)
+				}
+			}
+		case "B":
+			if _, err := s.GetCoverPath(episode.ID, 0); !errors.Is(err, ErrCoverNotFound) {
+				t.Errorf("GetCoverPath(B) error = %v, want ErrCoverNotFound", err)
+			}
+		}
+	}
+}
+
+func TestEpisodeService_Search(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, title, notes string) string {
+		episodeDir := filepath.Join(dir, name)
+		if err := os.MkdirAll(episodeDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		audio := filepath.Join(episodeDir, "podcast.m4a")
+		if err := os.WriteFile(audio, []byte("audio"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		metadata := `{"episode_title":"` + title + `","podcast_name":"科技早知道"}`
+		if err := os.WriteFile(filepath.Join(episodeDir, ".metadata.json"), []byte(metadata), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(episodeDir, "shownotes.txt"), []byte(notes), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return audio
+	}
+	write("a", "城市漫步", "我们聊到了人工智能")
+	write("b", "聊聊人工智能", "嘉宾介绍")
+
+	library := NewLibraryService(scanner.NewScanner(dir), filepath.Join(dir, ".library.json"))
+	s := NewEpisodeService(library)
+
+	search := func(query string) []string {
+		t.Helper()
+		result, err := s.GetEpisodes(1, 20, EpisodeListOptions{Sort: SortByRelevance, Query: query})
+		if err != nil {
+			t.Fatalf("GetEpisodes(%q) error = %v", query, err)
+		}
+		var titles []string
+		for _, episode := range result.Episodes {
+			if episode.Match == nil {
+				t.Fatalf("%s has no match details", episode.Title)
+			}
+			titles = append(titles, episode.Title)
+		}
+		return titles
+	}
+	if got := search("人工智能"); len(got) != 2 || got[0] != "聊聊人工智能" {
+		t.Errorf("search(人工智能) = %v, want the title match first", got)
+	}
+
+	// Finished downloads are indexed right away
+	audio := write("c", "量子计算入门", "")
+	id, err := library.IndexEpisode(audio)
+	if err != nil {
+		t.Fatalf("IndexEpisode() error = %v", err)
+	}
+	if got := search("量子"); len(got) != 1 || got[0] != "量子计算入门" {
+		t.Errorf("search(量子) = %v", got)
+	}
+	if notes, err := s.GetShowNotes(id); err != nil || notes != "" {
+		t.Errorf("GetShowNotes(%s) = %q, %v", id, notes, err)
+	}
+
+	// Episodes removed from disk drop out of the index on the next rescan
+	if err := os.RemoveAll(filepath.Join(dir, "b")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := library.Rescan(); err != nil {
+		t.Fatal(err)
+	}
+	if got := search("人工智能"); len(got) != 1 || got[0] != "城市漫步" {
+		t.Errorf("search(人工智能) after removal = %v", got)
+	}
+}
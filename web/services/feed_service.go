@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/meixg/podcast-reader/pkg/mediainfo"
+	"github.com/meixg/podcast-reader/pkg/models"
+	"github.com/meixg/podcast-reader/pkg/rss"
+)
+
+// ErrPodcastNotFound is returned for podcasts with no downloaded episodes
+var ErrPodcastNotFound = errors.New("podcast not found")
+
+// LibraryFeedTitle is the title of the feed of all downloaded episodes
+const LibraryFeedTitle = "Podcast Reader"
+
+// FeedService builds RSS feeds of the downloaded episodes so podcast apps
+// can subscribe to the library
+type FeedService struct {
+	library *LibraryService
+}
+
+// NewFeedService creates a new feed service
+func NewFeedService(library *LibraryService) *FeedService {
+	return &FeedService{
+		library: library,
+	}
+}
+
+// LibraryFeed returns a feed of every downloaded episode. baseURL is the
+// public URL of the server, which audio and cover links are built from.
+func (s *FeedService) LibraryFeed(baseURL string) (*rss.Channel, error) {
+	episodes, err := s.library.Episodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list episodes: %w", err)
+	}
+
+	channel := &rss.Channel{
+		Title:       LibraryFeedTitle,
+		Link:        baseURL + "/",
+		Description: "已下载的播客节目",
+		Language:    "zh-cn",
+	}
+	channel.Items = feedItems(baseURL, episodes)
+	return channel, nil
+}
+
+// PodcastFeed returns a feed of the downloaded episodes of one podcast,
+// matched by name
+func (s *FeedService) PodcastFeed(baseURL, podcast string) (*rss.Channel, error) {
+	episodes, err := s.library.Episodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list episodes: %w", err)
+	}
+
+	var matched []models.DownloadedEpisode
+	for _, episode := range episodes {
+		if episode.PodcastName == podcast {
+			matched = append(matched, episode)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, ErrPodcastNotFound
+	}
+
+	channel := &rss.Channel{
+		Title:       podcast,
+		Link:        baseURL + "/",
+		Description: podcast,
+		Language:    "zh-cn",
+		Author:      podcast,
+	}
+	channel.Items = feedItems(baseURL, matched)
+	// The newest cover stands in for the podcast artwork
+	for _, item := range channel.Items {
+		if item.ImageURL != "" {
+			channel.ImageURL = item.ImageURL
+			break
+		}
+	}
+	return channel, nil
+}
+
+// feedItems converts episodes to feed items, newest first
+func feedItems(baseURL string, episodes []models.DownloadedEpisode) []rss.Item {
+	items := make([]rss.Item, 0, len(episodes))
+	for _, episode := range episodes {
+		episodeURL := baseURL + "/api/episodes/" + url.PathEscape(episode.ID)
+		item := rss.Item{
+			Title:       episode.Title,
+			Link:        episode.SourceURL,
+			Description: strings.TrimPrefix(episode.ShowNotes, "\uFEFF"),
+			GUID:        feedGUID(episode),
+			PubDate:     episodeDate(episode),
+			Duration:    time.Duration(episode.DurationSeconds) * time.Second,
+			Enclosure: rss.Enclosure{
+				URL:    episodeURL + "/audio",
+				Length: episode.FileSize,
+				Type:   mediainfo.ContentType(episode.FilePath),
+			},
+		}
+		if episode.CoverImagePath != "" {
+			item.ImageURL = episodeURL + "/cover"
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].PubDate.After(items[j].PubDate)
+	})
+	return items
+}
+
+// feedGUID identifies an episode in feeds. The source URL survives moving
+// the downloads directory and re-downloading; the ID is used without one.
+func feedGUID(episode models.DownloadedEpisode) string {
+	if episode.SourceURL != "" {
+		return episode.SourceURL
+	}
+	return "podcast-reader:" + episode.ID
+}
+
+// episodeDate returns when an episode was published, or downloaded if unknown
+func episodeDate(episode models.DownloadedEpisode) time.Time {
+	if episode.PublishedAt != nil {
+		return *episode.PublishedAt
+	}
+	return episode.DownloadDate
+}
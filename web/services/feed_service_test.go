@@ -0,0 +1,65 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/meixg/podcast-reader/pkg/scanner"
+)
+
+func TestFeedService_PodcastFeed(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, audio, metadata string) {
+		episodeDir := filepath.Join(dir, name)
+		if err := os.MkdirAll(episodeDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(episodeDir, audio), []byte("audio"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(episodeDir, ".metadata.json"), []byte(metadata), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a", "podcast.m4a", `{"episode_title":"A1","podcast_name":"节目A","source_url":"https://example.com/a1","published_at":"2024-04-10T08:00:00Z"}`)
+	write("b", "podcast.mp3", `{"episode_title":"A2","podcast_name":"节目A","published_at":"2024-05-10T08:00:00Z"}`)
+	write("c", "podcast.m4a", `{"episode_title":"B1","podcast_name":"节目B"}`)
+	if err := os.WriteFile(filepath.Join(dir, "b", "cover.jpg"), []byte("jpg"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewFeedService(NewLibraryService(scanner.NewScanner(dir), filepath.Join(dir, ".library.json")))
+	library, err := s.LibraryFeed("http://nas:8080")
+	if err != nil {
+		t.Fatalf("LibraryFeed() error = %v", err)
+	}
+	if len(library.Items) != 3 {
+		t.Errorf("library feed has %d items, want 3", len(library.Items))
+	}
+
+	channel, err := s.PodcastFeed("http://nas:8080", "节目A")
+	if err != nil {
+		t.Fatalf("PodcastFeed() error = %v", err)
+	}
+	if len(channel.Items) != 2 || channel.Items[0].Title != "A2" || channel.Items[1].Title != "A1" {
+		t.Fatalf("items = %+v, want A2, A1", channel.Items)
+	}
+	newest, oldest := channel.Items[0], channel.Items[1]
+	if oldest.GUID != "https://example.com/a1" {
+		t.Errorf("GUID = %q, want the source URL", oldest.GUID)
+	}
+	if newest.Enclosure.Type != "audio/mpeg" || newest.Enclosure.Length != 5 ||
+		!strings.HasPrefix(newest.Enclosure.URL, "http://nas:8080/api/episodes/") || !strings.HasSuffix(newest.Enclosure.URL, "/audio") {
+		t.Errorf("enclosure = %+v", newest.Enclosure)
+	}
+	if channel.ImageURL == "" || channel.ImageURL != newest.ImageURL {
+		t.Errorf("channel image = %q, want the newest cover %q", channel.ImageURL, newest.ImageURL)
+	}
+
+	if _, err := s.PodcastFeed("http://nas:8080", "节目C"); !errors.Is(err, ErrPodcastNotFound) {
+		t.Errorf("PodcastFeed(节目C) error = %v, want ErrPodcastNotFound", err)
+	}
+}
@@ -0,0 +1,423 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/meixg/podcast-reader/pkg/models"
+	"github.com/meixg/podcast-reader/pkg/scanner"
+	"github.com/meixg/podcast-reader/pkg/search"
+)
+
+const (
+	// DefaultLibraryRescanInterval is how often the library is reconciled with
+	// the downloads directory unless configured otherwise
+	DefaultLibraryRescanInterval = 5 * time.Minute
+	// libraryVersion changes whenever stored episodes must be read again.
+	// Version 2 derives episode IDs from the source episode ID.
+	libraryVersion = 2
+)
+
+// libraryRecord is an episode as stored in the library file, with the
+// fingerprint of the files it was read from
+type libraryRecord struct {
+	models.DownloadedEpisode
+	Fingerprint string `json:"fingerprint"`
+}
+
+// libraryFile is the format of the library file
+type libraryFile struct {
+	Version  int               `json:"version"`
+	Episodes []*libraryRecord  `json:"episodes"`
+	Aliases  map[string]string `json:"aliases,omitempty"` // Former episode IDs and the IDs they resolve to
+}
+
+// LibraryService keeps the downloaded episodes and their search index in
+// memory, and in a JSON file across restarts, so requests don't walk the
+// downloads directory. Finished downloads are added as they complete;
+// rescans pick up changes made on disk by comparing file sizes and
+// modification times, and only read the episodes that changed. Episodes
+// keep resolving by their former IDs, such as the path-based IDs used before
+// IDs were derived from the source.
+type LibraryService struct {
+	scanner *scanner.Scanner
+	path    string
+	records map[string]*libraryRecord // By audio file path
+	ids     map[string]string         // Audio file path by episode ID
+	aliases map[string]string         // Former episode IDs and the IDs they resolve to
+	index   *search.Index
+	loaded  bool // Set once the library was loaded or scanned
+	dirty   bool // Set when records changed since the last save
+	scanMu  sync.Mutex
+	mu      sync.Mutex
+}
+
+// NewLibraryService creates a library of the episodes found by s, persisted at path
+func NewLibraryService(s *scanner.Scanner, path string) *LibraryService {
+	return &LibraryService{
+		scanner: s,
+		path:    path,
+		records: make(map[string]*libraryRecord),
+		ids:     make(map[string]string),
+		aliases: make(map[string]string),
+		index:   search.NewIndex(),
+	}
+}
+
+// Load reads the persisted library. A missing file is not an error; the
+// library is then filled by the first rescan, as it is for a file written by
+// an older version, of which only the aliases are kept.
+func (l *LibraryService) Load() error {
+	data, err := os.ReadFile(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read library: %w", err)
+	}
+
+	var file libraryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse library: %w", err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for alias, id := range file.Aliases {
+		l.aliases[alias] = id
+	}
+	if file.Version != libraryVersion {
+		return nil
+	}
+	for _, record := range file.Episodes {
+		if record.ID != "" && record.FilePath != "" {
+			l.put(record)
+		}
+	}
+	l.loaded = true
+	return nil
+}
+
+// Episodes returns every episode in the library, ordered by file path
+func (l *LibraryService) Episodes() ([]models.DownloadedEpisode, error) {
+	if err := l.ensureLoaded(); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	episodes := make([]models.DownloadedEpisode, 0, len(l.records))
+	for _, record := range l.records {
+		episodes = append(episodes, record.DownloadedEpisode)
+	}
+	sort.Slice(episodes, func(i, j int) bool {
+		return episodes[i].FilePath < episodes[j].FilePath
+	})
+	return episodes, nil
+}
+
+// Episode returns an episode by its ID or a former ID. Its files are
+// checked, so changes since the last rescan are picked up.
+func (l *LibraryService) Episode(id string) (models.DownloadedEpisode, error) {
+	if err := l.ensureLoaded(); err != nil {
+		return models.DownloadedEpisode{}, err
+	}
+
+	l.mu.Lock()
+	record := l.records[l.ids[id]]
+	if record == nil {
+		record = l.records[l.ids[l.aliases[id]]]
+	}
+	l.mu.Unlock()
+	if record == nil {
+		return models.DownloadedEpisode{}, ErrEpisodeNotFound
+	}
+
+	info, err := os.Stat(record.FilePath)
+	if os.IsNotExist(err) {
+		l.mu.Lock()
+		if l.records[record.FilePath] == record {
+			l.delete(record.FilePath)
+			l.dirty = true
+		}
+		l.mu.Unlock()
+		return models.DownloadedEpisode{}, ErrEpisodeNotFound
+	}
+	if err != nil {
+		return models.DownloadedEpisode{}, fmt.Errorf("failed to read episode: %w", err)
+	}
+
+	if fingerprint := l.scanner.Fingerprint(record.FilePath, info); fingerprint != record.Fingerprint {
+		updated, err := l.read(record.FilePath, fingerprint)
+		if err != nil {
+			return models.DownloadedEpisode{}, err
+		}
+		l.mu.Lock()
+		if l.records[record.FilePath] == record {
+			l.put(updated)
+			l.dirty = true
+		}
+		l.mu.Unlock()
+		record = updated
+	}
+	return record.DownloadedEpisode, nil
+}
+
+// Search returns the episodes matching a full-text query of their title,
+// podcast name and show notes, best matches first
+func (l *LibraryService) Search(query string) ([]search.Result, error) {
+	if err := l.ensureLoaded(); err != nil {
+		return nil, err
+	}
+	return l.index.Search(query), nil
+}
+
+// IndexEpisode adds a newly downloaded episode to the library and returns its ID
+func (l *LibraryService) IndexEpisode(audioPath string) (string, error) {
+	info, err := os.Stat(audioPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read episode: %w", err)
+	}
+	record, err := l.read(audioPath, l.scanner.Fingerprint(audioPath, info))
+	if err != nil {
+		return "", err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.put(record)
+	l.dirty = true
+	l.saveOrLog()
+	return record.ID, nil
+}
+
+// Rescan reconciles the library with the downloads directory: new and
+// changed episodes are read, and episodes whose audio file is gone are
+// dropped. Only one rescan runs at a time.
+func (l *LibraryService) Rescan() (*models.LibraryRescanResult, error) {
+	l.scanMu.Lock()
+	defer l.scanMu.Unlock()
+
+	files, err := l.scanner.AudioFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	known := make(map[string]*libraryRecord, len(l.records))
+	for path, record := range l.records {
+		known[path] = record
+	}
+	l.mu.Unlock()
+
+	// Read the new and changed episodes without holding the lock
+	var changed []*libraryRecord
+	for path, info := range files {
+		fingerprint := l.scanner.Fingerprint(path, info)
+		if record, ok := known[path]; ok && record.Fingerprint == fingerprint {
+			continue
+		}
+		record, err := l.read(path, fingerprint)
+		if err != nil {
+			log.Printf("Warning: Failed to read episode %s: %v", path, err)
+			continue
+		}
+		changed = append(changed, record)
+	}
+
+	// Apply in path order so copies of an episode get the same IDs every time
+	sort.Slice(changed, func(i, j int) bool {
+		return changed[i].FilePath < changed[j].FilePath
+	})
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := &models.LibraryRescanResult{ScannedAt: time.Now()}
+	// Drop removed episodes first, so moved ones keep their IDs
+	for path, record := range known {
+		if _, ok := files[path]; !ok && l.records[path] == record {
+			l.delete(path)
+			result.Removed++
+		}
+	}
+	for _, record := range changed {
+		current, exists := l.records[record.FilePath]
+		if exists && current != known[record.FilePath] {
+			// Added or updated by a download while scanning
+			continue
+		}
+		if exists {
+			result.Updated++
+		} else {
+			result.Added++
+		}
+		l.put(record)
+	}
+	result.Total = len(l.records)
+
+	if result.Added+result.Updated+result.Removed > 0 {
+		l.dirty = true
+	}
+	l.loaded = true
+	l.saveOrLog()
+	return result, nil
+}
+
+// Run rescans the downloads directory right away and then every interval
+// until ctx is cancelled. A zero interval rescans only once.
+func (l *LibraryService) Run(ctx context.Context, interval time.Duration) {
+	l.rescanOrLog()
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.rescanOrLog()
+		}
+	}
+}
+
+// rescanOrLog rescans the library and logs the changes it found
+func (l *LibraryService) rescanOrLog() {
+	result, err := l.Rescan()
+	if err != nil {
+		log.Printf("Warning: Failed to rescan library: %v", err)
+		return
+	}
+	if result.Added+result.Updated+result.Removed > 0 {
+		log.Printf("Library rescanned: %d added, %d updated, %d removed, %d episodes",
+			result.Added, result.Updated, result.Removed, result.Total)
+	}
+}
+
+// ensureLoaded scans the downloads directory if the library is still empty
+// because there was no library file
+func (l *LibraryService) ensureLoaded() error {
+	l.mu.Lock()
+	loaded := l.loaded
+	l.mu.Unlock()
+	if loaded {
+		return nil
+	}
+	_, err := l.Rescan()
+	return err
+}
+
+// read parses the episode of an audio file
+func (l *LibraryService) read(audioPath, fingerprint string) (*libraryRecord, error) {
+	episode, err := l.scanner.ScanEpisode(audioPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read episode: %w", err)
+	}
+	return &libraryRecord{DownloadedEpisode: episode, Fingerprint: fingerprint}, nil
+}
+
+// put adds or replaces the record of an audio file and records the former
+// IDs of the episode as aliases. Callers must hold l.mu.
+func (l *LibraryService) put(record *libraryRecord) {
+	pathID := scanner.PathID(record.FilePath)
+	if path, taken := l.ids[record.ID]; taken && path != record.FilePath {
+		// Another copy of the episode has its source ID
+		record.ID = pathID
+	}
+	if old, exists := l.records[record.FilePath]; exists && old.ID != record.ID {
+		l.delete(record.FilePath)
+		l.addAlias(old.ID, record.ID)
+	}
+	l.addAlias(pathID, record.ID)
+
+	l.records[record.FilePath] = record
+	l.ids[record.ID] = record.FilePath
+	l.index.Add(searchDocument(record.DownloadedEpisode))
+}
+
+// delete drops the record of an audio file. Callers must hold l.mu.
+func (l *LibraryService) delete(path string) {
+	record, exists := l.records[path]
+	if !exists {
+		return
+	}
+	delete(l.records, path)
+	if l.ids[record.ID] == path {
+		delete(l.ids, record.ID)
+		l.index.Remove(record.ID)
+	}
+}
+
+// addAlias makes an episode resolve by a former ID, including the IDs that
+// resolved to the former one. Callers must hold l.mu.
+func (l *LibraryService) addAlias(from, to string) {
+	if from == to || l.aliases[from] == to {
+		return
+	}
+	for alias, id := range l.aliases {
+		if id == from {
+			l.aliases[alias] = to
+		}
+	}
+	l.aliases[from] = to
+	delete(l.aliases, to)
+}
+
+// saveOrLog writes the library file if records changed and logs failures.
+// Callers must hold l.mu.
+func (l *LibraryService) saveOrLog() {
+	if !l.dirty {
+		return
+	}
+	if err := l.save(); err != nil {
+		log.Printf("Warning: Failed to save library: %v", err)
+		return
+	}
+	l.dirty = false
+}
+
+// save writes the library file. Callers must hold l.mu.
+func (l *LibraryService) save() error {
+	file := libraryFile{
+		Version:  libraryVersion,
+		Episodes: make([]*libraryRecord, 0, len(l.records)),
+		Aliases:  l.aliases,
+	}
+	for _, record := range l.records {
+		file.Episodes = append(file.Episodes, record)
+	}
+	sort.Slice(file.Episodes, func(i, j int) bool {
+		return file.Episodes[i].FilePath < file.Episodes[j].FilePath
+	})
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to marshal library: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create library directory: %w", err)
+	}
+	tmpPath := l.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write library: %w", err)
+	}
+	return os.Rename(tmpPath, l.path)
+}
+
+// searchDocument returns the indexed fields of an episode
+func searchDocument(episode models.DownloadedEpisode) search.Document {
+	return search.Document{
+		ID:          episode.ID,
+		Title:       episode.Title,
+		PodcastName: episode.PodcastName,
+		ShowNotes:   episode.ShowNotes,
+	}
+}
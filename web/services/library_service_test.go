@@ -0,0 +1,196 @@
+package services
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/meixg/podcast-reader/pkg/scanner"
+)
+
+func TestLibraryService_Rescan(t *testing.T) {
+	dir := t.TempDir()
+	libraryPath := filepath.Join(dir, ".library.json")
+	write := func(name, title string) string {
+		episodeDir := filepath.Join(dir, name)
+		if err := os.MkdirAll(episodeDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		audio := filepath.Join(episodeDir, "podcast.m4a")
+		if err := os.WriteFile(audio, []byte("audio"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		metadata := `{"episode_title":"` + title + `"}`
+		if err := os.WriteFile(filepath.Join(episodeDir, ".metadata.json"), []byte(metadata), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return audio
+	}
+	rescan := func(l *LibraryService, added, updated, removed, total int) {
+		t.Helper()
+		result, err := l.Rescan()
+		if err != nil {
+			t.Fatalf("Rescan() error = %v", err)
+		}
+		if result.Added != added || result.Updated != updated || result.Removed != removed || result.Total != total {
+			t.Errorf("Rescan() = %+v, want %d added, %d updated, %d removed, %d total",
+				result, added, updated, removed, total)
+		}
+	}
+
+	write("a", "A")
+	audio := write("b", "B")
+	l := NewLibraryService(scanner.NewScanner(dir), libraryPath)
+	rescan(l, 2, 0, 0, 2)
+	rescan(l, 0, 0, 0, 2)
+
+	// Edited metadata is read again, on rescan or when the episode is requested
+	write("b", "B2")
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(filepath.Join(dir, "b", ".metadata.json"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	episodes, _ := l.Episodes()
+	if episodes[1].Title != "B" {
+		t.Errorf("Episodes() before rescan = %q, want the indexed title B", episodes[1].Title)
+	}
+	episode, err := l.Episode(episodes[1].ID)
+	if err != nil || episode.Title != "B2" {
+		t.Errorf("Episode() = %q, %v, want B2", episode.Title, err)
+	}
+	rescan(l, 0, 0, 0, 2)
+
+	// Edited show notes are part of the fingerprint, so they are reindexed
+	notes := filepath.Join(dir, "a", "shownotes.txt")
+	if err := os.WriteFile(notes, []byte("读书会"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rescan(l, 0, 1, 0, 2)
+	if results, _ := l.Search("读书"); len(results) != 1 {
+		t.Errorf("Search() after adding show notes = %+v", results)
+	}
+	if err := os.WriteFile(notes, []byte("电影"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(notes, later, later); err != nil {
+		t.Fatal(err)
+	}
+	rescan(l, 0, 1, 0, 2)
+	if results, _ := l.Search("读书"); len(results) != 0 {
+		t.Errorf("Search() found replaced show notes: %+v", results)
+	}
+	if results, _ := l.Search("影"); len(results) != 1 {
+		t.Errorf("Search(影) after editing show notes = %+v", results)
+	}
+
+	// The library file lets a restarted server skip reading unchanged episodes
+	reloaded := NewLibraryService(scanner.NewScanner(dir), libraryPath)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if episodes, _ := reloaded.Episodes(); len(episodes) != 2 || episodes[1].Title != "B2" {
+		t.Errorf("Episodes() after Load = %+v", episodes)
+	}
+	rescan(reloaded, 0, 0, 0, 2)
+
+	if err := os.RemoveAll(filepath.Dir(audio)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reloaded.Episode(episodes[1].ID); !errors.Is(err, ErrEpisodeNotFound) {
+		t.Errorf("Episode() of a deleted episode error = %v, want ErrEpisodeNotFound", err)
+	}
+	write("c", "C")
+	rescan(reloaded, 1, 0, 0, 2)
+	if results, _ := reloaded.Search("B2"); len(results) != 0 {
+		t.Errorf("Search() found a deleted episode: %+v", results)
+	}
+}
+
+func TestLibraryService_StableIDs(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, metadata string) string {
+		episodeDir := filepath.Join(dir, name)
+		if err := os.MkdirAll(episodeDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		audio := filepath.Join(episodeDir, "podcast.m4a")
+		if err := os.WriteFile(audio, []byte("audio"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(episodeDir, ".metadata.json"), []byte(metadata), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return audio
+	}
+	xiaoyuzhou := write("a", `{"episode_title":"A","source_url":"https://www.xiaoyuzhoufm.com/episode/69392768281939cce65925d3?s=share"}`)
+	// Feed downloads made before episode_guid was saved have the GUID in the source URL only
+	write("b", `{"episode_title":"B","source_url":"https://example.com/feed.xml#guid=item+1"}`)
+	write("c", `{"episode_title":"C","source_url":"https://example.com/feed.xml#guid=item+2","episode_guid":"item 2"}`)
+	pathOnly := write("d", `{"episode_title":"D"}`)
+
+	l := NewLibraryService(scanner.NewScanner(dir), filepath.Join(dir, ".library.json"))
+	ids := make(map[string]string)
+	episodes, err := l.Episodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, episode := range episodes {
+		ids[episode.Title] = episode.ID
+	}
+	want := map[string]string{
+		"A": "xiaoyuzhou:69392768281939cce65925d3",
+		"B": "guid:https://example.com/feed.xml#item 1",
+		"C": "guid:https://example.com/feed.xml#item 2",
+	}
+	for title, key := range want {
+		if id := hashString(key); ids[title] != id {
+			t.Errorf("ID of %s = %s, want the hash of %q", title, ids[title], key)
+		}
+	}
+	if ids["D"] != scanner.PathID(pathOnly) {
+		t.Errorf("ID of D = %s, want its path ID", ids["D"])
+	}
+
+	// Renaming a folder keeps the ID, and the former path ID still resolves
+	if err := os.Rename(filepath.Join(dir, "a"), filepath.Join(dir, "renamed")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Rescan(); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{ids["A"], scanner.PathID(xiaoyuzhou)} {
+		if episode, err := l.Episode(id); err != nil || episode.ID != ids["A"] {
+			t.Errorf("Episode(%s) = %q, %v, want episode A", id, episode.ID, err)
+		}
+	}
+
+	// A second copy of an episode keeps a path ID
+	copied := write("z", `{"episode_title":"A copy","source_url":"https://www.xiaoyuzhoufm.com/episode/69392768281939cce65925d3"}`)
+	if _, err := l.Rescan(); err != nil {
+		t.Fatal(err)
+	}
+	if episode, err := l.Episode(scanner.PathID(copied)); err != nil || episode.Title != "A copy" {
+		t.Errorf("Episode() of the copy = %q, %v", episode.Title, err)
+	}
+	if episode, _ := l.Episode(ids["A"]); episode.Title != "A" {
+		t.Errorf("Episode(%s) = %q, want A", ids["A"], episode.Title)
+	}
+
+	// Aliases are kept across restarts
+	reloaded := NewLibraryService(scanner.NewScanner(dir), filepath.Join(dir, ".library.json"))
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if episode, err := reloaded.Episode(scanner.PathID(xiaoyuzhou)); err != nil || episode.Title != "A" {
+		t.Errorf("Episode() by former ID after Load = %q, %v", episode.Title, err)
+	}
+}
+
+func hashString(s string) string {
+	hash := md5.Sum([]byte(s))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/meixg/podcast-reader/pkg/downloader"
+	"github.com/meixg/podcast-reader/pkg/models"
+	"github.com/meixg/podcast-reader/pkg/opml"
+)
+
+// OPMLImportOptions controls what importing an OPML file does
+type OPMLImportOptions struct {
+	Mode models.OPMLImportMode // Defaults to models.OPMLImportSubscribe
+	// Latest is how many of the newest episodes of each show to download:
+	// the backfill of new subscriptions, or the batch size when downloading.
+	// 0 backfills nothing and downloads every episode.
+	Latest int
+}
+
+// OPMLService imports and exports shows as OPML, the format podcast apps use
+// to move subscriptions between each other
+type OPMLService struct {
+	library       *LibraryService
+	subscriptions *SubscriptionService
+	tasks         *TaskService
+}
+
+// NewOPMLService creates a new OPML service
+func NewOPMLService(library *LibraryService, subscriptions *SubscriptionService, tasks *TaskService) *OPMLService {
+	return &OPMLService{
+		library:       library,
+		subscriptions: subscriptions,
+		tasks:         tasks,
+	}
+}
+
+// Export lists the shows in the library and the subscriptions
+func (s *OPMLService) Export() (*opml.Document, error) {
+	episodes, err := s.library.Episodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list episodes: %w", err)
+	}
+	return opml.Library(episodes, s.subscriptions.List()), nil
+}
+
+// Import subscribes to or downloads every show of an OPML file, and queues
+// the single episodes it links to. Episodes listed under a show that is
+// imported are left to the show. Subscriptions are recorded right away;
+// downloads are only checked against the sources and active tasks, and are
+// created in the background since listing shows and looking up episodes
+// fetches their pages. Failures are reported in the result, or logged for
+// background downloads, rather than stopping the import.
+func (s *OPMLService) Import(doc *opml.Document, opts OPMLImportOptions) *models.OPMLImportResult {
+	result := &models.OPMLImportResult{Items: []models.OPMLImportItem{}}
+	seen := make(map[string]bool)
+	var downloads []opmlDownload
+	var walk func(outlines []opml.Outline, inShow bool)
+	walk = func(outlines []opml.Outline, inShow bool) {
+		for _, outline := range outlines {
+			url, isShow := s.outlineURL(outline)
+			if url == "" || seen[url] || inShow && !isShow {
+				walk(outline.Outlines, inShow || isShow)
+				continue
+			}
+			seen[url] = true
+
+			item := models.OPMLImportItem{Title: outline.Name(), URL: url}
+			if isShow && opts.Mode != models.OPMLImportDownload {
+				s.subscribe(&item, opts.Latest)
+			} else if s.checkDownload(&item) {
+				downloads = append(downloads, opmlDownload{url: url, isShow: isShow})
+			}
+			result.Add(item)
+			walk(outline.Outlines, inShow || isShow && item.Status != models.OPMLImportFailed)
+		}
+	}
+	walk(doc.Outlines, false)
+
+	if len(downloads) > 0 {
+		go s.queueDownloads(downloads, opts.Latest)
+	}
+	return result
+}
+
+// outlineURL returns the show or episode an outline refers to. Apps put
+// feeds in xmlUrl; show pages may only be known as htmlUrl.
+func (s *OPMLService) outlineURL(outline opml.Outline) (url string, isShow bool) {
+	switch {
+	case outline.XMLURL != "":
+		return outline.XMLURL, true
+	case outline.HTMLURL != "" && s.tasks.IsShowURL(outline.HTMLURL):
+		return outline.HTMLURL, true
+	case outline.URL != "":
+		return outline.URL, s.tasks.IsShowURL(outline.URL)
+	}
+	return "", false
+}
+
+// subscribe creates a subscription named after the outline
+func (s *OPMLService) subscribe(item *models.OPMLImportItem, backfill int) {
+	subscription, err := s.subscriptions.Create(models.CreateSubscriptionRequest{
+		URL:      item.URL,
+		Backfill: backfill,
+	})
+	switch {
+	case errors.Is(err, ErrSubscriptionExists):
+		item.Status = models.OPMLImportSkipped
+		return
+	case err != nil:
+		item.Status = models.OPMLImportFailed
+		item.Error = err.Error()
+		return
+	}
+
+	item.Status = models.OPMLImportSubscribed
+	item.SubscriptionID = subscription.ID
+	if item.Title != "" {
+		if _, err := s.subscriptions.Update(subscription.ID, models.UpdateSubscriptionRequest{Title: &item.Title}); err != nil {
+			item.Error = err.Error()
+		}
+	}
+}
+
+// checkDownload marks an entry queued if it can be downloaded as far as is
+// known without fetching anything, and reports whether it was
+func (s *OPMLService) checkDownload(item *models.OPMLImportItem) bool {
+	switch err := s.tasks.checkQueueable(item.URL); {
+	case err == nil:
+		item.Status = models.OPMLImportQueued
+		return true
+	case isDuplicateTaskError(err):
+		item.Status = models.OPMLImportSkipped
+	default:
+		item.Status = models.OPMLImportFailed
+		item.Error = err.Error()
+	}
+	return false
+}
+
+// opmlDownload is an imported show or episode waiting to be queued
+type opmlDownload struct {
+	url    string
+	isShow bool
+}
+
+// queueDownloads creates the download tasks of imported entries: a batch of
+// the newest episodes of each show that aren't downloaded yet, and a task for
+// each single episode
+func (s *OPMLService) queueDownloads(downloads []opmlDownload, latest int) {
+	for _, download := range downloads {
+		var err error
+		if download.isShow {
+			_, err = s.tasks.CreateBatch(context.Background(), download.url, BatchOptions{
+				Filter:       downloader.EpisodeFilter{Latest: latest},
+				SkipExisting: true,
+			})
+		} else {
+			_, err = s.tasks.CreateTask(download.url)
+		}
+		if err != nil && !isDuplicateTaskError(err) {
+			log.Printf("Warning: Failed to queue imported %s: %v", download.url, err)
+		}
+	}
+}
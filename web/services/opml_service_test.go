@@ -0,0 +1,89 @@
+package services
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meixg/podcast-reader/pkg/downloader"
+	"github.com/meixg/podcast-reader/pkg/models"
+	"github.com/meixg/podcast-reader/pkg/opml"
+	"github.com/meixg/podcast-reader/pkg/scanner"
+)
+
+func TestOPMLService_Import(t *testing.T) {
+	s := NewTaskService()
+	s.queue = NewDownloadQueue(1, QueueOrderFIFO, func(taskID, url string) {})
+	downloadsDir := t.TempDir()
+	ds := NewDownloadService(downloadsDir, s)
+	ds.sources = downloader.NewRegistry(&showSource{episodes: []*downloader.EpisodeMetadata{
+		{Title: "ep1", PageURL: "https://example.com/ep1"},
+		{Title: "ep2", PageURL: "https://example.com/ep2"},
+	}})
+	s.SetDownloadService(ds)
+	subs := NewSubscriptionService(filepath.Join(t.TempDir(), ".subscriptions.json"), ds, s)
+	library := NewLibraryService(scanner.NewScanner(downloadsDir), filepath.Join(downloadsDir, ".library.json"))
+	service := NewOPMLService(library, subs, s)
+
+	doc, err := opml.Parse(strings.NewReader(`<opml version="2.0"><body>
+  <outline text="Show" type="rss" xmlUrl="https://example.com/show">
+    <outline text="ep1" type="link" url="https://example.com/ep1" />
+  </outline>
+  <outline text="Show again" type="rss" xmlUrl="https://example.com/show" />
+  <outline text="ep2" type="link" url="https://example.com/ep2" />
+  <outline text="empty folder" />
+</body></opml>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Episodes under an imported show are left to it; other episodes are
+	// queued in the background
+	result := service.Import(doc, OPMLImportOptions{Latest: 1})
+	if result.Subscribed != 1 || result.Queued != 1 || len(result.Items) != 2 {
+		t.Fatalf("Import() = %+v, want the show subscribed and ep2 queued", result)
+	}
+	if list := subs.List(); len(list) != 1 || list[0].Title != "Show" {
+		t.Errorf("subscriptions = %+v, want one named after the outline", list)
+	}
+	waitForTask(t, s, "https://example.com/ep2")
+
+	// Importing again skips what is already subscribed or queued
+	result = service.Import(doc, OPMLImportOptions{})
+	if result.Skipped != 2 {
+		t.Errorf("second Import() = %+v, want 2 skipped", result)
+	}
+
+	// Download mode queues a batch per show instead of subscribing
+	subs.Delete(subs.List()[0].ID)
+	result = service.Import(doc, OPMLImportOptions{Mode: models.OPMLImportDownload})
+	if item := result.Items[0]; item.Status != models.OPMLImportQueued {
+		t.Errorf("download mode show = %+v, want it queued", item)
+	}
+	if task := waitForTask(t, s, "https://example.com/show"); task.Kind != models.TaskKindBatch {
+		t.Errorf("task = %+v, want a batch", task)
+	}
+	for _, task := range s.GetTasks() {
+		if task.URL == "https://example.com/ep1" && task.ParentID == "" {
+			t.Errorf("ep1 was queued on its own as well as in the batch")
+		}
+	}
+}
+
+// waitForTask waits for a task for url to be created
+func waitForTask(t *testing.T, s *TaskService, url string) *models.DownloadTask {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		for _, task := range s.GetTasks() {
+			if task.URL == url {
+				return task
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no task was created for %s", url)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
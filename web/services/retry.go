@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"time"
+
+	"github.com/meixg/podcast-reader/pkg/downloader"
+)
+
+// RetryPolicy decides whether and when a failed download is attempted again
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts including the first one; 1 disables retries
+	BaseDelay   time.Duration // Delay before the first retry, doubled for every further one
+	MaxDelay    time.Duration // Upper bound for a single delay
+	Jitter      float64       // Fraction (0-1) of each delay that is randomised
+	Retryable   func(error) bool
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   10 * time.Second,
+		MaxDelay:    5 * time.Minute,
+		Jitter:      0.2,
+		Retryable:   IsRetryable,
+	}
+}
+
+// ShouldRetry reports whether a task that failed with err after the given
+// number of attempts should be attempted again
+func (p RetryPolicy) ShouldRetry(attempts int, err error) bool {
+	if attempts >= p.MaxAttempts {
+		return false
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	return retryable(err)
+}
+
+// Delay returns how long to wait after the given number of failed attempts
+func (p RetryPolicy) Delay(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < attempts && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	// Spread retries out so failed tasks don't hit the server at the same moment
+	if p.Jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(delay))
+	}
+	return delay
+}
+
+// IsRetryable reports whether err is likely to be temporary: network errors,
+// timeouts, truncated transfers and 408/429/5xx responses
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var statusErr *downloader.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Temporary()
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, downloader.ErrNetworkTimeout) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/meixg/podcast-reader/pkg/downloader"
+)
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{40, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := p.Delay(tt.attempts); got != tt.want {
+			t.Errorf("Delay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.Delay(2); got < time.Second || got > 3*time.Second {
+			t.Fatalf("Delay(2) with jitter = %v, want within 1s-3s", got)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"network", fmt.Errorf("%w: dial failed", downloader.ErrNetworkTimeout), true},
+		{"net error", &net.OpError{Op: "read", Err: errors.New("connection reset")}, true},
+		{"truncated", fmt.Errorf("下载中断: %w", io.ErrUnexpectedEOF), true},
+		{"server error", fmt.Errorf("下载失败: %w", &downloader.StatusError{StatusCode: 503}), true},
+		{"rate limited", &downloader.StatusError{StatusCode: 429}, true},
+		{"not found", fmt.Errorf("%w: %w", downloader.ErrPageNotFound, &downloader.StatusError{StatusCode: 404}), false},
+		{"invalid audio", downloader.ErrInvalidAudio, false},
+		{"disk full", downloader.ErrDiskFull, false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
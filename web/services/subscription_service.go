@@ -0,0 +1,500 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/meixg/podcast-reader/pkg/downloader"
+	"github.com/meixg/podcast-reader/pkg/models"
+	"github.com/meixg/podcast-reader/pkg/rules"
+)
+
+// Subscription errors
+var (
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+	ErrSubscriptionExists   = errors.New("already subscribed to this URL")
+	ErrNotAShow             = errors.New("URL is not a show or feed")
+	ErrInvalidPollInterval  = errors.New("poll interval is too short")
+)
+
+const (
+	// DefaultPollInterval is how often a subscription is checked unless configured otherwise
+	DefaultPollInterval = time.Hour
+	// MinPollInterval keeps subscriptions from hammering the sites they check
+	MinPollInterval = 5 * time.Minute
+	// schedulerTick is how often the scheduler looks for subscriptions that are due
+	schedulerTick = 30 * time.Second
+	// maxSeenEpisodes bounds the remembered episodes of a subscription that
+	// its show no longer lists; the listed ones are always remembered
+	maxSeenEpisodes = 2000
+)
+
+// ShowLister lists the episodes of a show; implemented by DownloadService
+type ShowLister interface {
+	ListShow(ctx context.Context, showURL string) ([]*downloader.EpisodeMetadata, downloader.Source, error)
+}
+
+// subscriptionRecord is a subscription as stored on disk, with the episodes
+// that were already seen so only new ones are downloaded. Episodes rejected
+// by the rules are not seen, so they are evaluated again on every check.
+type subscriptionRecord struct {
+	models.Subscription
+	Initialized  bool     `json:"initialized"`        // Set by the first successful check
+	Backfill     int      `json:"backfill,omitempty"` // Episodes to download on the first check
+	SeenEpisodes []string `json:"seenEpisodes,omitempty"`
+}
+
+// SubscriptionService keeps the list of subscriptions in a JSON file and
+// periodically queues new episodes of subscribed shows as download tasks
+type SubscriptionService struct {
+	path    string
+	lister  ShowLister
+	tasks   *TaskService
+	records map[string]*subscriptionRecord
+	wake    chan struct{}
+	mu      sync.Mutex
+}
+
+// NewSubscriptionService creates a subscription service persisted at path.
+// Episodes are listed with lister and queued with tasks.
+func NewSubscriptionService(path string, lister ShowLister, tasks *TaskService) *SubscriptionService {
+	return &SubscriptionService{
+		path:    path,
+		lister:  lister,
+		tasks:   tasks,
+		records: make(map[string]*subscriptionRecord),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Load reads the persisted subscriptions. A missing file is not an error.
+func (s *SubscriptionService) Load() error {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read subscriptions: %w", err)
+	}
+
+	var records []*subscriptionRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("failed to parse subscriptions: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, record := range records {
+		if record.ID != "" {
+			s.records[record.ID] = record
+		}
+	}
+	return nil
+}
+
+// List returns all subscriptions ordered by creation time
+func (s *SubscriptionService) List() []*models.Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscriptions := make([]*models.Subscription, 0, len(s.records))
+	for _, record := range s.records {
+		subscription := record.Subscription
+		subscriptions = append(subscriptions, &subscription)
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+	})
+	return subscriptions
+}
+
+// Get returns a subscription by ID
+func (s *SubscriptionService) Get(id string) (*models.Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, exists := s.records[id]
+	if !exists {
+		return nil, ErrSubscriptionNotFound
+	}
+	subscription := record.Subscription
+	return &subscription, nil
+}
+
+// Create subscribes to a show or feed. It is checked right away.
+func (s *SubscriptionService) Create(req models.CreateSubscriptionRequest) (*models.Subscription, error) {
+	if !s.tasks.IsShowURL(req.URL) {
+		return nil, ErrNotAShow
+	}
+	interval, err := pollInterval(req.PollInterval)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rules.Compile(req.Rules); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	for _, record := range s.records {
+		if record.URL == req.URL {
+			s.mu.Unlock()
+			return nil, ErrSubscriptionExists
+		}
+	}
+
+	record := &subscriptionRecord{
+		Subscription: models.Subscription{
+			ID:           uuid.New().String(),
+			URL:          req.URL,
+			Enabled:      true,
+			PollInterval: int(interval.Seconds()),
+			CreatedAt:    time.Now(),
+			Rules:        req.Rules,
+		},
+		Backfill: max(req.Backfill, 0),
+	}
+	s.records[record.ID] = record
+	if err := s.save(); err != nil {
+		delete(s.records, record.ID)
+		s.mu.Unlock()
+		return nil, err
+	}
+	subscription := record.Subscription
+	s.mu.Unlock()
+
+	s.poke()
+	return &subscription, nil
+}
+
+// Update changes the title, enabled state or poll interval of a subscription
+func (s *SubscriptionService) Update(id string, req models.UpdateSubscriptionRequest) (*models.Subscription, error) {
+	var interval time.Duration
+	if req.PollInterval != nil {
+		var err error
+		if interval, err = pollInterval(*req.PollInterval); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := rules.Compile(req.Rules); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, exists := s.records[id]
+	if !exists {
+		return nil, ErrSubscriptionNotFound
+	}
+	rulesChanged := req.Rules != nil
+	if rulesChanged {
+		record.Rules = req.Rules
+	}
+	if req.Title != nil {
+		record.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Enabled != nil {
+		record.Enabled = *req.Enabled
+	}
+	if req.PollInterval != nil {
+		record.PollInterval = int(interval.Seconds())
+	}
+	record.scheduleNext()
+	if rulesChanged {
+		// Check right away so episodes the old rules rejected are reconsidered
+		record.NextCheck = nil
+	}
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	if rulesChanged {
+		s.poke()
+	}
+
+	subscription := record.Subscription
+	return &subscription, nil
+}
+
+// Delete unsubscribes. Episodes that were already queued are not affected.
+func (s *SubscriptionService) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, exists := s.records[id]
+	if !exists {
+		return ErrSubscriptionNotFound
+	}
+	delete(s.records, id)
+	if err := s.save(); err != nil {
+		s.records[id] = record
+		return err
+	}
+	return nil
+}
+
+// Run checks subscriptions whenever they are due until ctx is cancelled
+func (s *SubscriptionService) Run(ctx context.Context) {
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+
+	for {
+		for _, id := range s.due(time.Now()) {
+			if ctx.Err() != nil {
+				return
+			}
+			s.Check(ctx, id)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// poke wakes the scheduler up to check new subscriptions right away
+func (s *SubscriptionService) poke() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// due returns the IDs of the enabled subscriptions whose next check has come
+func (s *SubscriptionService) due(now time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	for id, record := range s.records {
+		if record.Enabled && (record.NextCheck == nil || !now.Before(*record.NextCheck)) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Check lists the episodes of a subscription and queues the ones not seen before.
+// The first check of a subscription only remembers the existing episodes,
+// except for the newest ones requested as backfill. Later checks remember the
+// queued episodes only, so the rejected ones are decided again by the rules
+// of the next check.
+func (s *SubscriptionService) Check(ctx context.Context, id string) (*models.Subscription, error) {
+	s.mu.Lock()
+	record, exists := s.records[id]
+	if !exists {
+		s.mu.Unlock()
+		return nil, ErrSubscriptionNotFound
+	}
+	url := record.URL
+	rule := record.Rules
+	firstCheck := !record.Initialized
+	backfill := record.Backfill
+	seen := make(map[string]bool, len(record.SeenEpisodes))
+	for _, episodeURL := range record.SeenEpisodes {
+		seen[episodeURL] = true
+	}
+	s.mu.Unlock()
+
+	// List and queue without holding the lock; both may take a while
+	episodes, source, err := s.lister.ListShow(ctx, url)
+	var matcher *rules.Matcher
+	if err == nil {
+		// Rules were validated when they were set
+		matcher, err = rules.Compile(rule)
+	}
+	var newEpisodes int
+	var handled, submitErrs []string
+	if err == nil {
+		// Decisions are newest first; rank the selected episodes so the first
+		// check can queue just the newest ones requested as backfill
+		decisions := matcher.Evaluate(episodes, time.Now())
+		rank := make(map[string]int)
+		for _, decision := range decisions {
+			if decision.Selected {
+				rank[decision.URL] = len(rank)
+			}
+		}
+
+		// Queue the oldest new episodes first
+		for i := len(decisions) - 1; i >= 0; i-- {
+			episodeURL := decisions[i].URL
+			if seen[episodeURL] {
+				continue
+			}
+			position, selected := rank[episodeURL]
+			if firstCheck && (!selected || position >= backfill) {
+				// Episodes published before the subscription are never downloaded
+				handled = append(handled, episodeURL)
+				continue
+			}
+			if !selected {
+				// Leave it unseen so a change of rules can still select it
+				continue
+			}
+			if _, err := s.tasks.CreateTask(episodeURL); err == nil {
+				newEpisodes++
+			} else if !isDuplicateTaskError(err) {
+				// Leave it unseen so the next check tries again
+				submitErrs = append(submitErrs, fmt.Sprintf("%s: %v", episodeURL, err))
+				continue
+			}
+			handled = append(handled, episodeURL)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, exists = s.records[id]
+	if !exists {
+		// Deleted while checking
+		return nil, ErrSubscriptionNotFound
+	}
+
+	now := time.Now()
+	record.LastChecked = &now
+	record.LastNewEpisodes = newEpisodes
+	record.TotalEpisodes += newEpisodes
+	record.scheduleNext()
+	if err != nil {
+		record.LastError = err.Error()
+		log.Printf("Subscription %s: failed to check %s: %v", id, url, err)
+		s.saveOrLog()
+		subscription := record.Subscription
+		return &subscription, err
+	}
+
+	record.Source = source.Name()
+	if record.Title == "" && len(episodes) > 0 {
+		record.Title = episodes[0].PodcastName
+	}
+	listed := make(map[string]bool, len(episodes))
+	for _, episode := range episodes {
+		listed[episode.PageURL] = true
+	}
+	record.markSeen(handled, listed)
+	record.Initialized = true
+	record.Backfill = 0
+	record.LastError = strings.Join(submitErrs, "; ")
+	if newEpisodes > 0 {
+		log.Printf("Subscription %s: queued %d new episodes of %s", id, newEpisodes, url)
+	}
+	s.saveOrLog()
+
+	subscription := record.Subscription
+	if record.LastError != "" {
+		return &subscription, errors.New(record.LastError)
+	}
+	return &subscription, nil
+}
+
+// DryRun lists the episodes of a show and evaluates rule against them
+// without queuing anything
+func (s *SubscriptionService) DryRun(ctx context.Context, url string, rule *models.EpisodeRule) ([]models.RuleDecision, error) {
+	matcher, err := rules.Compile(rule)
+	if err != nil {
+		return nil, err
+	}
+	if !s.tasks.IsShowURL(url) {
+		return nil, ErrNotAShow
+	}
+
+	episodes, _, err := s.lister.ListShow(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return matcher.Evaluate(episodes, time.Now()), nil
+}
+
+// scheduleNext sets the time of the next check from the last one
+func (r *subscriptionRecord) scheduleNext() {
+	if r.LastChecked == nil {
+		r.NextCheck = nil
+		return
+	}
+	next := r.LastChecked.Add(time.Duration(r.PollInterval) * time.Second)
+	r.NextCheck = &next
+}
+
+// markSeen remembers episodes. Beyond maxSeenEpisodes the oldest episodes
+// missing from listed are forgotten; a listed episode that was forgotten would
+// be queued again by the next check, however many episodes the show has.
+func (r *subscriptionRecord) markSeen(urls []string, listed map[string]bool) {
+	r.SeenEpisodes = append(r.SeenEpisodes, urls...)
+	excess := len(r.SeenEpisodes) - maxSeenEpisodes
+	if excess <= 0 {
+		return
+	}
+	kept := r.SeenEpisodes[:0]
+	for _, url := range r.SeenEpisodes {
+		if excess > 0 && !listed[url] {
+			excess--
+			continue
+		}
+		kept = append(kept, url)
+	}
+	r.SeenEpisodes = kept
+}
+
+// save writes all subscriptions to disk atomically. Callers must hold s.mu.
+func (s *SubscriptionService) save() error {
+	records := make([]*subscriptionRecord, 0, len(s.records))
+	for _, record := range s.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal subscriptions: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create subscriptions directory: %w", err)
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write subscriptions: %w", err)
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+// saveOrLog saves the subscriptions and logs failures. Callers must hold s.mu.
+func (s *SubscriptionService) saveOrLog() {
+	if err := s.save(); err != nil {
+		log.Printf("Warning: Failed to save subscriptions: %v", err)
+	}
+}
+
+// pollInterval converts a poll interval in seconds, applying the default and minimum
+func pollInterval(seconds int) (time.Duration, error) {
+	if seconds == 0 {
+		return DefaultPollInterval, nil
+	}
+	interval := time.Duration(seconds) * time.Second
+	if interval < MinPollInterval {
+		return 0, fmt.Errorf("%w: minimum is %v", ErrInvalidPollInterval, MinPollInterval)
+	}
+	return interval, nil
+}
+
+// isDuplicateTaskError reports whether CreateTask refused an episode because
+// it is already queued or downloaded
+func isDuplicateTaskError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "already exists") || strings.Contains(msg, "already downloaded")
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/meixg/podcast-reader/pkg/downloader"
+	"github.com/meixg/podcast-reader/pkg/models"
+)
+
+func TestSubscriptionService_Check(t *testing.T) {
+	s := NewTaskService()
+	s.queue = NewDownloadQueue(1, QueueOrderFIFO, func(taskID, url string) {})
+	ds := NewDownloadService(t.TempDir(), s)
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	source := &showSource{episodes: []*downloader.EpisodeMetadata{
+		{PodcastName: "Show", PageURL: "https://example.com/ep1", PublicationDate: day(1)},
+		{PodcastName: "Show", PageURL: "https://example.com/ep2", PublicationDate: day(2)},
+		{PodcastName: "Show", PageURL: "https://example.com/ep3", PublicationDate: day(3)},
+	}}
+	ds.sources = downloader.NewRegistry(source)
+	s.SetDownloadService(ds)
+
+	path := filepath.Join(t.TempDir(), ".subscriptions.json")
+	subs := NewSubscriptionService(path, ds, s)
+
+	if _, err := subs.Create(models.CreateSubscriptionRequest{URL: "https://example.com/ep1"}); !errors.Is(err, ErrNotAShow) {
+		t.Errorf("Create(episode URL) error = %v, want ErrNotAShow", err)
+	}
+	if _, err := subs.Create(models.CreateSubscriptionRequest{URL: "https://example.com/show", PollInterval: 60}); !errors.Is(err, ErrInvalidPollInterval) {
+		t.Errorf("Create(60s interval) error = %v, want ErrInvalidPollInterval", err)
+	}
+	sub, err := subs.Create(models.CreateSubscriptionRequest{URL: "https://example.com/show", Backfill: 1})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := subs.Create(models.CreateSubscriptionRequest{URL: "https://example.com/show"}); !errors.Is(err, ErrSubscriptionExists) {
+		t.Errorf("Create(duplicate) error = %v, want ErrSubscriptionExists", err)
+	}
+	if due := subs.due(time.Now()); len(due) != 1 {
+		t.Fatalf("due() = %v, want the new subscription", due)
+	}
+
+	// The first check only downloads the backfill
+	sub, err = subs.Check(context.Background(), sub.ID)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if sub.LastNewEpisodes != 1 || sub.Title != "Show" || sub.LastChecked == nil || sub.NextCheck == nil {
+		t.Fatalf("after first check: %+v", sub)
+	}
+	if tasks := s.GetTasks(); len(tasks) != 1 || tasks[0].URL != "https://example.com/ep3" {
+		t.Fatalf("tasks after first check = %d, want only the newest episode", len(tasks))
+	}
+	if due := subs.due(time.Now()); len(due) != 0 {
+		t.Errorf("due() = %v right after a check", due)
+	}
+
+	// Reload from disk; later checks download every new episode
+	subs = NewSubscriptionService(path, ds, s)
+	if err := subs.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	source.episodes = append(source.episodes, &downloader.EpisodeMetadata{PageURL: "https://example.com/ep4", PublicationDate: day(4)})
+	if sub, err = subs.Check(context.Background(), sub.ID); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if sub.LastNewEpisodes != 1 || sub.TotalEpisodes != 2 {
+		t.Errorf("lastNewEpisodes = %d, totalEpisodes = %d, want 1, 2", sub.LastNewEpisodes, sub.TotalEpisodes)
+	}
+
+	// Rules are applied before episodes are queued
+	rule := &models.EpisodeRule{ExcludeKeywords: []string{"预告"}}
+	if _, err := subs.Update(sub.ID, models.UpdateSubscriptionRequest{Rules: &models.EpisodeRule{IncludePattern: "("}}); err == nil {
+		t.Error("Update(invalid rule) succeeded")
+	}
+	if sub, err = subs.Update(sub.ID, models.UpdateSubscriptionRequest{Rules: rule}); err != nil {
+		t.Fatalf("Update(rules) error = %v", err)
+	}
+	source.episodes = append(source.episodes, &downloader.EpisodeMetadata{Title: "预告", PageURL: "https://example.com/trailer", PublicationDate: day(5)})
+	decisions, err := subs.DryRun(context.Background(), sub.URL, rule)
+	if err != nil || len(decisions) != 5 || decisions[0].Selected {
+		t.Fatalf("DryRun() = %+v, %v, want the trailer rejected", decisions, err)
+	}
+	if sub, err = subs.Check(context.Background(), sub.ID); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if sub.LastNewEpisodes != 0 || len(s.GetTasks()) != 2 {
+		t.Errorf("lastNewEpisodes = %d, tasks = %d, want the trailer skipped", sub.LastNewEpisodes, len(s.GetTasks()))
+	}
+
+	// Rejected episodes stay unseen and are queued once the rules accept them
+	if sub, err = subs.Check(context.Background(), sub.ID); err != nil || sub.LastNewEpisodes != 0 {
+		t.Errorf("second check with the same rules = %+v, %v, want the trailer skipped again", sub, err)
+	}
+	if sub, err = subs.Update(sub.ID, models.UpdateSubscriptionRequest{Rules: &models.EpisodeRule{}}); err != nil {
+		t.Fatalf("Update(clear rules) error = %v", err)
+	}
+	if due := subs.due(time.Now()); len(due) != 1 {
+		t.Errorf("due() = %v, want a check after the rules changed", due)
+	}
+	if sub, err = subs.Check(context.Background(), sub.ID); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if tasks := s.GetTasks(); sub.LastNewEpisodes != 1 || len(tasks) != 3 {
+		t.Errorf("lastNewEpisodes = %d, tasks = %d, want the trailer queued", sub.LastNewEpisodes, len(tasks))
+	}
+
+	enabled := false
+	if sub, err = subs.Update(sub.ID, models.UpdateSubscriptionRequest{Enabled: &enabled}); err != nil || sub.Enabled {
+		t.Errorf("Update(disable) = %+v, %v", sub, err)
+	}
+	if err := subs.Delete(sub.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := subs.Get(sub.ID); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("Get(deleted) error = %v, want ErrSubscriptionNotFound", err)
+	}
+}
+
+func TestSubscriptionService_CheckLongShow(t *testing.T) {
+	s := NewTaskService()
+	s.queue = NewDownloadQueue(1, QueueOrderFIFO, func(taskID, url string) {})
+	ds := NewDownloadService(t.TempDir(), s)
+	start := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := &showSource{}
+	for i := 0; i < maxSeenEpisodes+500; i++ {
+		source.episodes = append(source.episodes, &downloader.EpisodeMetadata{
+			PageURL:         fmt.Sprintf("https://example.com/ep%d", i),
+			PublicationDate: start.Add(time.Duration(i) * time.Hour),
+		})
+	}
+	ds.sources = downloader.NewRegistry(source)
+	s.SetDownloadService(ds)
+
+	subs := NewSubscriptionService(filepath.Join(t.TempDir(), ".subscriptions.json"), ds, s)
+	sub, err := subs.Create(models.CreateSubscriptionRequest{URL: "https://example.com/show"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Every listed episode stays seen, so later checks queue only new ones
+	for i := 0; i < 2; i++ {
+		if sub, err = subs.Check(context.Background(), sub.ID); err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+		if sub.LastNewEpisodes != 0 || len(s.GetTasks()) != 0 {
+			t.Fatalf("check %d queued %d episodes, want none", i+1, len(s.GetTasks()))
+		}
+	}
+	source.episodes = append(source.episodes, &downloader.EpisodeMetadata{
+		PageURL:         "https://example.com/new",
+		PublicationDate: time.Now(),
+	})
+	if sub, err = subs.Check(context.Background(), sub.ID); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if tasks := s.GetTasks(); len(tasks) != 1 || tasks[0].URL != "https://example.com/new" {
+		t.Errorf("tasks = %d, want only the new episode", len(tasks))
+	}
+
+	// Episodes the show no longer lists are forgotten beyond the limit
+	source.episodes = source.episodes[len(source.episodes)-10:]
+	if sub, err = subs.Check(context.Background(), sub.ID); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	subs.mu.Lock()
+	seen := subs.records[sub.ID].SeenEpisodes
+	subs.mu.Unlock()
+	if len(seen) != maxSeenEpisodes || seen[len(seen)-1] != "https://example.com/new" {
+		t.Errorf("seen episodes = %d, want the %d latest", len(seen), maxSeenEpisodes)
+	}
+	if sub.LastNewEpisodes != 0 || len(s.GetTasks()) != 1 {
+		t.Errorf("check of the shorter list queued %d episodes", sub.LastNewEpisodes)
+	}
+}
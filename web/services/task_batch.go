@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/meixg/podcast-reader/pkg/downloader"
+	"github.com/meixg/podcast-reader/pkg/models"
+)
+
+// BatchOptions controls which episodes of a show a batch downloads
+type BatchOptions struct {
+	Filter       downloader.EpisodeFilter
+	SkipExisting bool // Leave out episodes already in the downloads directory
+	Priority     int
+}
+
+// IsShowURL reports whether url refers to a whole show that is downloaded as a batch
+func (s *TaskService) IsShowURL(url string) bool {
+	s.mu.RLock()
+	downloadService := s.downloadService
+	s.mu.RUnlock()
+	return downloadService != nil && downloadService.Sources().IsShow(url)
+}
+
+// CreateBatch lists the episodes of a show and queues one task per selected
+// episode, grouped under a new batch task. Episodes that already have an
+// active task are counted as skipped, and older episodes the source can't
+// list as unlisted. A batch with nothing left to download
+// is completed right away.
+func (s *TaskService) CreateBatch(ctx context.Context, showURL string, opts BatchOptions) (*models.DownloadTask, error) {
+	s.mu.RLock()
+	downloadService := s.downloadService
+	s.mu.RUnlock()
+	if downloadService == nil {
+		return nil, errors.New("download service not configured")
+	}
+
+	// Fetch the episode list before taking the lock; it may take a while
+	episodes, source, unlisted, err := downloadService.listShow(ctx, showURL)
+	if err != nil {
+		return nil, err
+	}
+	filter := opts.Filter
+	if opts.SkipExisting {
+		filter.Skip = downloadService.downloadedFilter()
+	}
+	selected, skipped := filter.Apply(episodes)
+
+	s.mu.Lock()
+	if s.hasActiveTask(showURL) {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("task already exists for this URL")
+	}
+
+	now := time.Now()
+	batch := &models.DownloadTask{
+		ID:        uuid.New().String(),
+		URL:       showURL,
+		Source:    source.Name(),
+		Kind:      models.TaskKindBatch,
+		Status:    models.TaskStatusPending,
+		CreatedAt: now,
+		Priority:  opts.Priority,
+		Skipped:   skipped,
+		Unlisted:  unlisted,
+	}
+	var children []*models.DownloadTask
+	for _, episode := range selected {
+		if s.hasActiveTask(episode.PageURL) {
+			batch.Skipped++
+			continue
+		}
+		child := &models.DownloadTask{
+			ID:        uuid.New().String(),
+			URL:       episode.PageURL,
+			Source:    source.Name(),
+			Status:    models.TaskStatusPending,
+			CreatedAt: now,
+			Priority:  opts.Priority,
+			ParentID:  batch.ID,
+		}
+		children = append(children, child)
+		batch.ChildIDs = append(batch.ChildIDs, child.ID)
+	}
+
+	s.tasks[batch.ID] = batch
+	if len(children) == 0 {
+		progress := 100
+		batch.Status = models.TaskStatusCompleted
+		batch.CompletedAt = &now
+		batch.Progress = &progress
+	}
+	s.persist(batch)
+	s.events.Publish(TaskEventCreated, batch)
+	for _, child := range children {
+		s.tasks[child.ID] = child
+		s.persist(child)
+		s.events.Publish(TaskEventCreated, child)
+	}
+	s.refreshBatch(batch.ID)
+	s.mu.Unlock()
+
+	for _, child := range children {
+		s.queue.Enqueue(child.ID, child.URL, child.Priority)
+	}
+	return batch, nil
+}
+
+// children returns the episode tasks of a batch. Callers must hold s.mu.
+func (s *TaskService) children(batch *models.DownloadTask) []*models.DownloadTask {
+	children := make([]*models.DownloadTask, 0, len(batch.ChildIDs))
+	for _, id := range batch.ChildIDs {
+		if child, exists := s.tasks[id]; exists {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// refreshBatch recomputes the status and progress of a batch from its episode
+// tasks. A cancelled batch keeps its status. Callers must hold s.mu.
+func (s *TaskService) refreshBatch(id string) {
+	batch, exists := s.tasks[id]
+	if !exists || batch.Status == models.TaskStatusCancelled {
+		return
+	}
+	children := s.children(batch)
+	if len(children) == 0 {
+		return
+	}
+
+	var pending, running, paused, completed, failed, finished, total int
+	for _, child := range children {
+		progress := 0
+		if child.Progress != nil {
+			progress = *child.Progress
+		}
+		switch child.Status {
+		case models.TaskStatusPending:
+			pending++
+		case models.TaskStatusDownloading, models.TaskStatusExtractingMetadata:
+			running++
+			total += progress
+		case models.TaskStatusPaused:
+			paused++
+			total += progress
+		case models.TaskStatusCompleted:
+			completed++
+			finished++
+		case models.TaskStatusFailed:
+			failed++
+			finished++
+		case models.TaskStatusCancelled:
+			finished++
+		}
+	}
+	total += finished * 100
+	progress := total / len(children)
+
+	var status models.TaskStatus
+	switch {
+	case running > 0 || (pending > 0 && finished > 0):
+		status = models.TaskStatusDownloading
+	case pending > 0:
+		status = models.TaskStatusPending
+	case paused > 0:
+		status = models.TaskStatusPaused
+	case failed > 0:
+		status = models.TaskStatusFailed
+	case completed > 0:
+		status = models.TaskStatusCompleted
+	default:
+		status = models.TaskStatusCancelled
+	}
+
+	progressChanged := batch.Progress == nil || *batch.Progress != progress
+	batch.Progress = &progress
+	if status == batch.Status {
+		if progressChanged {
+			s.events.Publish(TaskEventProgress, batch)
+		}
+		return
+	}
+
+	batch.Status = status
+	batch.CompletedAt = nil
+	batch.ErrorMessage = ""
+	eventType := TaskEventStatusChanged
+	if !isActiveStatus(status) && status != models.TaskStatusPaused {
+		now := time.Now()
+		batch.CompletedAt = &now
+	}
+	switch status {
+	case models.TaskStatusCompleted:
+		eventType = TaskEventCompleted
+	case models.TaskStatusFailed:
+		batch.ErrorMessage = fmt.Sprintf("%d of %d episodes failed", failed, len(children))
+		eventType = TaskEventFailed
+	}
+	s.persist(batch)
+	s.events.Publish(eventType, batch)
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/meixg/podcast-reader/pkg/models"
+)
+
+// TaskEventType identifies what happened to a task
+type TaskEventType string
+
+const (
+	TaskEventCreated       TaskEventType = "created"
+	TaskEventProgress      TaskEventType = "progress"
+	TaskEventStatusChanged TaskEventType = "status_changed"
+	TaskEventCompleted     TaskEventType = "completed"
+	TaskEventFailed        TaskEventType = "failed"
+)
+
+// DefaultEventHistory is how many recent events are kept for reconnecting subscribers
+const DefaultEventHistory = 256
+
+// subscriberBuffer is how many events a subscriber may lag behind before it is dropped
+const subscriberBuffer = 64
+
+// TaskEvent is a change to a task, with a snapshot of the task after the change
+type TaskEvent struct {
+	ID   uint64
+	Type TaskEventType
+	Task models.DownloadTask
+}
+
+// EventBroker fans task events out to subscribers and keeps a short history
+// so clients can catch up after reconnecting
+type EventBroker struct {
+	mu          sync.Mutex
+	nextID      uint64
+	history     []TaskEvent // Ring buffer of the most recent events
+	start       int         // Index of the oldest event in history
+	size        int
+	subscribers map[chan TaskEvent]struct{}
+}
+
+// NewEventBroker creates a broker that remembers up to historySize events
+func NewEventBroker(historySize int) *EventBroker {
+	if historySize < 1 {
+		historySize = DefaultEventHistory
+	}
+	return &EventBroker{
+		history:     make([]TaskEvent, historySize),
+		subscribers: make(map[chan TaskEvent]struct{}),
+	}
+}
+
+// Publish records an event for task and delivers it to all subscribers.
+// It never blocks; subscribers that fall too far behind are disconnected.
+func (b *EventBroker) Publish(eventType TaskEventType, task *models.DownloadTask) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event := TaskEvent{ID: b.nextID, Type: eventType, Task: *task}
+
+	end := (b.start + b.size) % len(b.history)
+	b.history[end] = event
+	if b.size < len(b.history) {
+		b.size++
+	} else {
+		b.start = (b.start + 1) % len(b.history)
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Let the client reconnect and catch up from the history instead
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe registers a new subscriber. Events after lastEventID that are still
+// in the history are returned for replay; complete is false if some of them
+// were already discarded and the subscriber should reload the full task list.
+// The channel is closed when unsubscribe is called or the subscriber falls behind.
+func (b *EventBroker) Subscribe(lastEventID uint64) (events <-chan TaskEvent, replay []TaskEvent, complete bool, unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	complete = true
+	if lastEventID > 0 && lastEventID < b.nextID {
+		oldest := b.nextID + 1 - uint64(b.size)
+		if lastEventID+1 < oldest {
+			complete = false
+		}
+		for i := 0; i < b.size; i++ {
+			event := b.history[(b.start+i)%len(b.history)]
+			if event.ID > lastEventID {
+				replay = append(replay, event)
+			}
+		}
+	} else if lastEventID > b.nextID {
+		// The server restarted since the client last connected
+		complete = false
+	}
+
+	ch := make(chan TaskEvent, subscriberBuffer)
+	b.subscribers[ch] = struct{}{}
+
+	unsubscribe = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, replay, complete, unsubscribe
+}
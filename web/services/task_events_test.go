@@ -0,0 +1,61 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/meixg/podcast-reader/pkg/models"
+)
+
+func TestEventBroker_Replay(t *testing.T) {
+	b := NewEventBroker(3)
+	task := &models.DownloadTask{ID: "t1"}
+	for i := 0; i < 5; i++ {
+		b.Publish(TaskEventProgress, task)
+	}
+
+	// Events 3-5 are still in the history
+	_, replay, complete, unsubscribe := b.Subscribe(3)
+	unsubscribe()
+	if !complete || len(replay) != 2 || replay[0].ID != 4 || replay[1].ID != 5 {
+		t.Errorf("Subscribe(3) = %v, complete=%v, want events 4 and 5", replay, complete)
+	}
+
+	// Event 2 was discarded, so the subscriber has to resync
+	_, replay, complete, unsubscribe = b.Subscribe(1)
+	unsubscribe()
+	if complete || len(replay) != 3 {
+		t.Errorf("Subscribe(1) replayed %d events, complete=%v, want 3 and incomplete", len(replay), complete)
+	}
+
+	// An ID from before a server restart
+	_, _, complete, unsubscribe = b.Subscribe(100)
+	unsubscribe()
+	if complete {
+		t.Error("Subscribe(100) complete = true, want false")
+	}
+}
+
+func TestEventBroker_Publish(t *testing.T) {
+	b := NewEventBroker(DefaultEventHistory)
+	events, replay, _, unsubscribe := b.Subscribe(0)
+	defer unsubscribe()
+	if len(replay) != 0 {
+		t.Fatalf("new subscriber replayed %d events", len(replay))
+	}
+
+	task := &models.DownloadTask{ID: "t1", Status: models.TaskStatusPending}
+	b.Publish(TaskEventCreated, task)
+	task.Status = models.TaskStatusDownloading
+
+	event := <-events
+	if event.Type != TaskEventCreated || event.Task.Status != models.TaskStatusPending {
+		t.Errorf("event = %s/%s, want a snapshot taken at publish time", event.Type, event.Task.Status)
+	}
+
+	// A subscriber that stops reading is disconnected instead of blocking publishers
+	for i := 0; i < subscriberBuffer+1; i++ {
+		b.Publish(TaskEventProgress, task)
+	}
+	for range events {
+	}
+}
@@ -2,28 +2,293 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/meixg/podcast-reader/pkg/downloader"
 	"github.com/meixg/podcast-reader/pkg/models"
 )
 
-// TaskService manages download tasks in memory
+// Task errors
+var (
+	ErrTaskNotFound     = errors.New("task not found")
+	ErrInvalidTaskState = errors.New("operation not allowed in the current task state")
+
+	// ErrTaskCancelled and ErrTaskPaused are the causes attached to a task's
+	// context when it is stopped through the API
+	ErrTaskCancelled = errors.New("task cancelled")
+	ErrTaskPaused    = errors.New("task paused")
+)
+
+// TaskService manages download tasks in memory, optionally backed by a TaskStore
 type TaskService struct {
 	tasks           map[string]*models.DownloadTask
+	runs            map[string]*taskRun    // Running tasks by ID
+	retries         map[string]*time.Timer // Scheduled retries by task ID
+	retryPolicy     RetryPolicy
 	downloadService *DownloadService
+	store           TaskStore
+	queue           *DownloadQueue
+	events          *EventBroker
 	mu              sync.RWMutex
 }
 
 // NewTaskService creates a new task service
 func NewTaskService() *TaskService {
-	return &TaskService{
-		tasks: make(map[string]*models.DownloadTask),
+	s := &TaskService{
+		tasks:       make(map[string]*models.DownloadTask),
+		runs:        make(map[string]*taskRun),
+		retries:     make(map[string]*time.Timer),
+		retryPolicy: DefaultRetryPolicy(),
+		events:      NewEventBroker(DefaultEventHistory),
+	}
+	s.queue = NewDownloadQueue(DefaultConcurrency, QueueOrderPriority, s.runTask)
+	return s
+}
+
+// Queue returns the queue that schedules download tasks
+func (s *TaskService) Queue() *DownloadQueue {
+	return s.queue
+}
+
+// Events returns the broker that publishes task changes
+func (s *TaskService) Events() *EventBroker {
+	return s.events
+}
+
+// taskRun is one run of a task on a worker slot. A paused task that is
+// resumed runs again, so runs are told apart by identity rather than task ID.
+type taskRun struct {
+	cancel context.CancelCauseFunc
+	done   chan struct{} // Closed once the run has exited
+}
+
+// runTask executes a queued task on a worker slot with its own cancellable context
+func (s *TaskService) runTask(taskID, url string) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	s.mu.Lock()
+	// A task paused and resumed quickly may still be unwinding its previous
+	// run; wait for it so both don't write the same partial file
+	for previous := s.runs[taskID]; previous != nil; previous = s.runs[taskID] {
+		s.mu.Unlock()
+		<-previous.done
+		s.mu.Lock()
+	}
+	downloadService := s.downloadService
+	task, exists := s.tasks[taskID]
+	if !exists || task.Status != models.TaskStatusPending {
+		// Paused or cancelled while waiting in the queue
+		s.mu.Unlock()
+		return
+	}
+	run := &taskRun{cancel: cancel, done: make(chan struct{})}
+	s.runs[taskID] = run
+	task.Attempt++
+	task.MaxAttempts = s.retryPolicy.MaxAttempts
+	task.NextRetryAt = nil
+	s.persist(task)
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		if s.runs[taskID] == run {
+			delete(s.runs, taskID)
+		}
+		s.mu.Unlock()
+		close(run.done)
+	}()
+
+	if downloadService != nil {
+		downloadService.ExecuteDownload(ctx, taskID, url)
+	}
+}
+
+// CancelTask stops a task and discards its partial download.
+// Cancelling a batch cancels all of its unfinished episode tasks.
+func (s *TaskService) CancelTask(id string) (*models.DownloadTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, exists := s.tasks[id]
+	if !exists {
+		return nil, ErrTaskNotFound
+	}
+	if !isActiveStatus(task.Status) && task.Status != models.TaskStatusPaused {
+		return nil, ErrInvalidTaskState
+	}
+
+	if task.Kind == models.TaskKindBatch {
+		// Cancel the batch first so its children don't recompute its status
+		s.stop(task, models.TaskStatusCancelled, ErrTaskCancelled)
+		for _, child := range s.children(task) {
+			if isActiveStatus(child.Status) || child.Status == models.TaskStatusPaused {
+				s.cancel(child)
+			}
+		}
+		return task, nil
+	}
+
+	s.cancel(task)
+	return task, nil
+}
+
+// cancel stops a task and removes its partial download unless the running
+// download does so itself. Callers must hold s.mu.
+func (s *TaskService) cancel(task *models.DownloadTask) {
+	wasPaused := task.Status == models.TaskStatusPaused
+	s.stop(task, models.TaskStatusCancelled, ErrTaskCancelled)
+
+	// A running download cleans up after itself once it notices the cancellation
+	if _, running := s.runs[task.ID]; !running || wasPaused {
+		if task.FilePath != "" {
+			if err := downloader.RemovePartial(task.FilePath); err != nil {
+				log.Printf("Warning: Failed to remove partial download for task %s: %v", task.ID, err)
+			}
+		}
 	}
 }
 
+// PauseTask stops a task but keeps its partial download so it can be resumed.
+// Pausing a batch pauses all of its unfinished episode tasks.
+func (s *TaskService) PauseTask(id string) (*models.DownloadTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, exists := s.tasks[id]
+	if !exists {
+		return nil, ErrTaskNotFound
+	}
+	if !isActiveStatus(task.Status) {
+		return nil, ErrInvalidTaskState
+	}
+
+	if task.Kind == models.TaskKindBatch {
+		for _, child := range s.children(task) {
+			if isActiveStatus(child.Status) {
+				s.stop(child, models.TaskStatusPaused, ErrTaskPaused)
+			}
+		}
+		return task, nil
+	}
+
+	s.stop(task, models.TaskStatusPaused, ErrTaskPaused)
+	return task, nil
+}
+
+// ResumeTask puts a paused task back into the queue.
+// Resuming a batch resumes all of its paused episode tasks.
+func (s *TaskService) ResumeTask(id string) (*models.DownloadTask, error) {
+	s.mu.Lock()
+	task, exists := s.tasks[id]
+	if !exists {
+		s.mu.Unlock()
+		return nil, ErrTaskNotFound
+	}
+	if task.Status != models.TaskStatusPaused {
+		s.mu.Unlock()
+		return nil, ErrInvalidTaskState
+	}
+
+	resumed := s.episodeTasks(task, models.TaskStatusPaused)
+	for _, t := range resumed {
+		s.setStatus(t, models.TaskStatusPending)
+	}
+	s.mu.Unlock()
+
+	for _, t := range resumed {
+		s.queue.Enqueue(t.ID, t.URL, t.Priority)
+	}
+	return task, nil
+}
+
+// RetryTask queues a failed task again with a fresh set of attempts.
+// Retrying a batch retries all of its failed episode tasks.
+func (s *TaskService) RetryTask(id string) (*models.DownloadTask, error) {
+	s.mu.Lock()
+	task, exists := s.tasks[id]
+	if !exists {
+		s.mu.Unlock()
+		return nil, ErrTaskNotFound
+	}
+	if task.Status != models.TaskStatusFailed {
+		s.mu.Unlock()
+		return nil, ErrInvalidTaskState
+	}
+
+	retried := s.episodeTasks(task, models.TaskStatusFailed)
+	for _, t := range retried {
+		t.Attempt = 0
+		t.NextRetryAt = nil
+		t.CompletedAt = nil
+		t.ErrorMessage = ""
+		t.Progress = nil
+		s.setStatus(t, models.TaskStatusPending)
+	}
+	s.mu.Unlock()
+
+	for _, t := range retried {
+		s.queue.Enqueue(t.ID, t.URL, t.Priority)
+	}
+	return task, nil
+}
+
+// episodeTasks returns the task itself, or for a batch its episode tasks
+// with the given status. Callers must hold s.mu.
+func (s *TaskService) episodeTasks(task *models.DownloadTask, status models.TaskStatus) []*models.DownloadTask {
+	if task.Kind != models.TaskKindBatch {
+		return []*models.DownloadTask{task}
+	}
+	var tasks []*models.DownloadTask
+	for _, child := range s.children(task) {
+		if child.Status == status {
+			tasks = append(tasks, child)
+		}
+	}
+	return tasks
+}
+
+// stop removes a task from the queue or interrupts its running download.
+// Callers must hold s.mu.
+func (s *TaskService) stop(task *models.DownloadTask, status models.TaskStatus, cause error) {
+	s.queue.Remove(task.ID)
+	if run, running := s.runs[task.ID]; running {
+		run.cancel(cause)
+	}
+	if timer, scheduled := s.retries[task.ID]; scheduled {
+		timer.Stop()
+		delete(s.retries, task.ID)
+		task.NextRetryAt = nil
+	}
+
+	task.Speed = 0
+	task.ETA = nil
+	if status == models.TaskStatusCancelled {
+		now := time.Now()
+		task.CompletedAt = &now
+	}
+	s.setStatus(task, status)
+}
+
+// SetFilePath records where the audio file of a task is being written
+func (s *TaskService) SetFilePath(id, filePath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, exists := s.tasks[id]
+	if !exists {
+		return ErrTaskNotFound
+	}
+	task.FilePath = filePath
+	s.persist(task)
+	return nil
+}
+
 // SetDownloadService sets the download service
 func (s *TaskService) SetDownloadService(ds *DownloadService) {
 	s.mu.Lock()
@@ -31,47 +296,179 @@ func (s *TaskService) SetDownloadService(ds *DownloadService) {
 	s.downloadService = ds
 }
 
-// CreateTask creates a new download task and starts the download
-func (s *TaskService) CreateTask(url string) (*models.DownloadTask, error) {
+// SetRetryPolicy sets how failed downloads are retried
+func (s *TaskService) SetRetryPolicy(policy RetryPolicy) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.retryPolicy = policy
+}
 
-	// Check for duplicate URL - only block if there's an active task (pending or downloading)
-	for _, task := range s.tasks {
-		if task.URL == url && (task.Status == models.TaskStatusPending || task.Status == models.TaskStatusDownloading) {
-			return nil, fmt.Errorf("task already exists for this URL")
+// SetStore sets the persistence layer used to keep tasks across restarts
+func (s *TaskService) SetStore(store TaskStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+}
+
+// Restore loads persisted tasks and restarts the ones that were interrupted.
+// Returns the number of tasks that were resumed.
+func (s *TaskService) Restore() (int, error) {
+	if s.store == nil {
+		return 0, nil
+	}
+
+	tasks, err := s.store.Load()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load tasks: %w", err)
+	}
+
+	s.mu.Lock()
+	var interrupted []*models.DownloadTask
+	var batches []string
+	for _, task := range tasks {
+		s.tasks[task.ID] = task
+		if task.Kind == models.TaskKindBatch {
+			// Batches follow their episode tasks and are never queued themselves
+			batches = append(batches, task.ID)
+			continue
 		}
+		if isActiveStatus(task.Status) {
+			// Start over from the beginning of the pipeline; the audio
+			// download itself resumes from the partial file
+			task.Status = models.TaskStatusPending
+			task.Progress = nil
+			task.Speed = 0
+			task.ETA = nil
+			task.NextRetryAt = nil
+			s.persist(task)
+			interrupted = append(interrupted, task)
+		}
+	}
+	for _, id := range batches {
+		s.refreshBatch(id)
+	}
+	s.mu.Unlock()
+
+	for _, task := range interrupted {
+		log.Printf("Resuming interrupted task %s: %s", task.ID, task.URL)
+		s.queue.Enqueue(task.ID, task.URL, task.Priority)
+	}
+
+	return len(interrupted), nil
+}
+
+// CreateTask creates a new download task with default priority and queues it
+func (s *TaskService) CreateTask(url string) (*models.DownloadTask, error) {
+	return s.CreateTaskWithPriority(url, 0)
+}
+
+// CreateTaskWithPriority creates a new download task and queues it.
+// Tasks with a higher priority are started first when the queue is priority ordered.
+func (s *TaskService) CreateTaskWithPriority(url string, priority int) (*models.DownloadTask, error) {
+	task, err := s.addTask(url, priority)
+	if err != nil {
+		return nil, err
 	}
 
-	// Check if already downloaded by checking file system
-	if s.downloadService != nil {
-		if alreadyDownloaded, err := s.downloadService.IsAlreadyDownloaded(url); err == nil && alreadyDownloaded {
+	// Queue after releasing the lock; the queue may start the task right away
+	s.queue.Enqueue(task.ID, url, priority)
+
+	return task, nil
+}
+
+// addTask validates and registers a new pending task.
+// Returns an error wrapping downloader.ErrUnsupportedURL if no source handles the URL.
+func (s *TaskService) addTask(url string, priority int) (*models.DownloadTask, error) {
+	s.mu.RLock()
+	downloadService := s.downloadService
+	duplicate := s.hasActiveTask(url)
+	s.mu.RUnlock()
+
+	// Check for duplicate URL - only block if there's an unfinished task
+	if duplicate {
+		return nil, fmt.Errorf("task already exists for this URL")
+	}
+
+	// Find the source that handles the URL
+	var source string
+	if downloadService != nil {
+		src, err := downloadService.Sources().Lookup(url)
+		if err != nil {
+			return nil, err
+		}
+		if src.IsShow(url) {
+			return nil, fmt.Errorf("show URLs must be downloaded as a batch")
+		}
+		source = src.Name()
+
+		// Check if already downloaded by checking file system. This fetches
+		// the episode page, so it runs without holding the lock.
+		if alreadyDownloaded, err := downloadService.IsAlreadyDownloaded(url); err == nil && alreadyDownloaded {
 			return nil, fmt.Errorf("episode already downloaded")
 		}
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Another task for the URL may have been created during the lookup
+	if s.hasActiveTask(url) {
+		return nil, fmt.Errorf("task already exists for this URL")
+	}
+
 	task := &models.DownloadTask{
 		ID:        uuid.New().String(),
 		URL:       url,
+		Source:    source,
 		Status:    models.TaskStatusPending,
 		CreatedAt: time.Now(),
+		Priority:  priority,
 	}
 
 	s.tasks[task.ID] = task
+	s.persist(task)
+	s.publish(TaskEventCreated, task)
+
+	return task, nil
+}
+
+// checkQueueable reports why url can't be downloaded, as far as is known
+// without fetching anything: no source handles it, or a task for it is
+// already active.
+func (s *TaskService) checkQueueable(url string) error {
+	s.mu.RLock()
+	downloadService := s.downloadService
+	duplicate := s.hasActiveTask(url)
+	s.mu.RUnlock()
 
-	// Start download in background
-	if s.downloadService != nil {
-		go s.downloadService.ExecuteDownload(context.Background(), task.ID, url)
+	if duplicate {
+		return fmt.Errorf("task already exists for this URL")
 	}
+	if downloadService == nil {
+		return errors.New("download service not configured")
+	}
+	_, err := downloadService.Sources().Lookup(url)
+	return err
+}
 
-	return task, nil
+// hasActiveTask reports whether an unfinished task exists for url: one that
+// is queued, running or paused, and may own a partial download.
+// Callers must hold s.mu.
+func (s *TaskService) hasActiveTask(url string) bool {
+	for _, task := range s.tasks {
+		if task.URL == url && (isActiveStatus(task.Status) || task.Status == models.TaskStatusPaused) {
+			return true
+		}
+	}
+	return false
 }
 
 // GetTasks returns all tasks
 func (s *TaskService) GetTasks() []*models.DownloadTask {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	s.applyQueuePositions()
 	tasks := make([]*models.DownloadTask, 0, len(s.tasks))
 	for _, task := range s.tasks {
 		tasks = append(tasks, task)
@@ -81,50 +478,94 @@ func (s *TaskService) GetTasks() []*models.DownloadTask {
 
 // GetTask returns a task by ID
 func (s *TaskService) GetTask(id string) (*models.DownloadTask, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	task, exists := s.tasks[id]
 	if !exists {
-		return nil, fmt.Errorf("task not found")
+		return nil, ErrTaskNotFound
 	}
+	s.applyQueuePositions()
 	return task, nil
 }
 
-// UpdateProgress updates the progress of a task
-func (s *TaskService) UpdateProgress(id string, progress int) error {
+// applyQueuePositions refreshes the queue position of every task. Callers must hold s.mu.
+func (s *TaskService) applyQueuePositions() {
+	positions := s.queue.Positions()
+	for id, task := range s.tasks {
+		if position, queued := positions[id]; queued {
+			task.QueuePosition = &position
+		} else {
+			task.QueuePosition = nil
+		}
+	}
+}
+
+// UpdateProgress updates the progress of a task from the run with context ctx
+func (s *TaskService) UpdateProgress(ctx context.Context, id string, progress int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	task, exists := s.tasks[id]
-	if !exists {
-		return fmt.Errorf("task not found")
+	task, err := s.runningTask(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	task.Progress = &progress
+	s.setStatus(task, models.TaskStatusDownloading)
+	s.publish(TaskEventProgress, task)
+	return nil
+}
+
+// UpdateTransfer updates the progress of a task together with its transfer statistics.
+// total is -1 and eta is negative when they are unknown.
+func (s *TaskService) UpdateTransfer(ctx context.Context, id string, progress int, downloaded, total int64, speed float64, eta time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, err := s.runningTask(ctx, id)
+	if err != nil {
+		return err
 	}
 
 	task.Progress = &progress
-	// Only update status to downloading if not already completed or failed
-	if task.Status != models.TaskStatusCompleted && task.Status != models.TaskStatusFailed {
-		task.Status = models.TaskStatusDownloading
+	task.BytesDownloaded = downloaded
+	if total > 0 {
+		task.TotalBytes = total
+	}
+	task.Speed = speed
+	task.ETA = nil
+	if eta >= 0 {
+		seconds := int(eta.Seconds())
+		task.ETA = &seconds
 	}
+	s.setStatus(task, models.TaskStatusDownloading)
+	s.publish(TaskEventProgress, task)
 	return nil
 }
 
-// MarkCompleted marks a task as completed
-func (s *TaskService) MarkCompleted(id string, episodeID string) error {
+// MarkCompleted marks a task as completed by the run with context ctx
+func (s *TaskService) MarkCompleted(ctx context.Context, id string, episodeID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	task, exists := s.tasks[id]
-	if !exists {
-		return fmt.Errorf("task not found")
+	// Fails if the task was paused or cancelled while finishing up
+	task, err := s.runningTask(ctx, id)
+	if err != nil {
+		return err
 	}
 
 	now := time.Now()
 	task.Status = models.TaskStatusCompleted
 	task.CompletedAt = &now
 	task.EpisodeID = episodeID
+	task.ErrorMessage = ""
 	progress := 100
 	task.Progress = &progress
+	task.Speed = 0
+	task.ETA = nil
+	s.persist(task)
+	s.publish(TaskEventCompleted, task)
 	return nil
 }
 
@@ -135,26 +576,142 @@ func (s *TaskService) MarkFailed(id string, errorMsg string) error {
 
 	task, exists := s.tasks[id]
 	if !exists {
-		return fmt.Errorf("task not found")
+		return ErrTaskNotFound
+	}
+	if !isActiveStatus(task.Status) {
+		return ErrInvalidTaskState
 	}
 
+	s.fail(task, errorMsg)
+	return nil
+}
+
+// RetryOrFail schedules another attempt of a task when err is retryable and
+// attempts remain, and marks the task as failed otherwise. err is the failure
+// of the run with context ctx. Returns true if a retry was scheduled.
+func (s *TaskService) RetryOrFail(ctx context.Context, id string, errorMsg string, err error) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, runErr := s.runningTask(ctx, id)
+	if runErr != nil {
+		return false, runErr
+	}
+
+	if !s.retryPolicy.ShouldRetry(task.Attempt, err) {
+		s.fail(task, errorMsg)
+		return false, nil
+	}
+
+	delay := s.retryPolicy.Delay(task.Attempt)
+	next := time.Now().Add(delay)
+	task.NextRetryAt = &next
+	task.ErrorMessage = errorMsg
+	task.Progress = nil
+	task.Speed = 0
+	task.ETA = nil
+	task.Status = models.TaskStatusPending
+	s.persist(task)
+	s.publish(TaskEventStatusChanged, task)
+
+	s.retries[id] = time.AfterFunc(delay, func() { s.requeue(id) })
+	log.Printf("Task %s failed (attempt %d/%d), retrying in %v: %s",
+		id, task.Attempt, s.retryPolicy.MaxAttempts, delay.Round(time.Second), errorMsg)
+	return true, nil
+}
+
+// requeue puts a task back into the queue once its retry delay has passed
+func (s *TaskService) requeue(id string) {
+	s.mu.Lock()
+	delete(s.retries, id)
+	task, exists := s.tasks[id]
+	if !exists || task.Status != models.TaskStatusPending {
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+
+	s.queue.Enqueue(task.ID, task.URL, task.Priority)
+}
+
+// fail marks a task as failed. Callers must hold s.mu.
+func (s *TaskService) fail(task *models.DownloadTask, errorMsg string) {
 	now := time.Now()
 	task.Status = models.TaskStatusFailed
 	task.CompletedAt = &now
 	task.ErrorMessage = errorMsg
-	return nil
+	task.NextRetryAt = nil
+	task.Speed = 0
+	task.ETA = nil
+	s.persist(task)
+	s.publish(TaskEventFailed, task)
 }
 
-// UpdateTaskStatus updates the status of a task
-func (s *TaskService) UpdateTaskStatus(id string, status models.TaskStatus) error {
+// UpdateTaskStatus updates the status of a task from the run with context ctx
+func (s *TaskService) UpdateTaskStatus(ctx context.Context, id string, status models.TaskStatus) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	task, err := s.runningTask(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	s.setStatus(task, status)
+	return nil
+}
+
+// runningTask returns a task that the run with context ctx may update. A run
+// is cancelled under s.mu when its task is paused or cancelled, so a run still
+// unwinding can't change the task after it was resumed and queued again.
+// Callers must hold s.mu.
+func (s *TaskService) runningTask(ctx context.Context, id string) (*models.DownloadTask, error) {
 	task, exists := s.tasks[id]
 	if !exists {
-		return fmt.Errorf("task not found")
+		return nil, ErrTaskNotFound
+	}
+	if ctx.Err() != nil || !isActiveStatus(task.Status) {
+		return nil, ErrInvalidTaskState
 	}
+	return task, nil
+}
 
+// setStatus changes the status of a task, persisting and publishing it if it changed.
+// Callers must hold s.mu.
+func (s *TaskService) setStatus(task *models.DownloadTask, status models.TaskStatus) {
+	if task.Status == status {
+		return
+	}
 	task.Status = status
-	return nil
+	s.persist(task)
+	s.publish(TaskEventStatusChanged, task)
+}
+
+// publish announces a change to a task and updates the batch it belongs to.
+// Callers must hold s.mu.
+func (s *TaskService) publish(eventType TaskEventType, task *models.DownloadTask) {
+	s.events.Publish(eventType, task)
+	if task.ParentID != "" {
+		s.refreshBatch(task.ParentID)
+	}
+}
+
+// persist saves a task to the store if one is configured.
+// Callers must hold s.mu so the snapshot is consistent.
+func (s *TaskService) persist(task *models.DownloadTask) {
+	if s.store == nil {
+		return
+	}
+	if err := s.store.Save(task); err != nil {
+		log.Printf("Warning: Failed to persist task %s: %v", task.ID, err)
+	}
+}
+
+// isActiveStatus reports whether a task with this status is still being worked on
+func isActiveStatus(status models.TaskStatus) bool {
+	switch status {
+	case models.TaskStatusPending, models.TaskStatusDownloading, models.TaskStatusExtractingMetadata:
+		return true
+	}
+	return false
 }
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/meixg/podcast-reader/pkg/downloader"
+	"github.com/meixg/podcast-reader/pkg/models"
+)
+
+func TestTaskService_PauseResumeCancel(t *testing.T) {
+	s := NewTaskService()
+	task, err := s.CreateTask("https://www.xiaoyuzhoufm.com/episode/abc")
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	if _, err := s.ResumeTask(task.ID); !errors.Is(err, ErrInvalidTaskState) {
+		t.Errorf("ResumeTask(pending) error = %v, want ErrInvalidTaskState", err)
+	}
+
+	if _, err := s.PauseTask(task.ID); err != nil {
+		t.Fatalf("PauseTask() error = %v", err)
+	}
+	if task.Status != models.TaskStatusPaused {
+		t.Errorf("status = %s, want paused", task.Status)
+	}
+
+	// A paused task keeps its partial download, so the URL can't be queued twice
+	if _, err := s.CreateTask(task.URL); err == nil {
+		t.Error("CreateTask() for the URL of a paused task should fail")
+	}
+
+	// Progress reports from a winding-down download must not revive the task
+	s.UpdateProgress(context.Background(), task.ID, 50)
+	if task.Status != models.TaskStatusPaused {
+		t.Errorf("status = %s after progress update, want paused", task.Status)
+	}
+
+	if _, err := s.ResumeTask(task.ID); err != nil {
+		t.Fatalf("ResumeTask() error = %v", err)
+	}
+	if task.Status != models.TaskStatusPending {
+		t.Errorf("status = %s, want pending", task.Status)
+	}
+
+	if _, err := s.CancelTask(task.ID); err != nil {
+		t.Fatalf("CancelTask() error = %v", err)
+	}
+	if task.Status != models.TaskStatusCancelled {
+		t.Errorf("status = %s, want cancelled", task.Status)
+	}
+	if _, err := s.CancelTask(task.ID); !errors.Is(err, ErrInvalidTaskState) {
+		t.Errorf("CancelTask(cancelled) error = %v, want ErrInvalidTaskState", err)
+	}
+	if _, err := s.PauseTask("missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("PauseTask(missing) error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+// blockingSource resolves episodes until the download is stopped, then
+// takes a moment to unwind, like a download closing its partial file
+type blockingSource struct {
+	showSource
+	started   chan struct{}
+	mu        sync.Mutex
+	active    int
+	maxActive int
+}
+
+func (s *blockingSource) Resolve(ctx context.Context, url string) (*downloader.EpisodeMetadata, error) {
+	if _, ok := ctx.Deadline(); ok {
+		// The duplicate check of CreateTask
+		return nil, downloader.ErrEpisodeNotFound
+	}
+	s.mu.Lock()
+	s.active++
+	s.maxActive = max(s.maxActive, s.active)
+	s.mu.Unlock()
+	s.started <- struct{}{}
+
+	<-ctx.Done()
+	time.Sleep(50 * time.Millisecond)
+	s.mu.Lock()
+	s.active--
+	s.mu.Unlock()
+	return nil, ctx.Err()
+}
+
+func (s *blockingSource) activeRuns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active
+}
+
+func TestTaskService_QuickPauseResume(t *testing.T) {
+	s := NewTaskService()
+	ds := NewDownloadService(t.TempDir(), s)
+	source := &blockingSource{started: make(chan struct{}, 4)}
+	ds.sources = downloader.NewRegistry(source)
+	s.SetDownloadService(ds)
+
+	task, err := s.CreateTask("https://example.com/ep1")
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	<-source.started
+
+	// Resume while the first run is still unwinding
+	s.PauseTask(task.ID)
+	if _, err := s.ResumeTask(task.ID); err != nil {
+		t.Fatalf("ResumeTask() error = %v", err)
+	}
+	select {
+	case <-source.started:
+	case <-time.After(time.Second):
+		t.Fatal("resumed task did not run again")
+	}
+	if source.maxActive > 1 {
+		t.Errorf("%d runs of the task overlapped", source.maxActive)
+	}
+
+	// The new run can still be paused, and frees its worker slot
+	if _, err := s.PauseTask(task.ID); err != nil {
+		t.Fatalf("PauseTask() error = %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for source.activeRuns() > 0 || s.Queue().Stats().Running > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("pausing did not stop the resumed run: %d active, %+v", source.activeRuns(), s.Queue().Stats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// unwindingSource holds the first resolve of an episode until released and
+// then returns its metadata, like a run that only notices it was stopped
+// after finishing its current step
+type unwindingSource struct {
+	showSource
+	audioURL string
+	started  chan struct{}
+	release  chan struct{}
+	runs     atomic.Int32
+}
+
+func (s *unwindingSource) Resolve(ctx context.Context, url string) (*downloader.EpisodeMetadata, error) {
+	if _, ok := ctx.Deadline(); ok {
+		// The duplicate check of CreateTask
+		return nil, downloader.ErrEpisodeNotFound
+	}
+	if s.runs.Add(1) == 1 {
+		s.started <- struct{}{}
+		<-s.release
+	}
+	return &downloader.EpisodeMetadata{Title: "Ep", PageURL: url, AudioURL: s.audioURL}, nil
+}
+
+func TestTaskService_ResumeWhileUnwinding(t *testing.T) {
+	audio := make([]byte, 1024)
+	copy(audio, []byte{0x00, 0x00, 0x00, 0x20, 'f', 't', 'y', 'p', 'M', '4', 'A', ' '})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(audio)
+	}))
+	defer server.Close()
+
+	s := NewTaskService()
+	ds := NewDownloadService(t.TempDir(), s)
+	source := &unwindingSource{audioURL: server.URL + "/ep1.m4a", started: make(chan struct{}), release: make(chan struct{})}
+	ds.sources = downloader.NewRegistry(source)
+	s.SetDownloadService(ds)
+
+	task, err := s.CreateTask("https://example.com/ep1")
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	<-source.started
+	if _, err := s.PauseTask(task.ID); err != nil {
+		t.Fatalf("PauseTask() error = %v", err)
+	}
+	if _, err := s.ResumeTask(task.ID); err != nil {
+		t.Fatalf("ResumeTask() error = %v", err)
+	}
+
+	// Progress from a stopped run doesn't move the resumed task out of pending
+	stopped, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.UpdateProgress(stopped, task.ID, 95); !errors.Is(err, ErrInvalidTaskState) {
+		t.Errorf("UpdateProgress() from a stopped run error = %v, want ErrInvalidTaskState", err)
+	}
+	if got, _ := s.GetTask(task.ID); got.Status != models.TaskStatusPending {
+		t.Errorf("status after a stale update = %s, want pending", got.Status)
+	}
+
+	// The first run carries on with its next steps, then the resumed run finishes the task
+	close(source.release)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.mu.RLock()
+		status := task.Status
+		s.mu.RUnlock()
+		if status == models.TaskStatusCompleted {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status = %s, want the resumed task completed", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if runs := source.runs.Load(); runs != 2 {
+		t.Errorf("resolved %d times, want once per run", runs)
+	}
+}
+
+// lookupSource holds the duplicate check of CreateTask until released
+type lookupSource struct {
+	showSource
+	looking chan struct{}
+	release chan struct{}
+}
+
+func (s *lookupSource) Resolve(ctx context.Context, url string) (*downloader.EpisodeMetadata, error) {
+	s.looking <- struct{}{}
+	<-s.release
+	return nil, downloader.ErrEpisodeNotFound
+}
+
+func TestTaskService_CreateTaskLooksUpWithoutLock(t *testing.T) {
+	s := NewTaskService()
+	s.queue = NewDownloadQueue(1, QueueOrderFIFO, func(taskID, url string) {})
+	ds := NewDownloadService(t.TempDir(), s)
+	source := &lookupSource{looking: make(chan struct{}), release: make(chan struct{})}
+	ds.sources = downloader.NewRegistry(source)
+	s.SetDownloadService(ds)
+
+	created := make(chan error)
+	go func() {
+		_, err := s.CreateTask("https://example.com/ep1")
+		created <- err
+	}()
+	<-source.looking
+
+	listed := make(chan struct{})
+	go func() {
+		s.GetTasks()
+		close(listed)
+	}()
+	select {
+	case <-listed:
+	case <-time.After(time.Second):
+		t.Fatal("GetTasks() blocked while CreateTask() fetched the episode page")
+	}
+
+	close(source.release)
+	if err := <-created; err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+}
+
+func TestTaskService_CancelPausedRemovesPartial(t *testing.T) {
+	s := NewTaskService()
+	task, _ := s.CreateTask("https://www.xiaoyuzhoufm.com/episode/abc")
+
+	audioPath := filepath.Join(t.TempDir(), "podcast.m4a")
+	os.WriteFile(downloader.PartPath(audioPath), []byte("partial"), 0644)
+	s.SetFilePath(task.ID, audioPath)
+
+	s.PauseTask(task.ID)
+	if !downloader.HasPartial(audioPath) {
+		t.Fatal("pausing must keep the partial file")
+	}
+
+	s.CancelTask(task.ID)
+	if downloader.HasPartial(audioPath) {
+		t.Error("cancelling must remove the partial file")
+	}
+}
+
+func TestTaskService_RetryOrFail(t *testing.T) {
+	s := NewTaskService()
+	s.SetRetryPolicy(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Hour})
+	task := &models.DownloadTask{ID: "t1", URL: "https://www.xiaoyuzhoufm.com/episode/abc", Status: models.TaskStatusDownloading, Attempt: 1}
+	s.tasks[task.ID] = task
+
+	serverErr := &downloader.StatusError{StatusCode: 502}
+	if retried, err := s.RetryOrFail(context.Background(), task.ID, "下载音频失败", serverErr); err != nil || !retried {
+		t.Fatalf("RetryOrFail() = %v, %v, want retry scheduled", retried, err)
+	}
+	if task.Status != models.TaskStatusPending || task.NextRetryAt == nil {
+		t.Fatalf("status = %s, nextRetryAt = %v, want pending with a retry time", task.Status, task.NextRetryAt)
+	}
+
+	// Out of attempts: the next failure is final
+	task.Attempt = 2
+	task.Status = models.TaskStatusDownloading
+	if retried, _ := s.RetryOrFail(context.Background(), task.ID, "下载音频失败", serverErr); retried {
+		t.Fatal("RetryOrFail() scheduled a retry after the last attempt")
+	}
+	if task.Status != models.TaskStatusFailed || task.NextRetryAt != nil {
+		t.Errorf("status = %s, nextRetryAt = %v, want failed without a retry time", task.Status, task.NextRetryAt)
+	}
+
+	if _, err := s.RetryTask(task.ID); err != nil {
+		t.Fatalf("RetryTask() error = %v", err)
+	}
+	s.mu.RLock()
+	if task.Status == models.TaskStatusFailed || task.ErrorMessage != "" {
+		t.Errorf("status = %s, error = %q after manual retry", task.Status, task.ErrorMessage)
+	}
+	s.mu.RUnlock()
+	if _, err := s.RetryTask(task.ID); !errors.Is(err, ErrInvalidTaskState) {
+		t.Errorf("RetryTask(pending) error = %v, want ErrInvalidTaskState", err)
+	}
+}
+
+func TestTaskService_PermanentErrorFailsImmediately(t *testing.T) {
+	s := NewTaskService()
+	task := &models.DownloadTask{ID: "t1", Status: models.TaskStatusDownloading, Attempt: 1}
+	s.tasks[task.ID] = task
+
+	if retried, _ := s.RetryOrFail(context.Background(), task.ID, "下载音频失败", downloader.ErrInvalidAudio); retried {
+		t.Error("RetryOrFail() retried a permanent error")
+	}
+	if task.Status != models.TaskStatusFailed {
+		t.Errorf("status = %s, want failed", task.Status)
+	}
+}
+
+// showSource lists a fixed set of episodes for any show URL
+type showSource struct {
+	episodes []*downloader.EpisodeMetadata
+}
+
+func (s *showSource) Name() string           { return "test" }
+func (s *showSource) Match(url string) bool  { return true }
+func (s *showSource) IsShow(url string) bool { return url == "https://example.com/show" }
+func (s *showSource) Resolve(ctx context.Context, url string) (*downloader.EpisodeMetadata, error) {
+	return nil, downloader.ErrAudioNotFound
+}
+func (s *showSource) List(ctx context.Context, url string) ([]*downloader.EpisodeMetadata, error) {
+	return s.episodes, nil
+}
+
+func TestTaskService_CreateBatch(t *testing.T) {
+	s := NewTaskService()
+	// Keep the episode tasks pending instead of downloading them
+	s.queue = NewDownloadQueue(1, QueueOrderFIFO, func(taskID, url string) {})
+
+	downloadsDir := t.TempDir()
+	ds := NewDownloadService(downloadsDir, s)
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	ds.sources = downloader.NewRegistry(&showSource{episodes: []*downloader.EpisodeMetadata{
+		{Title: "ep1", PageURL: "https://example.com/ep1", PublicationDate: day(1)},
+		{Title: "ep2", PageURL: "https://example.com/ep2", PublicationDate: day(2)},
+		{Title: "ep3", PageURL: "https://example.com/ep3", PublicationDate: day(3)},
+	}})
+	s.SetDownloadService(ds)
+
+	// ep3 was downloaded before
+	os.MkdirAll(filepath.Join(downloadsDir, "ep3"), 0755)
+	os.WriteFile(filepath.Join(downloadsDir, "ep3", ".metadata.json"), []byte(`{"source_url":"https://example.com/ep3"}`), 0644)
+
+	if !s.IsShowURL("https://example.com/show") {
+		t.Fatal("IsShowURL() = false for a show URL")
+	}
+	batch, err := s.CreateBatch(context.Background(), "https://example.com/show", BatchOptions{SkipExisting: true})
+	if err != nil {
+		t.Fatalf("CreateBatch() error = %v", err)
+	}
+	if batch.Kind != models.TaskKindBatch || len(batch.ChildIDs) != 2 || batch.Skipped != 1 {
+		t.Fatalf("batch = kind %q, %d children, %d skipped; want batch, 2, 1", batch.Kind, len(batch.ChildIDs), batch.Skipped)
+	}
+
+	first, _ := s.GetTask(batch.ChildIDs[0])
+	second, _ := s.GetTask(batch.ChildIDs[1])
+	if first.URL != "https://example.com/ep2" || first.ParentID != batch.ID {
+		t.Errorf("first child = %s (parent %s), want newest episode of the batch", first.URL, first.ParentID)
+	}
+
+	s.MarkCompleted(context.Background(), first.ID, "ep2")
+	if batch.Status != models.TaskStatusDownloading || batch.Progress == nil || *batch.Progress != 50 {
+		t.Errorf("batch = %s at %v, want downloading at 50%%", batch.Status, batch.Progress)
+	}
+
+	s.MarkFailed(second.ID, "boom")
+	if batch.Status != models.TaskStatusFailed {
+		t.Errorf("batch status = %s, want failed", batch.Status)
+	}
+
+	// Retrying the batch retries only the failed episode
+	if _, err := s.RetryTask(batch.ID); err != nil {
+		t.Fatalf("RetryTask(batch) error = %v", err)
+	}
+	s.mu.RLock()
+	if second.Status != models.TaskStatusPending || first.Status != models.TaskStatusCompleted {
+		t.Errorf("children = %s, %s after retry; want completed, pending", first.Status, second.Status)
+	}
+	s.mu.RUnlock()
+
+	if _, err := s.CancelTask(batch.ID); err != nil {
+		t.Fatalf("CancelTask(batch) error = %v", err)
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if batch.Status != models.TaskStatusCancelled || second.Status != models.TaskStatusCancelled {
+		t.Errorf("batch = %s, child = %s after cancel; want both cancelled", batch.Status, second.Status)
+	}
+}
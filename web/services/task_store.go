@@ -0,0 +1,197 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/meixg/podcast-reader/pkg/models"
+)
+
+// TaskStore persists download tasks so they survive server restarts
+type TaskStore interface {
+	// Save records the current state of a task
+	Save(task *models.DownloadTask) error
+	// Delete removes a task
+	Delete(id string) error
+	// Load returns all stored tasks ordered by creation time
+	Load() ([]*models.DownloadTask, error)
+}
+
+// journalEntry is a single line of the task journal
+type journalEntry struct {
+	Op   string               `json:"op"`
+	ID   string               `json:"id,omitempty"`
+	Task *models.DownloadTask `json:"task,omitempty"`
+}
+
+const (
+	journalOpSave   = "save"
+	journalOpDelete = "delete"
+)
+
+// journalCompactMin is the fewest entries the journal grows to before Save
+// and Delete compact it. Progress updates append often, so a long-running
+// server can't leave compaction to the next Load.
+const journalCompactMin = 1000
+
+// JournalTaskStore persists tasks as an append-only JSON-lines journal.
+// Every change appends a full task snapshot; Load replays the journal and
+// compacts it to one line per task, and so does an append that grows the
+// journal to twice its compacted size, or journalCompactMin entries.
+type JournalTaskStore struct {
+	path    string
+	mu      sync.Mutex
+	entries int // Lines in the journal
+	live    int // Tasks in the journal when it was last compacted
+}
+
+// NewJournalTaskStore creates a journal store backed by the given file
+func NewJournalTaskStore(path string) *JournalTaskStore {
+	return &JournalTaskStore{path: path}
+}
+
+// Save appends a snapshot of the task to the journal
+func (s *JournalTaskStore) Save(task *models.DownloadTask) error {
+	return s.append(journalEntry{Op: journalOpSave, Task: task})
+}
+
+// Delete appends a deletion record to the journal
+func (s *JournalTaskStore) Delete(id string) error {
+	return s.append(journalEntry{Op: journalOpDelete, ID: id})
+}
+
+// Load replays the journal and rewrites it in compacted form
+func (s *JournalTaskStore) Load() ([]*models.DownloadTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks, err := s.replay()
+	if err != nil || tasks == nil {
+		return nil, err
+	}
+	if err := s.compact(tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// replay reads the tasks in the journal ordered by creation time, or nil if
+// there is no journal. Callers must hold s.mu.
+func (s *JournalTaskStore) replay() ([]*models.DownloadTask, error) {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open task journal: %w", err)
+	}
+
+	tasks := make(map[string]*models.DownloadTask)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A crash can leave a truncated last line; skip it
+			continue
+		}
+		switch entry.Op {
+		case journalOpSave:
+			if entry.Task != nil && entry.Task.ID != "" {
+				tasks[entry.Task.ID] = entry.Task
+			}
+		case journalOpDelete:
+			delete(tasks, entry.ID)
+		}
+	}
+	file.Close()
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read task journal: %w", err)
+	}
+
+	result := make([]*models.DownloadTask, 0, len(tasks))
+	for _, task := range tasks {
+		result = append(result, task)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// append writes one entry to the end of the journal
+func (s *JournalTaskStore) append(entry journalEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open task journal: %w", err)
+	}
+	_, err = file.Write(append(data, '\n'))
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write task journal: %w", err)
+	}
+
+	s.entries++
+	if s.entries >= max(journalCompactMin, 2*s.live) {
+		// The entry is saved either way; a failed compaction is retried on
+		// the next append
+		tasks, err := s.replay()
+		if err == nil {
+			err = s.compact(tasks)
+		}
+		if err != nil {
+			log.Printf("Warning: Failed to compact task journal: %v", err)
+		}
+	}
+	return nil
+}
+
+// compact atomically replaces the journal with one save entry per task.
+// Callers must hold s.mu.
+func (s *JournalTaskStore) compact(tasks []*models.DownloadTask) error {
+	tmpPath := s.path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to compact task journal: %w", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, task := range tasks {
+		if err := encoder.Encode(journalEntry{Op: journalOpSave, Task: task}); err != nil {
+			file.Close()
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to compact task journal: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to compact task journal: %w", err)
+	}
+	file.Close()
+
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return err
+	}
+	s.entries, s.live = len(tasks), len(tasks)
+	return nil
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meixg/podcast-reader/pkg/models"
+)
+
+func TestJournalTaskStore_LoadReplaysJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".tasks.jsonl")
+	store := NewJournalTaskStore(path)
+
+	first := &models.DownloadTask{ID: "a", URL: "https://example.com/1", Status: models.TaskStatusPending, CreatedAt: time.Now()}
+	second := &models.DownloadTask{ID: "b", URL: "https://example.com/2", Status: models.TaskStatusPending, CreatedAt: time.Now().Add(time.Second)}
+
+	store.Save(first)
+	store.Save(second)
+	first.Status = models.TaskStatusCompleted
+	store.Save(first)
+	store.Delete("b")
+
+	tasks, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(tasks) != 1 {
+		t.Fatalf("len(tasks) = %d, want 1", len(tasks))
+	}
+	if tasks[0].ID != "a" || tasks[0].Status != models.TaskStatusCompleted {
+		t.Errorf("task = %+v, want completed task a", tasks[0])
+	}
+
+	// Load compacts the journal to one line per task
+	data, _ := os.ReadFile(path)
+	if lines := strings.Count(string(data), "\n"); lines != 1 {
+		t.Errorf("journal has %d lines after compaction, want 1", lines)
+	}
+}
+
+func TestJournalTaskStore_CompactsWhileRunning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".tasks.jsonl")
+	store := NewJournalTaskStore(path)
+	task := &models.DownloadTask{ID: "a", URL: "https://example.com/1", Status: models.TaskStatusDownloading, CreatedAt: time.Now()}
+	for i := 0; i < journalCompactMin+10; i++ {
+		progress := i % 100
+		task.Progress = &progress
+		if err := store.Save(task); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	// The journal was compacted on reaching journalCompactMin entries
+	data, _ := os.ReadFile(path)
+	if lines := strings.Count(string(data), "\n"); lines != 11 {
+		t.Errorf("journal has %d lines, want 11", lines)
+	}
+	tasks, err := store.Load()
+	if err != nil || len(tasks) != 1 || *tasks[0].Progress != (journalCompactMin+9)%100 {
+		t.Errorf("Load() = %+v, %v, want the last progress of task a", tasks, err)
+	}
+}
+
+func TestJournalTaskStore_SkipsTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".tasks.jsonl")
+	store := NewJournalTaskStore(path)
+	store.Save(&models.DownloadTask{ID: "a", Status: models.TaskStatusPending})
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"op":"save","task":{"id":"b"`)
+	f.Close()
+
+	tasks, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != "a" {
+		t.Errorf("tasks = %+v, want only task a", tasks)
+	}
+}
+
+func TestJournalTaskStore_LoadMissingFile(t *testing.T) {
+	store := NewJournalTaskStore(filepath.Join(t.TempDir(), "missing.jsonl"))
+	tasks, err := store.Load()
+	if err != nil || len(tasks) != 0 {
+		t.Errorf("Load() = %v, %v; want empty, nil", tasks, err)
+	}
+}